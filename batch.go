@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"aws-bedrock-prompt-engineering/internal/batch"
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+	"aws-bedrock-prompt-engineering/internal/prompting"
+)
+
+// runBatch runs a JSONL file of requests and appends a result line per request to a results file:
+//
+//	prompt-engineering batch [-workers n] [-o results.jsonl] requests.jsonl
+func runBatch(ctx context.Context, client *bedrock.Client, library *prompting.Library, args []string) error {
+	flags := flag.NewFlagSet("batch", flag.ContinueOnError)
+	workers := flags.Int("workers", 4, "requests to run at the same time")
+	output := flags.String("o", "", "results file to append to (default: the input name with .results.jsonl)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: prompt-engineering batch [flags] requests.jsonl")
+		fmt.Fprintln(flags.Output(), "Requests with a successful result in the results file are skipped, so a rerun resumes.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("batch needs exactly one requests file")
+	}
+
+	input := flags.Arg(0)
+	resultsPath := *output
+	if resultsPath == "" {
+		resultsPath = strings.TrimSuffix(input, ".jsonl") + ".results.jsonl"
+	}
+
+	file, err := os.Open(input)
+	if err != nil {
+		return fmt.Errorf("failed to open requests: %w", err)
+	}
+	records, err := batch.ReadRecords(file)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", input, err)
+	}
+
+	completed, err := batch.Completed(resultsPath)
+	if err != nil {
+		return err
+	}
+	results, err := batch.OpenResults(resultsPath)
+	if err != nil {
+		return err
+	}
+	defer results.Close()
+
+	runner := batch.NewRunner(client, library)
+	runner.Workers = *workers
+	runner.Progress = func(result batch.Result) {
+		if result.Error != "" {
+			fmt.Printf("❌ %s: %s\n", result.ID, result.Error)
+			return
+		}
+		fmt.Printf("✅ %s (%s, %d output tokens)\n", result.ID, result.Latency.Round(time.Millisecond), result.Usage.OutputTokens)
+	}
+
+	fmt.Printf("📦 Running %d requests from %s into %s\n", len(records), input, resultsPath)
+	if len(completed) > 0 {
+		fmt.Println("⏭️  Resuming: requests already completed are skipped")
+	}
+
+	summary, err := runner.Run(ctx, records, completed, results)
+	fmt.Printf("\n📊 %d succeeded, %d failed, %d skipped of %d; %d calls, %d input / %d output tokens, $%.4f\n",
+		summary.Succeeded, summary.Failed, summary.Skipped, summary.Records,
+		summary.Usage.Calls, summary.Usage.InputTokens, summary.Usage.OutputTokens, summary.Usage.Cost)
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("⏸️  Interrupted: rerun the same command to resume")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return results.Close()
+}
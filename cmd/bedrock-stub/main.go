@@ -0,0 +1,35 @@
+// Command bedrock-stub serves a local Bedrock-compatible endpoint for offline and CI runs.
+// Point the application at it with AWS_ENDPOINT_URL=http://localhost:4010.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"aws-bedrock-prompt-engineering/internal/stub"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:4010", "address to listen on")
+	repliesPath := flag.String("replies", "", "JSON file with scripted replies (optional)")
+	chunkDelay := flag.Duration("chunk-delay", 30*time.Millisecond, "pause between streamed chunks")
+	flag.Parse()
+
+	var replies []stub.Reply
+	if *repliesPath != "" {
+		var err error
+		if replies, err = stub.LoadReplies(*repliesPath); err != nil {
+			log.Fatal("Error loading replies: ", err)
+		}
+	}
+
+	server, err := stub.NewServer(replies, *chunkDelay)
+	if err != nil {
+		log.Fatal("Error creating stub server: ", err)
+	}
+
+	log.Printf("🧪 Bedrock stub listening on http://%s (%d scripted replies)", *addr, len(replies))
+	log.Fatal(http.ListenAndServe(*addr, server))
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+	"aws-bedrock-prompt-engineering/internal/eval"
+	"aws-bedrock-prompt-engineering/internal/prompting"
+	"aws-bedrock-prompt-engineering/prompts"
+)
+
+// runEval scores techniques against golden datasets:
+//
+//	prompt-engineering eval [-technique name] [-model id] [-json file] [dataset ...]
+func runEval(ctx context.Context, client *bedrock.Client, library *prompting.Library, args []string) error {
+	flags := flag.NewFlagSet("eval", flag.ContinueOnError)
+	techniqueName := flags.String("technique", "", "technique to run every dataset with (default: each dataset's own)")
+	modelID := flags.String("model", "", "model ID to evaluate (default: MODEL_ID)")
+	concurrency := flags.Int("concurrency", 4, "cases to run at the same time")
+	jsonPath := flags.String("json", "", "also write the reports as JSON to this file")
+	judgeModel := flags.String("judge-model", "", "grader model for the judge metric (default: JUDGE_MODEL_ID, then MODEL_ID)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: prompt-engineering eval [flags] [dataset ...]")
+		fmt.Fprintln(flags.Output(), "Runs every dataset when none is named.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	var technique prompting.Technique
+	if *techniqueName != "" {
+		var ok bool
+		if technique, ok = prompting.Lookup(*techniqueName); !ok {
+			return fmt.Errorf("unknown technique %q", *techniqueName)
+		}
+	}
+
+	datasets, err := loadDatasets(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	library, err = prompting.WithVariants(library)
+	if err != nil {
+		return err
+	}
+	runner := eval.NewRunner(client, library)
+	runner.Concurrency = *concurrency
+	runner.Judge = eval.NewJudge(client, *judgeModel)
+
+	var reports []*eval.Report
+	for _, ds := range datasets {
+		fmt.Printf("\n🔄 Evaluating %s (%d cases)...\n", ds.Name, len(ds.Cases))
+		report, err := runner.Run(ctx, ds, technique, *modelID)
+		if err != nil {
+			return fmt.Errorf("failed to evaluate %s: %w", ds.Name, err)
+		}
+		if err := report.WriteText(os.Stdout); err != nil {
+			return err
+		}
+		reports = append(reports, report)
+	}
+	printJudgeUsage(runner.Judge)
+
+	if *jsonPath != "" {
+		if err := writeFile(*jsonPath, func(w io.Writer) error { return eval.WriteJSON(w, reports) }); err != nil {
+			return err
+		}
+		fmt.Printf("\n📝 Wrote %s\n", *jsonPath)
+	}
+	return nil
+}
+
+// runCompare runs datasets through several techniques and models and prints a comparison matrix:
+//
+//	prompt-engineering compare [-techniques a,b] [-models a,b] [-pairwise] [-markdown file] [-csv file] [dataset ...]
+func runCompare(ctx context.Context, client *bedrock.Client, library *prompting.Library, args []string) error {
+	flags := flag.NewFlagSet("compare", flag.ContinueOnError)
+	techniqueNames := flags.String("techniques", strings.Join([]string{prompting.TechniqueZeroShot, prompting.TechniqueFewShot, prompting.TechniqueChainOfThought}, ","), "comma-separated techniques to compare")
+	modelIDs := flags.String("models", "", "comma-separated model IDs to compare (default: MODEL_ID)")
+	concurrency := flags.Int("concurrency", 4, "cases to run at the same time")
+	markdownPath := flags.String("markdown", "", "also write the matrix as Markdown to this file")
+	csvPath := flags.String("csv", "", "also write the matrix as CSV to this file")
+	pairwise := flags.Bool("pairwise", false, "have a judge compare each output with the first technique and model's, for datasets with a rubric")
+	judgeModel := flags.String("judge-model", "", "grader model for the judge metric and -pairwise (default: JUDGE_MODEL_ID, then MODEL_ID)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: prompt-engineering compare [flags] [dataset ...]")
+		fmt.Fprintln(flags.Output(), "Compares every dataset when none is named.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	var techniques []prompting.Technique
+	for _, name := range splitList(*techniqueNames) {
+		technique, ok := prompting.Lookup(name)
+		if !ok {
+			return fmt.Errorf("unknown technique %q", name)
+		}
+		techniques = append(techniques, technique)
+	}
+	if len(techniques) == 0 {
+		return errors.New("no techniques to compare")
+	}
+	models := splitList(*modelIDs)
+
+	datasets, err := loadDatasets(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	library, err = prompting.WithVariants(library)
+	if err != nil {
+		return err
+	}
+	runner := eval.NewRunner(client, library)
+	runner.Concurrency = *concurrency
+	runner.Judge = eval.NewJudge(client, *judgeModel)
+
+	var matrices []*eval.Matrix
+	for _, ds := range datasets {
+		fmt.Printf("🔄 Comparing %s (%d cases)...\n", ds.Name, len(ds.Cases))
+		matrix, err := runner.Compare(ctx, ds, techniques, models)
+		if err != nil {
+			return fmt.Errorf("failed to compare %s: %w", ds.Name, err)
+		}
+
+		switch {
+		case !*pairwise:
+		case ds.Rubric == "":
+			fmt.Printf("⏭️  %s has no rubric to judge outputs pairwise with\n", ds.Name)
+		case len(matrix.Reports) < 2:
+			fmt.Printf("⏭️  %s has a single technique and model, so there is nothing to compare pairwise\n", ds.Name)
+		default:
+			fmt.Printf("⚖️  Judging %s pairwise with %s...\n", ds.Name, runner.Judge.ModelID())
+			if err := runner.JudgePairwise(ctx, ds, matrix); err != nil {
+				return fmt.Errorf("failed to judge %s: %w", ds.Name, err)
+			}
+		}
+		matrices = append(matrices, matrix)
+	}
+
+	fmt.Println()
+	if err := eval.WriteMarkdown(os.Stdout, matrices); err != nil {
+		return err
+	}
+
+	exports := []struct {
+		path  string
+		write func(io.Writer, []*eval.Matrix) error
+	}{
+		{*markdownPath, eval.WriteMarkdown},
+		{*csvPath, eval.WriteCSV},
+	}
+	for _, export := range exports {
+		if export.path == "" {
+			continue
+		}
+		if err := writeFile(export.path, func(w io.Writer) error { return export.write(w, matrices) }); err != nil {
+			return err
+		}
+		fmt.Printf("\n📝 Wrote %s\n", export.path)
+	}
+	printJudgeUsage(runner.Judge)
+	return nil
+}
+
+// printJudgeUsage reports what grading cost, when the judge was used
+func printJudgeUsage(judge *eval.Judge) {
+	usage := judge.Usage()
+	if usage.Calls == 0 {
+		return
+	}
+	fmt.Printf("\n⚖️  Judge %s: %d calls, %d input / %d output tokens, $%.4f\n",
+		judge.ModelID(), usage.Calls, usage.InputTokens, usage.OutputTokens, usage.Cost)
+}
+
+// writeFile creates path and fills it with write
+func writeFile(path string, write func(io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer file.Close()
+
+	if err := write(file); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return file.Close()
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// loadDatasets reads the datasets in EVAL_DATASET_DIR, or the ones built into the binary,
+// and keeps the named ones
+func loadDatasets(names []string) ([]*eval.Dataset, error) {
+	var fsys fs.FS
+	if dir := os.Getenv("EVAL_DATASET_DIR"); dir != "" {
+		fsys = os.DirFS(dir)
+	} else {
+		fsys, _ = fs.Sub(prompts.Datasets, "datasets")
+	}
+
+	datasets, err := eval.LoadDatasets(fsys)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return datasets, nil
+	}
+
+	var selected []*eval.Dataset
+	for _, name := range names {
+		i := slices.IndexFunc(datasets, func(ds *eval.Dataset) bool { return ds.Name == name })
+		if i < 0 {
+			var available []string
+			for _, ds := range datasets {
+				available = append(available, ds.Name)
+			}
+			return nil, fmt.Errorf("unknown dataset %q (available: %s)", name, strings.Join(available, ", "))
+		}
+		selected = append(selected, datasets[i])
+	}
+	return selected, nil
+}
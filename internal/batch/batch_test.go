@@ -0,0 +1,173 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock/bedrocktest"
+	"aws-bedrock-prompt-engineering/internal/prompting"
+)
+
+const testModelID = "anthropic.claude-3-haiku-20240307-v1:0"
+
+func TestReadRecords(t *testing.T) {
+	records, err := ReadRecords(strings.NewReader(`{"id": "review", "prompt": "text-classification", "variables": {"text": "Great!"}}
+
+{"template": "Summarize: {{doc}}", "variables": {"doc": "..."}, "params": {"temperature": 0.2}}
+`))
+	if err != nil {
+		t.Fatalf("ReadRecords() error = %v", err)
+	}
+	if len(records) != 2 || records[0].ID != "review" || records[1].ID != "line-3" {
+		t.Fatalf("ReadRecords() = %+v, want review and line-3", records)
+	}
+	if got := records[1].Params.Temperature; got == nil || *got != 0.2 {
+		t.Errorf("temperature = %v, want 0.2", got)
+	}
+}
+
+func TestReadRecordsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"invalid json", `{"prompt": `, "line 1"},
+		{"unknown field", `{"prompt": "p", "temprature": 1}`, "unknown field"},
+		{"no prompt", `{"id": "a", "variables": {}}`, "neither a prompt nor a template"},
+		{"both", `{"prompt": "p", "template": "t"}`, "both a prompt and a template"},
+		{"duplicate id", "{\"id\": \"a\", \"prompt\": \"p\"}\n{\"id\": \"a\", \"prompt\": \"q\"}", "already defined on line 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadRecords(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ReadRecords() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompletedAfterCrash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	results := `{"id": "a", "completion": "ok"}
+{"id": "b", "error": "ThrottlingException"}
+{"id": "c", "error": "ThrottlingException"}
+{"id": "c", "completion": "ok"}
+{"id": "d", "compl`
+	if err := os.WriteFile(path, []byte(results), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	completed, err := Completed(path)
+	if err != nil {
+		t.Fatalf("Completed() error = %v", err)
+	}
+	if len(completed) != 2 || !completed["a"] || !completed["c"] {
+		t.Errorf("Completed() = %v, want a and c", completed)
+	}
+
+	// Appending removes the cut-short line, so the file stays readable
+	file, err := OpenResults(path)
+	if err != nil {
+		t.Fatalf("OpenResults() error = %v", err)
+	}
+	file.WriteString(`{"id": "d", "completion": "ok"}` + "\n")
+	file.Close()
+	if completed, err = Completed(path); err != nil || len(completed) != 3 || !completed["d"] {
+		t.Errorf("Completed() = %v, %v, want a, c and d", completed, err)
+	}
+
+	missing, err := Completed(filepath.Join(t.TempDir(), "missing.jsonl"))
+	if err != nil || len(missing) != 0 {
+		t.Errorf("Completed(missing) = %v, %v, want no results", missing, err)
+	}
+}
+
+func TestRunnerRun(t *testing.T) {
+	t.Setenv("MODEL_ID", testModelID)
+
+	records, err := ReadRecords(strings.NewReader(`{"id": "review", "prompt": "text-classification", "variables": {"text": "Great service"}, "params": {"model_id": "anthropic.claude-3-5-sonnet-20240620-v1:0", "temperature": 0.2}}
+{"id": "summary", "template": "Summarize: {{doc}}", "variables": {"doc": "A long report"}}
+{"id": "done", "template": "Already {{x}}", "variables": {"x": "done"}}
+{"id": "unknown", "prompt": "no-such-prompt"}
+{"id": "missing", "template": "Translate {{text}}"}
+{"id": "throttled", "template": "Retry {{x}}", "variables": {"x": "me"}}
+`))
+	if err != nil {
+		t.Fatalf("ReadRecords() error = %v", err)
+	}
+	fake := bedrocktest.NewFake().
+		Respond(`Great service`, "positive").
+		Respond(`Summarize: A long report`, "A summary.").
+		Fail(`Retry me`, bedrocktest.ErrNoMatch)
+
+	runner := NewRunner(fake, prompting.DefaultLibrary())
+	var progress []string
+	runner.Progress = func(result Result) { progress = append(progress, result.ID) }
+
+	var out bytes.Buffer
+	summary, err := runner.Run(context.Background(), records, map[string]bool{"done": true}, &out)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if summary.Records != 6 || summary.Skipped != 1 || summary.Succeeded != 2 || summary.Failed != 3 || summary.Usage.Calls != 2 {
+		t.Errorf("Run() summary = %+v, want 2 succeeded, 3 failed, 1 skipped over 2 billed calls", summary)
+	}
+	if len(progress) != 5 {
+		t.Errorf("progress reported %v, want every record that ran", progress)
+	}
+
+	results := map[string]Result{}
+	for decoder := json.NewDecoder(&out); decoder.More(); {
+		var result Result
+		if err := decoder.Decode(&result); err != nil {
+			t.Fatalf("reading results: %v", err)
+		}
+		results[result.ID] = result
+	}
+
+	review := results["review"]
+	if review.Completion != "positive" || review.StopReason != "stop_sequence" || review.Technique != prompting.TechniqueZeroShot ||
+		review.ModelID != "anthropic.claude-3-5-sonnet-20240620-v1:0" || review.Usage.Calls != 1 || review.Latency <= 0 {
+		t.Errorf("review = %+v, want the overridden model's completion", review)
+	}
+	for _, call := range fake.Calls() {
+		if strings.Contains(call.Prompt, "Great service") && call.Params.Temperature != 0.2 {
+			t.Errorf("review temperature = %v, want the record's 0.2", call.Params.Temperature)
+		}
+	}
+	if summary := results["summary"]; summary.Completion != "A summary." || summary.ModelID != testModelID {
+		t.Errorf("summary = %+v, want the default model's completion", summary)
+	}
+	for id, want := range map[string]string{"unknown": "not in the prompt library", "missing": "text", "throttled": "no scripted reply"} {
+		if !strings.Contains(results[id].Error, want) {
+			t.Errorf("%s error = %q, want %q", id, results[id].Error, want)
+		}
+	}
+	if _, ok := results["done"]; ok {
+		t.Error("the completed record ran again")
+	}
+}
+
+func TestRunnerRunCancelled(t *testing.T) {
+	t.Setenv("MODEL_ID", testModelID)
+
+	records := []Record{{ID: "a", Template: "Say {{x}}", Variables: map[string]string{"x": "hi"}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var out bytes.Buffer
+	summary, err := NewRunner(bedrocktest.NewFake().Default("hi"), prompting.DefaultLibrary()).Run(ctx, records, nil, &out)
+	if err == nil {
+		t.Error("Run() error = nil, want the cancellation")
+	}
+	if out.Len() != 0 || summary.Failed != 0 {
+		t.Errorf("Run() wrote %q with %+v, want interrupted records left for the rerun", out.String(), summary)
+	}
+}
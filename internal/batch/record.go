@@ -0,0 +1,125 @@
+// Package batch runs JSONL files of prompt requests without the interactive menu: each line
+// names a library prompt or carries its own template, and each result is appended to a JSONL
+// results file as soon as it completes, so an interrupted batch resumes where it stopped.
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+	"aws-bedrock-prompt-engineering/internal/prompting"
+)
+
+// maxLineSize bounds one JSONL line; prompts with long documents fit comfortably
+const maxLineSize = 4 << 20
+
+// Record is one request of a batch file
+type Record struct {
+	ID        string                   `json:"id"`        // unique within the file; defaults to "line-N"
+	Technique string                   `json:"technique"` // defaults to the prompt's technique, or zero-shot for a template
+	Prompt    string                   `json:"prompt"`    // library prompt to render, e.g. "text-classification"
+	Template  string                   `json:"template"`  // prompt text with {{variable}} placeholders, instead of a library prompt
+	Variables map[string]string        `json:"variables"` // values for the placeholders; sample inputs of library prompts are not used
+	Params    prompting.ParamOverrides `json:"params"`    // changes to the technique's and prompt's ModelParams
+}
+
+// Result is one line of a results file
+type Result struct {
+	ID         string              `json:"id"`
+	Technique  string              `json:"technique,omitempty"`
+	ModelID    string              `json:"model_id,omitempty"`
+	Completion string              `json:"completion,omitempty"`
+	StopReason string              `json:"stop_reason,omitempty"`
+	Usage      bedrock.UsageTotals `json:"usage"` // every call the record made, e.g. each step of a multi-step technique
+	Latency    time.Duration       `json:"latency_ns"`
+	Error      string              `json:"error,omitempty"`
+}
+
+// ReadRecords decodes a JSONL batch file. Blank lines are skipped; a line that does not decode,
+// a record with neither or both of prompt and template, and a repeated ID are errors.
+func ReadRecords(r io.Reader) ([]Record, error) {
+	var records []Record
+	ids := map[string]int{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		var record Record
+		if err := decoder.Decode(&record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if record.ID == "" {
+			record.ID = fmt.Sprintf("line-%d", line)
+		}
+		switch {
+		case record.Prompt == "" && record.Template == "":
+			return nil, fmt.Errorf("line %d: record %q has neither a prompt nor a template", line, record.ID)
+		case record.Prompt != "" && record.Template != "":
+			return nil, fmt.Errorf("line %d: record %q has both a prompt and a template", line, record.ID)
+		}
+		if first, ok := ids[record.ID]; ok {
+			return nil, fmt.Errorf("line %d: record %q is already defined on line %d", line, record.ID, first)
+		}
+
+		ids[record.ID] = line
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch file: %w", err)
+	}
+	return records, nil
+}
+
+// Completed returns the IDs with a successful result in a results file, so a rerun can skip
+// them. Failed results are retried. A missing file has no results, and a final line cut short
+// by a crash is ignored.
+func Completed(path string) (map[string]bool, error) {
+	completed := map[string]bool{}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return completed, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results: %w", err)
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var result Result
+		if err := json.Unmarshal(line, &result); err != nil {
+			if i == len(lines)-1 {
+				break
+			}
+			return nil, fmt.Errorf("failed to read results: line %d: %w", i+1, err)
+		}
+		// Later lines are later attempts of the same record
+		completed[result.ID] = result.Error == ""
+	}
+
+	for id, ok := range completed {
+		if !ok {
+			delete(completed, id)
+		}
+	}
+	return completed, nil
+}
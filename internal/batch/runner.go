@@ -0,0 +1,230 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+	"aws-bedrock-prompt-engineering/internal/prompting"
+)
+
+// Runner runs batch records with a bounded pool of workers
+type Runner struct {
+	client  bedrock.Invoker
+	library *prompting.Library
+
+	Workers  int                // records run at the same time
+	Prices   bedrock.PriceTable // prices the reported token usage
+	Progress func(Result)       // called after each result is written; calls never overlap
+}
+
+// Summary counts what a run did
+type Summary struct {
+	Records   int                 // records in the batch
+	Skipped   int                 // records already completed by an earlier run
+	Succeeded int                 // records that ran and succeeded
+	Failed    int                 // records that ran and failed; a rerun retries them
+	Usage     bedrock.UsageTotals // every call of this run
+}
+
+// NewRunner creates a runner that renders records with library prompts and sends them to client
+func NewRunner(client bedrock.Invoker, library *prompting.Library) *Runner {
+	return &Runner{
+		client:  client,
+		library: library,
+		Workers: 4,
+		Prices:  bedrock.DefaultPrices,
+	}
+}
+
+// Run executes every record whose ID is not in completed and writes one JSON result line per
+// record to out as soon as it finishes, in completion order. A record that fails is written with
+// its error and the batch carries on. Records interrupted by ctx are not written, so a rerun
+// picks them up; Run then returns ctx's error along with the summary so far.
+func (r *Runner) Run(ctx context.Context, records []Record, completed map[string]bool, out io.Writer) (*Summary, error) {
+	summary := &Summary{Records: len(records)}
+	var pending []Record
+	for _, record := range records {
+		if completed[record.ID] {
+			summary.Skipped++
+			continue
+		}
+		pending = append(pending, record)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan Record)
+	go func() {
+		defer close(jobs)
+		for _, record := range pending {
+			select {
+			case jobs <- record:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var (
+		mu       sync.Mutex
+		writeErr error
+		encoder  = json.NewEncoder(out)
+	)
+	write := func(result Result) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if writeErr != nil {
+			return
+		}
+		// Each result is a single write, so a crash leaves at most the last line incomplete
+		if err := encoder.Encode(result); err != nil {
+			writeErr = fmt.Errorf("failed to write result: %w", err)
+			cancel()
+			return
+		}
+
+		if result.Error == "" {
+			summary.Succeeded++
+		} else {
+			summary.Failed++
+		}
+		summary.Usage.Calls += result.Usage.Calls
+		summary.Usage.InputTokens += result.Usage.InputTokens
+		summary.Usage.OutputTokens += result.Usage.OutputTokens
+		summary.Usage.Cost += result.Usage.Cost
+		summary.Usage.Unpriced += result.Usage.Unpriced
+		if r.Progress != nil {
+			r.Progress(result)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for range max(r.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for record := range jobs {
+				result := r.runRecord(ctx, record)
+				if ctx.Err() != nil && result.Error != "" {
+					continue // interrupted, not failed
+				}
+				write(result)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if writeErr != nil {
+		return summary, writeErr
+	}
+	return summary, context.Cause(ctx)
+}
+
+func (r *Runner) runRecord(ctx context.Context, record Record) Result {
+	result := Result{ID: record.ID}
+
+	spec, technique, err := r.resolve(record)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Technique = technique.Info().Name
+
+	// The record's params win over the prompt's, which win over the technique's
+	overridden := *spec
+	overridden.Params = spec.Params.Merge(record.Params)
+	params := technique.DefaultParams()
+	result.ModelID = overridden.Params.Apply(params).ModelID
+
+	// Multi-step techniques make several calls per record; the ledger counts them all
+	ledger := bedrock.NewLedger(r.Prices)
+	start := time.Now()
+	executed, err := technique.Execute(ctx, ledger.Meter(r.client, result.Technique), &overridden, params, record.Variables)
+	result.Latency = time.Since(start)
+	result.Usage = ledger.Session()
+
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Completion = executed.Response.Completion
+	result.StopReason = executed.Response.StopReason
+	return result
+}
+
+// resolve returns the prompt and technique a record runs with
+func (r *Runner) resolve(record Record) (*prompting.PromptSpec, prompting.Technique, error) {
+	var spec *prompting.PromptSpec
+	if record.Template != "" {
+		name := record.Technique
+		if name == "" {
+			name = prompting.TechniqueZeroShot
+		}
+		var err error
+		if spec, err = prompting.InlineSpec(record.ID, name, record.Template); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		var ok bool
+		if spec, ok = r.library.Get(record.Prompt); !ok {
+			return nil, nil, fmt.Errorf("prompt %q is not in the prompt library", record.Prompt)
+		}
+	}
+
+	if record.Technique == "" {
+		return spec, prompting.TechniqueFor(spec.Technique), nil
+	}
+	technique, ok := prompting.Lookup(record.Technique)
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown technique %q", record.Technique)
+	}
+	return spec, technique, nil
+}
+
+// OpenResults opens a results file for appending, creating it if needed. A last line cut short
+// by a crash is removed first; its record has no result yet, so it runs again.
+func OpenResults(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open results: %w", err)
+	}
+	if err := trimPartialLine(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open results: %w", err)
+	}
+	return file, nil
+}
+
+// trimPartialLine truncates file after its last newline, reading backwards from the end
+func trimPartialLine(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 4096)
+	for end := info.Size(); end > 0; {
+		start := max(end-int64(len(buf)), 0)
+		chunk := buf[:end-start]
+		if _, err := file.ReadAt(chunk, start); err != nil {
+			return err
+		}
+		if i := bytes.LastIndexByte(chunk, '\n'); i >= 0 {
+			if start+int64(i)+1 == info.Size() {
+				return nil
+			}
+			return file.Truncate(start + int64(i) + 1)
+		}
+		end = start
+	}
+	return file.Truncate(0)
+}
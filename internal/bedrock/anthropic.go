@@ -0,0 +1,196 @@
+package bedrock
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// anthropicVersion is the Messages API version Bedrock expects in every request body
+const anthropicVersion = "bedrock-2023-05-31"
+
+// ContentBlock is a single block of a Messages API message
+type ContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+type message struct {
+	Role    string         `json:"role"`
+	Content []ContentBlock `json:"content"`
+}
+
+type messagesRequest struct {
+	AnthropicVersion string    `json:"anthropic_version"`
+	MaxTokens        int       `json:"max_tokens"`
+	System           string    `json:"system,omitempty"`
+	Messages         []message `json:"messages"`
+	Temperature      float64   `json:"temperature"`
+	TopP             float64   `json:"top_p,omitempty"`
+	TopK             int       `json:"top_k,omitempty"`
+	StopSequences    []string  `json:"stop_sequences,omitempty"`
+}
+
+type textCompletionChunk struct {
+	Completion string  `json:"completion"`
+	StopReason *string `json:"stop_reason"`
+	Stop       *string `json:"stop"`
+}
+
+type messagesEvent struct {
+	Type    string            `json:"type"`
+	Message *messagesResponse `json:"message"`
+	Delta   *struct {
+		Type         string  `json:"type"`
+		Text         string  `json:"text"`
+		StopReason   string  `json:"stop_reason"`
+		StopSequence *string `json:"stop_sequence"`
+	} `json:"delta"`
+	Usage *Usage `json:"usage"`
+}
+
+type messagesResponse struct {
+	ID           string         `json:"id"`
+	Type         string         `json:"type"`
+	Role         string         `json:"role"`
+	Model        string         `json:"model"`
+	Content      []ContentBlock `json:"content"`
+	StopReason   string         `json:"stop_reason"`
+	StopSequence *string        `json:"stop_sequence"`
+	Usage        Usage          `json:"usage"`
+}
+
+type anthropicTextProvider struct{}
+
+func (anthropicTextProvider) Name() string { return "anthropic" }
+
+// EncodeRequest builds the legacy Claude v2 / Instant text-completion body
+func (anthropicTextProvider) EncodeRequest(prompt string, params ModelParams) ([]byte, error) {
+	requestBody := map[string]any{
+		"prompt":               fmt.Sprintf("%s\n\nHuman: %s\n\nAssistant:", params.System, prompt),
+		"temperature":          params.Temperature,
+		"top_p":                params.TopP,
+		"top_k":                params.TopK,
+		"max_tokens_to_sample": params.MaxTokens,
+	}
+
+	if len(params.StopSequences) > 0 {
+		requestBody["stop_sequences"] = params.StopSequences
+	}
+
+	return json.Marshal(requestBody)
+}
+
+func (anthropicTextProvider) DecodeResponse(body []byte) (*ModelResponse, error) {
+	var modelResp ModelResponse
+	if err := json.Unmarshal(body, &modelResp); err != nil {
+		return nil, err
+	}
+
+	return &modelResp, nil
+}
+
+func (anthropicTextProvider) DecodeChunk(chunk []byte, resp *ModelResponse) (string, error) {
+	var part textCompletionChunk
+	if err := json.Unmarshal(chunk, &part); err != nil {
+		return "", err
+	}
+
+	resp.Type = "completion"
+	if part.StopReason != nil {
+		resp.StopReason = *part.StopReason
+	}
+	if part.Stop != nil {
+		resp.Stop = *part.Stop
+	}
+
+	return part.Completion, nil
+}
+
+type anthropicMessagesProvider struct{}
+
+func (anthropicMessagesProvider) Name() string { return "anthropic" }
+
+// EncodeRequest builds the Messages API body used by Claude 3 and later
+func (anthropicMessagesProvider) EncodeRequest(prompt string, params ModelParams) ([]byte, error) {
+	request := messagesRequest{
+		AnthropicVersion: anthropicVersion,
+		MaxTokens:        params.MaxTokens,
+		System:           params.System,
+		Messages: []message{
+			{Role: "user", Content: []ContentBlock{{Type: "text", Text: prompt}}},
+		},
+		Temperature:   params.Temperature,
+		TopK:          params.TopK,
+		StopSequences: params.StopSequences,
+	}
+
+	// Newer Claude models reject requests that set both temperature and top_p,
+	// so only send top_p when it actually narrows sampling
+	if params.TopP > 0 && params.TopP < 1 {
+		request.TopP = params.TopP
+	}
+
+	return json.Marshal(request)
+}
+
+func (anthropicMessagesProvider) DecodeResponse(body []byte) (*ModelResponse, error) {
+	var resp messagesResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	var completion strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			completion.WriteString(block.Text)
+		}
+	}
+
+	modelResp := &ModelResponse{
+		Type:       resp.Type,
+		Completion: completion.String(),
+		StopReason: resp.StopReason,
+		Content:    resp.Content,
+		Usage:      &resp.Usage,
+	}
+	if resp.StopSequence != nil {
+		modelResp.Stop = *resp.StopSequence
+	}
+
+	return modelResp, nil
+}
+
+func (anthropicMessagesProvider) DecodeChunk(chunk []byte, resp *ModelResponse) (string, error) {
+	var event messagesEvent
+	if err := json.Unmarshal(chunk, &event); err != nil {
+		return "", err
+	}
+
+	switch event.Type {
+	case "message_start":
+		if event.Message != nil {
+			resp.Type = event.Message.Type
+			resp.Usage = &Usage{InputTokens: event.Message.Usage.InputTokens}
+		}
+	case "content_block_delta":
+		if event.Delta != nil && event.Delta.Type == "text_delta" {
+			return event.Delta.Text, nil
+		}
+	case "message_delta":
+		if event.Delta != nil {
+			resp.StopReason = event.Delta.StopReason
+			if event.Delta.StopSequence != nil {
+				resp.Stop = *event.Delta.StopSequence
+			}
+		}
+		if event.Usage != nil {
+			if resp.Usage == nil {
+				resp.Usage = &Usage{}
+			}
+			resp.Usage.OutputTokens = event.Usage.OutputTokens
+		}
+	}
+
+	return "", nil
+}
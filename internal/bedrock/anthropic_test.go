@@ -0,0 +1,101 @@
+package bedrock
+
+import "testing"
+
+func TestAnthropicTextAdapter(t *testing.T) {
+	runAdapterCases(t, anthropicTextProvider{}, []adapterCase{
+		{
+			name:    "request",
+			prompt:  "Why is the sky blue?",
+			params:  ModelParams{Temperature: 0.5, TopP: 1, TopK: 250, MaxTokens: 300, StopSequences: []string{"\n\nHuman:"}},
+			request: `{"prompt": "\n\nHuman: Why is the sky blue?\n\nAssistant:", "temperature": 0.5, "top_p": 1, "top_k": 250, "max_tokens_to_sample": 300, "stop_sequences": ["\n\nHuman:"]}`,
+		},
+		{
+			name:    "request with system prompt",
+			prompt:  "Why is the sky blue?",
+			params:  ModelParams{System: "You are terse.", Temperature: 0, TopP: 0.9, TopK: 50, MaxTokens: 100},
+			request: `{"prompt": "You are terse.\n\nHuman: Why is the sky blue?\n\nAssistant:", "temperature": 0, "top_p": 0.9, "top_k": 50, "max_tokens_to_sample": 100}`,
+		},
+		{
+			name:     "response",
+			response: `{"completion": " Because of Rayleigh scattering.", "stop_reason": "stop_sequence", "stop": "\n\nHuman:"}`,
+			want:     &ModelResponse{Completion: " Because of Rayleigh scattering.", StopReason: "stop_sequence", Stop: "\n\nHuman:"},
+		},
+		{
+			name:     "response cut at max tokens",
+			response: `{"completion": " Because of", "stop_reason": "max_tokens", "stop": null}`,
+			want:     &ModelResponse{Completion: " Because of", StopReason: "max_tokens"},
+		},
+		{
+			name: "stream",
+			chunks: []string{
+				`{"completion": " Because of", "stop_reason": null, "stop": null}`,
+				`{"completion": " Rayleigh scattering.", "stop_reason": "stop_sequence", "stop": "\n\nHuman:", "amazon-bedrock-invocationMetrics": {"inputTokenCount": 14, "outputTokenCount": 6, "invocationLatency": 512, "firstByteLatency": 301}}`,
+			},
+			want: &ModelResponse{Type: "completion", Completion: " Because of Rayleigh scattering.", StopReason: "stop_sequence", Stop: "\n\nHuman:", Usage: &Usage{InputTokens: 14, OutputTokens: 6}},
+		},
+	})
+}
+
+func TestAnthropicMessagesAdapter(t *testing.T) {
+	runAdapterCases(t, anthropicMessagesProvider{}, []adapterCase{
+		{
+			// top_p of 1 does not narrow sampling, so it is left out for models that reject it next to temperature
+			name:    "request",
+			prompt:  "Why is the sky blue?",
+			params:  ModelParams{System: "You are a physicist.", Temperature: 0.5, TopP: 1, TopK: 250, MaxTokens: 300},
+			request: `{"anthropic_version": "bedrock-2023-05-31", "max_tokens": 300, "system": "You are a physicist.", "messages": [{"role": "user", "content": [{"type": "text", "text": "Why is the sky blue?"}]}], "temperature": 0.5, "top_k": 250}`,
+		},
+		{
+			name:    "request with top_p and stop sequences",
+			prompt:  "Why is the sky blue?",
+			params:  ModelParams{Temperature: 0, TopP: 0.9, MaxTokens: 100, StopSequences: []string{"Human:"}},
+			request: `{"anthropic_version": "bedrock-2023-05-31", "max_tokens": 100, "messages": [{"role": "user", "content": [{"type": "text", "text": "Why is the sky blue?"}]}], "temperature": 0, "top_p": 0.9, "stop_sequences": ["Human:"]}`,
+		},
+		{
+			name: "response",
+			response: `{
+				"id": "msg_bdrk_01XFDUDYJgAACzvnptvVoYEL",
+				"type": "message",
+				"role": "assistant",
+				"model": "claude-3-haiku-20240307",
+				"content": [{"type": "text", "text": "Rayleigh scattering."}],
+				"stop_reason": "end_turn",
+				"stop_sequence": null,
+				"usage": {"input_tokens": 14, "output_tokens": 6}
+			}`,
+			want: &ModelResponse{
+				Type:       "message",
+				Completion: "Rayleigh scattering.",
+				StopReason: "end_turn",
+				Content:    []ContentBlock{{Type: "text", Text: "Rayleigh scattering."}},
+				Usage:      &Usage{InputTokens: 14, OutputTokens: 6},
+			},
+		},
+		{
+			name:     "response stopped by a stop sequence",
+			response: `{"id": "msg_1", "type": "message", "role": "assistant", "content": [{"type": "text", "text": "Blue"}], "stop_reason": "stop_sequence", "stop_sequence": "Human:", "usage": {"input_tokens": 14, "output_tokens": 1}}`,
+			want: &ModelResponse{
+				Type:       "message",
+				Completion: "Blue",
+				StopReason: "stop_sequence",
+				Stop:       "Human:",
+				Content:    []ContentBlock{{Type: "text", Text: "Blue"}},
+				Usage:      &Usage{InputTokens: 14, OutputTokens: 1},
+			},
+		},
+		{
+			name: "stream",
+			chunks: []string{
+				`{"type": "message_start", "message": {"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-3-haiku-20240307", "content": [], "stop_reason": null, "stop_sequence": null, "usage": {"input_tokens": 14, "output_tokens": 1}}}`,
+				`{"type": "content_block_start", "index": 0, "content_block": {"type": "text", "text": ""}}`,
+				`{"type": "content_block_delta", "index": 0, "delta": {"type": "text_delta", "text": "Rayleigh"}}`,
+				`{"type": "content_block_delta", "index": 0, "delta": {"type": "text_delta", "text": " scattering."}}`,
+				`{"type": "content_block_stop", "index": 0}`,
+				`{"type": "message_delta", "delta": {"stop_reason": "end_turn", "stop_sequence": null}, "usage": {"output_tokens": 6}}`,
+				`{"type": "message_stop"}`,
+			},
+			want: &ModelResponse{Type: "message", Completion: "Rayleigh scattering.", StopReason: "end_turn", Usage: &Usage{InputTokens: 14, OutputTokens: 6}},
+		},
+	})
+}
@@ -0,0 +1,209 @@
+// Package bedrocktest provides an in-process stand-in for the Bedrock client
+// so prompting code can be tested without AWS credentials or network access.
+package bedrocktest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+)
+
+// ErrNoMatch is returned when no scripted reply matches a prompt and no default is set
+var ErrNoMatch = errors.New("bedrocktest: no scripted reply matches prompt")
+
+// Call records a single InvokeModel or Converse call received by the fake
+type Call struct {
+	Prompt   string
+	Params   bedrock.ModelParams
+	Messages []bedrock.Message  // Converse only
+	Tools    []bedrock.ToolSpec // Converse only
+}
+
+type rule struct {
+	pattern  *regexp.Regexp
+	response *bedrock.ModelResponse
+	err      error
+	sequence []*bedrock.ModelResponse
+	next     int
+}
+
+// reply returns the rule's response, advancing through a sequence; callers hold the fake's lock
+func (r *rule) reply() (*bedrock.ModelResponse, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(r.sequence) == 0 {
+		return r.response, nil
+	}
+
+	response := r.sequence[min(r.next, len(r.sequence)-1)]
+	r.next++
+	return response, nil
+}
+
+// Fake is a scriptable bedrock.Invoker and bedrock.Converser. Replies are matched against the prompt by regular
+// expression in the order they were added; the first match wins.
+type Fake struct {
+	mu       sync.Mutex
+	rules    []rule
+	fallback *rule
+	latency  time.Duration
+	calls    []Call
+}
+
+// NewFake creates a fake with no scripted replies
+func NewFake() *Fake {
+	return &Fake{}
+}
+
+// Respond replies with completion to every prompt matching pattern
+func (f *Fake) Respond(pattern, completion string) *Fake {
+	return f.RespondWith(pattern, &bedrock.ModelResponse{
+		Type:       "completion",
+		Completion: completion,
+		StopReason: "stop_sequence",
+	})
+}
+
+// RespondWith replies with a full response to every prompt matching pattern
+func (f *Fake) RespondWith(pattern string, response *bedrock.ModelResponse) *Fake {
+	return f.add(rule{pattern: regexp.MustCompile(pattern), response: response})
+}
+
+// RespondSequence replies to successive prompts matching pattern with successive completions,
+// repeating the last one once they run out
+func (f *Fake) RespondSequence(pattern string, completions ...string) *Fake {
+	r := rule{pattern: regexp.MustCompile(pattern)}
+	for _, completion := range completions {
+		r.sequence = append(r.sequence, &bedrock.ModelResponse{
+			Type:       "completion",
+			Completion: completion,
+			StopReason: "stop_sequence",
+		})
+	}
+	return f.add(r)
+}
+
+// Fail returns err for every prompt matching pattern
+func (f *Fake) Fail(pattern string, err error) *Fake {
+	return f.add(rule{pattern: regexp.MustCompile(pattern), err: err})
+}
+
+// Default replies with completion to prompts that match no other rule
+func (f *Fake) Default(completion string) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.fallback = &rule{response: &bedrock.ModelResponse{
+		Type:       "completion",
+		Completion: completion,
+		StopReason: "stop_sequence",
+	}}
+	return f
+}
+
+// WithLatency delays every reply by d
+func (f *Fake) WithLatency(d time.Duration) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.latency = d
+	return f
+}
+
+// Calls returns every call received so far, in order
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Call(nil), f.calls...)
+}
+
+// InvokeModel implements bedrock.Invoker. A cancelled ctx cuts the scripted latency short
+// and returns the context error, like the real client.
+func (f *Fake) InvokeModel(ctx context.Context, prompt string, params bedrock.ModelParams) (*bedrock.ModelResponse, error) {
+	return f.invoke(ctx, Call{Prompt: prompt, Params: params})
+}
+
+// Converse implements bedrock.Converser. Rules are matched against the text of the last message,
+// or against its tool result contents joined by newlines when it has no text.
+func (f *Fake) Converse(ctx context.Context, messages []bedrock.Message, params bedrock.ModelParams, tools ...bedrock.ToolSpec) (*bedrock.ModelResponse, error) {
+	var prompt string
+	if len(messages) > 0 {
+		last := messages[len(messages)-1]
+		prompt = last.Content
+		if prompt == "" {
+			var results []string
+			for _, result := range last.ToolResults {
+				results = append(results, result.Content)
+			}
+			prompt = strings.Join(results, "\n")
+		}
+	}
+
+	return f.invoke(ctx, Call{Prompt: prompt, Params: params, Messages: slices.Clone(messages), Tools: tools})
+}
+
+func (f *Fake) invoke(ctx context.Context, call Call) (*bedrock.ModelResponse, error) {
+	prompt := call.Prompt
+
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	matched := f.match(prompt)
+	var scripted *bedrock.ModelResponse
+	var scriptedErr error
+	if matched != nil {
+		scripted, scriptedErr = matched.reply()
+	}
+	latency := f.latency
+	f.mu.Unlock()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if matched == nil {
+		return nil, fmt.Errorf("%w: %.60q", ErrNoMatch, prompt)
+	}
+	if scriptedErr != nil {
+		return nil, scriptedErr
+	}
+
+	// Hand out a copy so callers cannot mutate the scripted reply
+	response := *scripted
+	return &response, nil
+}
+
+func (f *Fake) add(r rule) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.rules = append(f.rules, r)
+	return f
+}
+
+func (f *Fake) match(prompt string) *rule {
+	for i := range f.rules {
+		if f.rules[i].pattern.MatchString(prompt) {
+			return &f.rules[i]
+		}
+	}
+
+	return f.fallback
+}
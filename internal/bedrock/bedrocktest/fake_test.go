@@ -0,0 +1,108 @@
+package bedrocktest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+)
+
+func TestFakeMatchesFirstRule(t *testing.T) {
+	fake := NewFake().
+		Respond(`(?i)sentiment`, "positive").
+		Respond(`.*`, "catch-all")
+
+	resp, err := fake.InvokeModel(context.Background(), "Classify the sentiment", bedrock.ModelParams{})
+	if err != nil {
+		t.Fatalf("InvokeModel() error = %v", err)
+	}
+	if resp.Completion != "positive" {
+		t.Errorf("Completion = %q, want %q", resp.Completion, "positive")
+	}
+
+	resp, err = fake.InvokeModel(context.Background(), "Translate this", bedrock.ModelParams{})
+	if err != nil {
+		t.Fatalf("InvokeModel() error = %v", err)
+	}
+	if resp.Completion != "catch-all" {
+		t.Errorf("Completion = %q, want %q", resp.Completion, "catch-all")
+	}
+}
+
+func TestFakeUnmatchedPrompt(t *testing.T) {
+	fake := NewFake().Respond(`sentiment`, "positive")
+
+	if _, err := fake.InvokeModel(context.Background(), "something else", bedrock.ModelParams{}); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("InvokeModel() error = %v, want ErrNoMatch", err)
+	}
+
+	fake.Default("fallback")
+	resp, err := fake.InvokeModel(context.Background(), "something else", bedrock.ModelParams{})
+	if err != nil {
+		t.Fatalf("InvokeModel() error = %v", err)
+	}
+	if resp.Completion != "fallback" {
+		t.Errorf("Completion = %q, want %q", resp.Completion, "fallback")
+	}
+}
+
+func TestFakeInjectedError(t *testing.T) {
+	injected := errors.New("throttled")
+	fake := NewFake().Fail(`.*`, injected)
+
+	if _, err := fake.InvokeModel(context.Background(), "anything", bedrock.ModelParams{}); !errors.Is(err, injected) {
+		t.Errorf("InvokeModel() error = %v, want %v", err, injected)
+	}
+}
+
+func TestFakeRespondSequence(t *testing.T) {
+	fake := NewFake().RespondSequence(`count`, "one", "two")
+
+	for _, want := range []string{"one", "two", "two"} {
+		resp, err := fake.InvokeModel(context.Background(), "count", bedrock.ModelParams{})
+		if err != nil {
+			t.Fatalf("InvokeModel() error = %v", err)
+		}
+		if resp.Completion != want {
+			t.Errorf("Completion = %q, want %q", resp.Completion, want)
+		}
+	}
+}
+
+func TestFakeLatencyAndCalls(t *testing.T) {
+	fake := NewFake().Default("ok").WithLatency(20 * time.Millisecond)
+	params := bedrock.ModelParams{ModelID: "anthropic.claude-v2:1", Temperature: 0.3}
+
+	start := time.Now()
+	if _, err := fake.InvokeModel(context.Background(), "hello", params); err != nil {
+		t.Fatalf("InvokeModel() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("InvokeModel() returned after %v, want at least 20ms", elapsed)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("len(Calls()) = %d, want 1", len(calls))
+	}
+	if calls[0].Prompt != "hello" || calls[0].Params.ModelID != params.ModelID || calls[0].Params.Temperature != params.Temperature {
+		t.Errorf("Calls()[0] = %+v, want prompt %q with %+v", calls[0], "hello", params)
+	}
+}
+
+func TestFakeCancelledContext(t *testing.T) {
+	fake := NewFake().Default("ok").WithLatency(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := fake.InvokeModel(ctx, "hello", bedrock.ModelParams{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("InvokeModel() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("InvokeModel() returned after %v, want it cut short by the deadline", elapsed)
+	}
+}
@@ -0,0 +1,245 @@
+package bedrock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// CassetteMode selects whether a cassette records live traffic or replays it
+type CassetteMode string
+
+const (
+	CassetteRecord CassetteMode = "record"
+	CassetteReplay CassetteMode = "replay"
+)
+
+// ErrCassetteMiss is returned in replay mode when a request has no recorded interaction
+var ErrCassetteMiss = errors.New("no recorded interaction matches request")
+
+// Interaction is one recorded request/response pair
+type Interaction struct {
+	Method          string            `json:"method"`
+	Path            string            `json:"path"`
+	RequestBody     json.RawMessage   `json:"request_body"`
+	Status          int               `json:"status"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	ResponseBody    json.RawMessage   `json:"response_body,omitempty"`  // JSON responses, stored readable
+	ResponseBytes   []byte            `json:"response_bytes,omitempty"` // event streams and other non-JSON bodies, base64 in the file
+}
+
+// CassetteTransport is an http.RoundTripper that records Bedrock traffic to a cassette file,
+// or serves it back from one. Requests are matched on method, path and request body, so the same
+// prompt with the same ModelParams always replays the same response.
+type CassetteTransport struct {
+	path string
+	mode CassetteMode
+	next http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	replayed     map[int]bool
+}
+
+// recordedHeaders are the response headers worth keeping; the rest change on every call
+var recordedHeaders = []string{"Content-Type", "X-Amzn-Errortype", "X-Amzn-Bedrock-Input-Token-Count", "X-Amzn-Bedrock-Output-Token-Count"}
+
+// NewCassetteTransport opens the cassette at path. In replay mode the file must exist;
+// in record mode new interactions are appended to it. next performs the live calls when recording.
+func NewCassetteTransport(path string, mode CassetteMode, next http.RoundTripper) (*CassetteTransport, error) {
+	if mode != CassetteRecord && mode != CassetteReplay {
+		return nil, fmt.Errorf("unknown cassette mode %q (want %q or %q)", mode, CassetteRecord, CassetteReplay)
+	}
+
+	t := &CassetteTransport{
+		path:     path,
+		mode:     mode,
+		next:     next,
+		replayed: map[int]bool{},
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &t.interactions); err != nil {
+			return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist) && mode == CassetteRecord:
+		// A new cassette is created on the first recorded interaction
+	default:
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	return t, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *CassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.mode == CassetteReplay {
+		return t.replay(req, body)
+	}
+	return t.record(req, body)
+}
+
+func (t *CassetteTransport) replay(req *http.Request, body []byte) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Identical requests are served in recorded order; once all are used the last one repeats
+	match := -1
+	for i, interaction := range t.interactions {
+		if !interaction.matches(req, body) {
+			continue
+		}
+		match = i
+		if !t.replayed[i] {
+			break
+		}
+	}
+
+	if match < 0 {
+		return nil, fmt.Errorf("cassette %s: %w: %s %s %.200s", t.path, ErrCassetteMiss, req.Method, req.URL.Path, body)
+	}
+	t.replayed[match] = true
+
+	interaction := t.interactions[match]
+	resp := &http.Response{
+		StatusCode: interaction.Status,
+		Status:     fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Request:    req,
+	}
+	for name, value := range interaction.ResponseHeaders {
+		resp.Header.Set(name, value)
+	}
+
+	payload := compactJSON(interaction.ResponseBody)
+	if interaction.ResponseBytes != nil {
+		payload = interaction.ResponseBytes
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(payload))
+	resp.ContentLength = int64(len(payload))
+
+	return resp, nil
+}
+
+func (t *CassetteTransport) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := Interaction{
+		Method:          req.Method,
+		Path:            req.URL.Path,
+		RequestBody:     rawJSON(body),
+		Status:          resp.StatusCode,
+		ResponseHeaders: map[string]string{},
+	}
+	for _, name := range recordedHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			interaction.ResponseHeaders[name] = value
+		}
+	}
+
+	// The response is passed through as it is read, so streams still arrive live,
+	// and the interaction is saved once the body has been fully consumed
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		done: func(payload []byte) {
+			if json.Valid(payload) {
+				interaction.ResponseBody = compactJSON(payload)
+			} else {
+				interaction.ResponseBytes = payload
+			}
+			t.save(interaction)
+		},
+	}
+
+	return resp, nil
+}
+
+func (t *CassetteTransport) save(interaction Interaction) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.interactions = append(t.interactions, interaction)
+
+	data, err := json.MarshalIndent(t.interactions, "", "  ")
+	if err == nil {
+		err = os.WriteFile(t.path, data, 0o644)
+	}
+	if err != nil {
+		// The live response is still returned; only the recording is lost
+		fmt.Fprintf(os.Stderr, "cassette %s: failed to save interaction: %v\n", t.path, err)
+	}
+}
+
+// matches compares the live body in the form record stored it, so non-JSON bodies, saved as quoted strings, match too
+func (i Interaction) matches(req *http.Request, body []byte) bool {
+	return i.Method == req.Method && i.Path == req.URL.Path && bytes.Equal(compactJSON(i.RequestBody), rawJSON(body))
+}
+
+// recordingBody buffers everything read through it and reports the payload once it reaches EOF.
+// Bodies closed early, such as cancelled streams, are not recorded.
+type recordingBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	once sync.Once
+	done func(payload []byte)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.once.Do(func() { b.done(b.buf.Bytes()) })
+	}
+	return n, err
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
+
+// rawJSON keeps valid JSON as-is so cassettes stay readable, and quotes anything else
+func rawJSON(data []byte) json.RawMessage {
+	if json.Valid(data) {
+		return compactJSON(data)
+	}
+
+	quoted, _ := json.Marshal(string(data))
+	return quoted
+}
+
+func compactJSON(data []byte) []byte {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return data
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,109 @@
+package bedrock
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCassetteRecordThenReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Amzn-Bedrock-Input-Token-Count", "12")
+		io.WriteString(w, `{"completion": "recorded reply"}`)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "demo.cassette.json")
+
+	recorder, err := NewCassetteTransport(path, CassetteRecord, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("NewCassetteTransport(record) error = %v", err)
+	}
+	recorded := roundTrip(t, recorder, server.URL+"/model/m/invoke", `{"prompt": "hi"}`)
+
+	replayer, err := NewCassetteTransport(path, CassetteReplay, nil)
+	if err != nil {
+		t.Fatalf("NewCassetteTransport(replay) error = %v", err)
+	}
+	// Whitespace differences in the JSON body still match
+	replayed := roundTrip(t, replayer, server.URL+"/model/m/invoke", `{"prompt":"hi"}`)
+
+	if replayed != string(compactJSON([]byte(recorded))) {
+		t.Errorf("replayed body = %q, want %q", replayed, recorded)
+	}
+	if calls != 1 {
+		t.Errorf("server received %d calls, want 1", calls)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/model/m/invoke", strings.NewReader(`{"prompt":"other"}`))
+	if _, err := replayer.RoundTrip(req); !errors.Is(err, ErrCassetteMiss) {
+		t.Errorf("RoundTrip(unrecorded) error = %v, want ErrCassetteMiss", err)
+	}
+}
+
+func TestCassetteReplaysNonJSONBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Amzn-Bedrock-Input-Token-Count", "12")
+		io.WriteString(w, "plain reply")
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "text.cassette.json")
+
+	recorder, err := NewCassetteTransport(path, CassetteRecord, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("NewCassetteTransport(record) error = %v", err)
+	}
+	roundTrip(t, recorder, server.URL+"/model/m/invoke", "not json: hi")
+
+	replayer, err := NewCassetteTransport(path, CassetteReplay, nil)
+	if err != nil {
+		t.Fatalf("NewCassetteTransport(replay) error = %v", err)
+	}
+	if replayed := roundTrip(t, replayer, server.URL+"/model/m/invoke", "not json: hi"); replayed != "plain reply" {
+		t.Errorf("replayed body = %q, want %q", replayed, "plain reply")
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/model/m/invoke", strings.NewReader("not json: bye"))
+	if _, err := replayer.RoundTrip(req); !errors.Is(err, ErrCassetteMiss) {
+		t.Errorf("RoundTrip(unrecorded) error = %v, want ErrCassetteMiss", err)
+	}
+}
+
+func TestCassetteReplayRequiresFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.json")
+	if _, err := NewCassetteTransport(path, CassetteReplay, nil); err == nil {
+		t.Error("NewCassetteTransport(replay, missing file) error = nil, want error")
+	}
+}
+
+func roundTrip(t *testing.T, rt http.RoundTripper, url, body string) string {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if resp.Header.Get("X-Amzn-Bedrock-Input-Token-Count") != "12" {
+		t.Errorf("token count header = %q, want 12", resp.Header.Get("X-Amzn-Bedrock-Input-Token-Count"))
+	}
+	return string(data)
+}
@@ -2,36 +2,84 @@ package bedrock
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 )
 
+// Invoker sends a single prompt to a model. Client implements it against Bedrock;
+// bedrocktest.Fake implements it in-process for offline tests.
+type Invoker interface {
+	InvokeModel(ctx context.Context, prompt string, params ModelParams) (*ModelResponse, error)
+}
+
 type Client struct {
-	client *bedrockruntime.Client
-	ctx    context.Context
+	client  *bedrockruntime.Client
+	retry   RetryPolicy
+	timeout time.Duration
+	limiter *RateLimiter // nil when calls are not rate limited
+}
+
+// Option customizes a Client created by NewClient
+type Option func(*Client)
+
+// WithRetryPolicy replaces DefaultRetryPolicy for every call made by the client
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithTimeout bounds every call, including its retries, to d unless ModelParams.Timeout
+// overrides it. It takes precedence over BEDROCK_TIMEOUT; zero disables the default deadline.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithRateLimiter paces every call attempt, retries included, through limiter. It takes precedence
+// over BEDROCK_RPM, BEDROCK_TPM and BEDROCK_MAX_CONCURRENCY; nil disables rate limiting.
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(c *Client) {
+		c.limiter = limiter
+	}
 }
 
 type ModelParams struct {
-	ModelID     string  `json:"model_id"`    // e.g., "anthropic.claude-v2:1"
-	Temperature float64 `json:"temperature"` // creativity of the model's output (0.0 to 1.0)
-	TopP        float64 `json:"top_p"`       // consider a broad range of possible words (0.0 to 1.0)
-	TopK        int     `json:"top_k"`       // limits the number of probable words
-	MaxTokens   int     `json:"max_tokens"`  // maximum number of tokens to generate
+	ModelID       string        `json:"model_id"`                 // e.g., "anthropic.claude-v2:1" or "anthropic.claude-3-5-sonnet-20240620-v1:0"
+	Temperature   float64       `json:"temperature"`              // creativity of the model's output (0.0 to 1.0)
+	TopP          float64       `json:"top_p"`                    // consider a broad range of possible words (0.0 to 1.0)
+	TopK          int           `json:"top_k"`                    // limits the number of probable words
+	MaxTokens     int           `json:"max_tokens"`               // maximum number of tokens to generate
+	System        string        `json:"system,omitempty"`         // optional system prompt
+	StopSequences []string      `json:"stop_sequences,omitempty"` // sequences that stop generation
+	Timeout       time.Duration `json:"-"`                        // per-call deadline; zero uses the client default
 }
 
 type ModelResponse struct {
-	Type       string `json:"type"`
-	Completion string `json:"completion"`
-	StopReason string `json:"stop_reason"`
-	Stop       string `json:"stop"`
+	Type       string         `json:"type"`
+	Completion string         `json:"completion"`
+	StopReason string         `json:"stop_reason"`
+	Stop       string         `json:"stop"`
+	Content    []ContentBlock `json:"content,omitempty"`    // raw content blocks, Messages API only
+	ToolCalls  []ToolCall     `json:"tool_calls,omitempty"` // tools the model asked to run, Converse API only
+	Usage      *Usage         `json:"usage,omitempty"`      // token counts, when the model reports them
 }
 
-func NewClient() (*Client, error) {
+// Usage holds the token counts reported for a single invocation
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+func NewClient(opts ...Option) (*Client, error) {
 	ctx := context.Background()
 
 	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("AWS_REGION")))
@@ -39,22 +87,74 @@ func NewClient() (*Client, error) {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
-	return &Client{
-		client: bedrockruntime.NewFromConfig(cfg),
-		ctx:    ctx,
-	}, nil
+	var timeout time.Duration
+	if value := os.Getenv("BEDROCK_TIMEOUT"); value != "" {
+		timeout, err = time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BEDROCK_TIMEOUT: %w", err)
+		}
+	}
+
+	limiter, err := rateLimiterFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	var cassette *CassetteTransport
+	if path := os.Getenv("BEDROCK_CASSETTE"); path != "" {
+		mode := CassetteMode(os.Getenv("BEDROCK_CASSETTE_MODE"))
+		if mode == "" {
+			mode = CassetteReplay
+		}
+
+		cassette, err = NewCassetteTransport(path, mode, http.DefaultTransport)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open cassette: %w", err)
+		}
+	}
+
+	client := bedrockruntime.NewFromConfig(cfg, func(o *bedrockruntime.Options) {
+		// Retries are handled by the client's RetryPolicy so they can be classified and budgeted
+		o.Retryer = aws.NopRetryer{}
+
+		// AWS_ENDPOINT_URL points the client at a local stub or proxy instead of the regional endpoint
+		if endpoint := os.Getenv("AWS_ENDPOINT_URL"); endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+
+		if cassette != nil {
+			o.HTTPClient = &http.Client{Transport: cassette}
+			// Replayed calls never reach AWS, so placeholder credentials are enough to sign them
+			if cassette.mode == CassetteReplay {
+				o.Credentials = aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+					return aws.Credentials{AccessKeyID: "replay", SecretAccessKey: "replay", Source: "cassette"}, nil
+				})
+			}
+		}
+	})
+
+	c := &Client{
+		client:  client,
+		retry:   DefaultRetryPolicy(),
+		timeout: timeout,
+		limiter: limiter,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
 }
 
-func (c *Client) InvokeModel(prompt string, params ModelParams) (*ModelResponse, error) {
-	requestBody := map[string]any{
-		"prompt":               fmt.Sprintf("\n\nHuman: %s\n\nAssistant:", prompt),
-		"temperature":          params.Temperature,
-		"top_p":                params.TopP,
-		"top_k":                params.TopK,
-		"max_tokens_to_sample": params.MaxTokens,
+// InvokeModel sends a single prompt to the model, encoding it with the provider adapter
+// selected from params.ModelID
+func (c *Client) InvokeModel(ctx context.Context, prompt string, params ModelParams) (*ModelResponse, error) {
+	provider, err := ProviderFor(params.ModelID)
+	if err != nil {
+		return nil, err
 	}
 
-	bodyBytes, err := json.Marshal(requestBody)
+	bodyBytes, err := provider.EncodeRequest(prompt, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request body: %w", err)
 	}
@@ -65,20 +165,74 @@ func (c *Client) InvokeModel(prompt string, params ModelParams) (*ModelResponse,
 		ContentType: aws.String("application/json"),
 	}
 
-	resp, err := c.client.InvokeModel(c.ctx, input)
+	ctx, cancel := c.callContext(ctx, params)
+	defer cancel()
+
+	var resp *bedrockruntime.InvokeModelOutput
+	err = c.withRetry(ctx, "InvokeModel", params.ModelID, c.limited(ctx, params.ModelID, estimateTokens(params.MaxTokens, prompt), func() (usage *Usage, err error) {
+		if resp, err = c.client.InvokeModel(ctx, input); err != nil {
+			return nil, err
+		}
+		return headerUsage(resp.ResultMetadata), nil
+	}))
 	if err != nil {
 		return nil, fmt.Errorf("failed to invoke model: %w", err)
 	}
 
-	var modelResp ModelResponse
-	if err := json.Unmarshal(resp.Body, &modelResp); err != nil {
+	modelResp, err := provider.DecodeResponse(resp.Body)
+	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	return &modelResp, nil
+	// The headers carry token counts for every model family, including those whose body has none
+	if usage := headerUsage(resp.ResultMetadata); usage != nil {
+		modelResp.Usage = usage
+	}
+
+	return modelResp, nil
+}
+
+// RateLimiter returns the client's rate limiter, or nil when calls are not rate limited
+func (c *Client) RateLimiter() *RateLimiter {
+	return c.limiter
+}
+
+// rateLimiterFromEnv builds the limiter set by BEDROCK_RPM, BEDROCK_TPM and BEDROCK_MAX_CONCURRENCY,
+// or returns nil when none of them is set
+func rateLimiterFromEnv() (*RateLimiter, error) {
+	var values [3]int
+	for i, name := range []string{"BEDROCK_RPM", "BEDROCK_TPM", "BEDROCK_MAX_CONCURRENCY"} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid %s: %q is not a non-negative whole number", name, value)
+		}
+		values[i] = n
+	}
+	if values == [3]int{} {
+		return nil, nil
+	}
+
+	return NewRateLimiter(RateLimits{RequestsPerMinute: values[0], TokensPerMinute: values[1]}, values[2]), nil
+}
+
+// callContext applies the per-call deadline: params.Timeout if set, otherwise the client default
+func (c *Client) callContext(ctx context.Context, params ModelParams) (context.Context, context.CancelFunc) {
+	timeout := c.timeout
+	if params.Timeout > 0 {
+		timeout = params.Timeout
+	}
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, timeout)
 }
 
-// GetDefaultClaudeParams returns default parameters for Claude v2 model
+// GetDefaultClaudeParams returns default parameters for the Claude model set in MODEL_ID
 func GetDefaultClaudeParams() ModelParams {
 	return ModelParams{
 		ModelID:     os.Getenv("MODEL_ID"),
@@ -0,0 +1,150 @@
+package bedrock
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// cohereMaxP is the largest nucleus sampling value Cohere models accept
+const cohereMaxP = 0.99
+
+type cohereRequest struct {
+	Prompt        string   `json:"prompt"`
+	MaxTokens     int      `json:"max_tokens"`
+	Temperature   float64  `json:"temperature"`
+	P             float64  `json:"p"`
+	K             int      `json:"k"`
+	StopSequences []string `json:"stop_sequences,omitempty"`
+}
+
+// cohereResponse covers both the InvokeModel body and the stream chunks, which carry the text
+// at the top level instead of inside generations
+type cohereResponse struct {
+	Generations []struct {
+		Text         string `json:"text"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"generations"`
+	Text         string `json:"text"`
+	FinishReason string `json:"finish_reason"`
+}
+
+type cohereChatRequest struct {
+	Message       string   `json:"message"`
+	Preamble      string   `json:"preamble,omitempty"`
+	MaxTokens     int      `json:"max_tokens"`
+	Temperature   float64  `json:"temperature"`
+	P             float64  `json:"p"`
+	K             int      `json:"k"`
+	StopSequences []string `json:"stop_sequences,omitempty"`
+}
+
+// cohereChatResponse covers both the InvokeModel body and the "text-generation" / "stream-end" chunks
+type cohereChatResponse struct {
+	EventType    string `json:"event_type"`
+	Text         string `json:"text"`
+	FinishReason string `json:"finish_reason"`
+}
+
+// cohereProvider speaks the Cohere Command (text generation) body format
+type cohereProvider struct{}
+
+func (cohereProvider) Name() string { return "cohere" }
+
+func (cohereProvider) EncodeRequest(prompt string, params ModelParams) ([]byte, error) {
+	if params.System != "" {
+		prompt = params.System + "\n\n" + prompt
+	}
+
+	return json.Marshal(cohereRequest{
+		Prompt:        prompt,
+		MaxTokens:     params.MaxTokens,
+		Temperature:   params.Temperature,
+		P:             min(params.TopP, cohereMaxP),
+		K:             params.TopK,
+		StopSequences: params.StopSequences,
+	})
+}
+
+func (cohereProvider) DecodeResponse(body []byte) (*ModelResponse, error) {
+	modelResp := &ModelResponse{Type: "completion"}
+	text, err := cohereProvider{}.DecodeChunk(body, modelResp)
+	if err != nil {
+		return nil, err
+	}
+
+	modelResp.Completion = text
+	return modelResp, nil
+}
+
+func (cohereProvider) DecodeChunk(chunk []byte, resp *ModelResponse) (string, error) {
+	var part cohereResponse
+	if err := json.Unmarshal(chunk, &part); err != nil {
+		return "", err
+	}
+
+	resp.Type = "completion"
+	if part.FinishReason != "" {
+		resp.StopReason = part.FinishReason
+	}
+
+	if len(part.Generations) == 0 {
+		return part.Text, nil
+	}
+
+	var text strings.Builder
+	for _, generation := range part.Generations {
+		text.WriteString(generation.Text)
+		if generation.FinishReason != "" {
+			resp.StopReason = generation.FinishReason
+		}
+	}
+
+	return text.String(), nil
+}
+
+// cohereChatProvider speaks the Cohere Command R / R+ chat body format
+type cohereChatProvider struct{}
+
+func (cohereChatProvider) Name() string { return "cohere" }
+
+func (cohereChatProvider) EncodeRequest(prompt string, params ModelParams) ([]byte, error) {
+	return json.Marshal(cohereChatRequest{
+		Message:       prompt,
+		Preamble:      params.System,
+		MaxTokens:     params.MaxTokens,
+		Temperature:   params.Temperature,
+		P:             min(params.TopP, cohereMaxP),
+		K:             params.TopK,
+		StopSequences: params.StopSequences,
+	})
+}
+
+func (cohereChatProvider) DecodeResponse(body []byte) (*ModelResponse, error) {
+	var resp cohereChatResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	return &ModelResponse{
+		Type:       "completion",
+		Completion: resp.Text,
+		StopReason: resp.FinishReason,
+	}, nil
+}
+
+func (cohereChatProvider) DecodeChunk(chunk []byte, resp *ModelResponse) (string, error) {
+	var part cohereChatResponse
+	if err := json.Unmarshal(chunk, &part); err != nil {
+		return "", err
+	}
+
+	resp.Type = "completion"
+	switch part.EventType {
+	case "text-generation":
+		return part.Text, nil
+	case "stream-end":
+		resp.StopReason = part.FinishReason
+	}
+
+	return "", nil
+}
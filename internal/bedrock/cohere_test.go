@@ -0,0 +1,73 @@
+package bedrock
+
+import "testing"
+
+func TestCohereAdapter(t *testing.T) {
+	runAdapterCases(t, cohereProvider{}, []adapterCase{
+		{
+			// p above 0.99 is rejected by Cohere, so a top_p of 1 is lowered to it
+			name:    "request",
+			prompt:  "Why is the sky blue?",
+			params:  ModelParams{System: "You are terse.", Temperature: 0.5, TopP: 1, TopK: 250, MaxTokens: 300, StopSequences: []string{"--"}},
+			request: `{"prompt": "You are terse.\n\nWhy is the sky blue?", "max_tokens": 300, "temperature": 0.5, "p": 0.99, "k": 250, "stop_sequences": ["--"]}`,
+		},
+		{
+			name:     "response",
+			response: `{"generations": [{"finish_reason": "COMPLETE", "id": "e6a5f3a2-7f3c-4c8e-9d3a-2b1f0c6d8e4f", "text": " Because of Rayleigh scattering."}], "id": "5f7c9a1e-3b2d-4e6f-8a9b-0c1d2e3f4a5b", "prompt": "Why is the sky blue?"}`,
+			want:     &ModelResponse{Type: "completion", Completion: " Because of Rayleigh scattering.", StopReason: "COMPLETE"},
+		},
+		{
+			name:     "response cut at max tokens",
+			response: `{"generations": [{"finish_reason": "MAX_TOKENS", "id": "e6a5f3a2", "text": " Because of"}], "id": "5f7c9a1e", "prompt": "Why is the sky blue?"}`,
+			want:     &ModelResponse{Type: "completion", Completion: " Because of", StopReason: "MAX_TOKENS"},
+		},
+		{
+			// The last chunk repeats the whole generation inside response, which must not be added again
+			name: "stream",
+			chunks: []string{
+				`{"text": " Because of", "is_finished": false}`,
+				`{"text": " Rayleigh scattering.", "is_finished": false}`,
+				`{"is_finished": true, "finish_reason": "COMPLETE", "response": {"id": "5f7c9a1e", "generations": [{"id": "e6a5f3a2", "text": " Because of Rayleigh scattering.", "finish_reason": "COMPLETE"}], "prompt": "Why is the sky blue?"}, "amazon-bedrock-invocationMetrics": {"inputTokenCount": 6, "outputTokenCount": 7, "invocationLatency": 580, "firstByteLatency": 240}}`,
+			},
+			want: &ModelResponse{Type: "completion", Completion: " Because of Rayleigh scattering.", StopReason: "COMPLETE", Usage: &Usage{InputTokens: 6, OutputTokens: 7}},
+		},
+	})
+}
+
+func TestCohereChatAdapter(t *testing.T) {
+	runAdapterCases(t, cohereChatProvider{}, []adapterCase{
+		{
+			name:    "request",
+			prompt:  "Why is the sky blue?",
+			params:  ModelParams{System: "You are terse.", Temperature: 0.5, TopP: 0.9, TopK: 250, MaxTokens: 300, StopSequences: []string{"--"}},
+			request: `{"message": "Why is the sky blue?", "preamble": "You are terse.", "max_tokens": 300, "temperature": 0.5, "p": 0.9, "k": 250, "stop_sequences": ["--"]}`,
+		},
+		{
+			name:    "request without preamble",
+			prompt:  "Why is the sky blue?",
+			params:  ModelParams{Temperature: 0, TopP: 1, MaxTokens: 100},
+			request: `{"message": "Why is the sky blue?", "max_tokens": 100, "temperature": 0, "p": 0.99, "k": 0}`,
+		},
+		{
+			name: "response",
+			response: `{
+				"response_id": "4b3c2a1d-9e8f-4d7c-a6b5-1e2f3a4b5c6d",
+				"text": "Because of Rayleigh scattering.",
+				"generation_id": "7d6c5b4a-3e2f-4a1b-9c8d-6e5f4a3b2c1d",
+				"chat_history": [{"role": "USER", "message": "Why is the sky blue?"}, {"role": "CHATBOT", "message": "Because of Rayleigh scattering."}],
+				"finish_reason": "COMPLETE"
+			}`,
+			want: &ModelResponse{Type: "completion", Completion: "Because of Rayleigh scattering.", StopReason: "COMPLETE"},
+		},
+		{
+			name: "stream",
+			chunks: []string{
+				`{"is_finished": false, "event_type": "stream-start", "generation_id": "7d6c5b4a"}`,
+				`{"is_finished": false, "event_type": "text-generation", "text": "Because of"}`,
+				`{"is_finished": false, "event_type": "text-generation", "text": " Rayleigh"}`,
+				`{"is_finished": true, "event_type": "stream-end", "finish_reason": "MAX_TOKENS", "response": {"response_id": "4b3c2a1d", "text": "Because of Rayleigh", "finish_reason": "MAX_TOKENS"}, "amazon-bedrock-invocationMetrics": {"inputTokenCount": 7, "outputTokenCount": 3, "invocationLatency": 420, "firstByteLatency": 205}}`,
+			},
+			want: &ModelResponse{Type: "completion", Completion: "Because of Rayleigh", StopReason: "MAX_TOKENS", Usage: &Usage{InputTokens: 7, OutputTokens: 3}},
+		},
+	})
+}
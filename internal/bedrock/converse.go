@@ -0,0 +1,152 @@
+package bedrock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// Role identifies the author of a conversation turn
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+)
+
+// Message is one role-tagged turn of a conversation. Assistant turns may carry the tool calls
+// the model made, and the user turn after them the results.
+type Message struct {
+	Role        Role         `json:"role"`
+	Content     string       `json:"content"`
+	ToolCalls   []ToolCall   `json:"tool_calls,omitempty"`
+	ToolResults []ToolResult `json:"tool_results,omitempty"`
+}
+
+// Converse sends a multi-turn conversation through the Converse API, which uses the same request
+// shape for every model family. params.System becomes the system prompt and the response carries
+// the input/output token counts Bedrock reports. With tools, the model may stop with
+// StopReasonToolUse and return ToolCalls to be answered in the next user turn.
+func (c *Client) Converse(ctx context.Context, messages []Message, params ModelParams, tools ...ToolSpec) (*ModelResponse, error) {
+	converseMessages, err := toConverseMessages(messages)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &bedrockruntime.ConverseInput{
+		ModelId:         &params.ModelID,
+		Messages:        converseMessages,
+		InferenceConfig: toInferenceConfig(params),
+		ToolConfig:      toToolConfiguration(tools),
+	}
+
+	if params.System != "" {
+		input.System = []types.SystemContentBlock{
+			&types.SystemContentBlockMemberText{Value: params.System},
+		}
+	}
+
+	// top_k is not part of the shared inference config; Claude accepts it as an additional field
+	if provider, err := ProviderFor(params.ModelID); err == nil && provider.Name() == "anthropic" && params.TopK > 0 {
+		input.AdditionalModelRequestFields = document.NewLazyDocument(map[string]any{"top_k": params.TopK})
+	}
+
+	ctx, cancel := c.callContext(ctx, params)
+	defer cancel()
+
+	texts := []string{params.System}
+	for _, msg := range messages {
+		texts = append(texts, msg.Content)
+	}
+
+	var resp *bedrockruntime.ConverseOutput
+	err = c.withRetry(ctx, "Converse", params.ModelID, c.limited(ctx, params.ModelID, estimateTokens(params.MaxTokens, texts...), func() (usage *Usage, err error) {
+		if resp, err = c.client.Converse(ctx, input); err != nil {
+			return nil, err
+		}
+		if resp.Usage != nil {
+			usage = &Usage{InputTokens: int(aws.ToInt32(resp.Usage.InputTokens)), OutputTokens: int(aws.ToInt32(resp.Usage.OutputTokens))}
+		}
+		return usage, nil
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to converse with model: %w", err)
+	}
+
+	modelResp := &ModelResponse{
+		Type:       "message",
+		StopReason: string(resp.StopReason),
+	}
+
+	if output, ok := resp.Output.(*types.ConverseOutputMemberMessage); ok {
+		var completion strings.Builder
+		for _, block := range output.Value.Content {
+			switch block := block.(type) {
+			case *types.ContentBlockMemberText:
+				completion.WriteString(block.Value)
+				modelResp.Content = append(modelResp.Content, ContentBlock{Type: "text", Text: block.Value})
+			case *types.ContentBlockMemberToolUse:
+				call, err := fromToolUseBlock(block.Value)
+				if err != nil {
+					return nil, err
+				}
+				modelResp.ToolCalls = append(modelResp.ToolCalls, call)
+			}
+		}
+		modelResp.Completion = completion.String()
+	}
+
+	if resp.Usage != nil {
+		modelResp.Usage = &Usage{
+			InputTokens:  int(aws.ToInt32(resp.Usage.InputTokens)),
+			OutputTokens: int(aws.ToInt32(resp.Usage.OutputTokens)),
+		}
+	}
+
+	return modelResp, nil
+}
+
+func toConverseMessages(messages []Message) ([]types.Message, error) {
+	converted := make([]types.Message, 0, len(messages))
+	for _, msg := range messages {
+		var content []types.ContentBlock
+		// Turns that only carry tool calls or results have no text, and Converse rejects empty text blocks
+		if msg.Content != "" || len(msg.ToolCalls) == 0 && len(msg.ToolResults) == 0 {
+			content = append(content, &types.ContentBlockMemberText{Value: msg.Content})
+		}
+		for _, call := range msg.ToolCalls {
+			block, err := toToolUseBlock(call)
+			if err != nil {
+				return nil, err
+			}
+			content = append(content, block)
+		}
+		for _, result := range msg.ToolResults {
+			content = append(content, toToolResultBlock(result))
+		}
+
+		converted = append(converted, types.Message{Role: types.ConversationRole(msg.Role), Content: content})
+	}
+
+	return converted, nil
+}
+
+func toInferenceConfig(params ModelParams) *types.InferenceConfiguration {
+	config := &types.InferenceConfiguration{
+		MaxTokens:     aws.Int32(int32(params.MaxTokens)),
+		Temperature:   aws.Float32(float32(params.Temperature)),
+		StopSequences: params.StopSequences,
+	}
+
+	// Same rule as the Messages API body: only send top_p when it actually narrows sampling
+	if params.TopP > 0 && params.TopP < 1 {
+		config.TopP = aws.Float32(float32(params.TopP))
+	}
+
+	return config
+}
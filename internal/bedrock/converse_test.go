@@ -0,0 +1,91 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestConverse(t *testing.T) {
+	conversation := []Message{
+		{Role: RoleUser, Content: "Why is the sky blue?"},
+		{Role: RoleAssistant, Content: "Rayleigh scattering."},
+		{Role: RoleUser, Content: "And sunsets?"},
+	}
+	messages := `[
+		{"role": "user", "content": [{"text": "Why is the sky blue?"}]},
+		{"role": "assistant", "content": [{"text": "Rayleigh scattering."}]},
+		{"role": "user", "content": [{"text": "And sunsets?"}]}
+	]`
+
+	tests := []struct {
+		name    string
+		params  ModelParams
+		request string // exact JSON body, so misspelled or extra fields fail
+	}{
+		{
+			// top_k only reaches Claude, as an additional model request field
+			name:    "claude with system prompt and top_k",
+			params:  ModelParams{ModelID: "anthropic.claude-3-haiku-20240307-v1:0", System: "You are terse.", Temperature: 0.5, TopP: 1, TopK: 250, MaxTokens: 300, StopSequences: []string{"Human:"}},
+			request: `{"messages": ` + messages + `, "system": [{"text": "You are terse."}], "inferenceConfig": {"maxTokens": 300, "temperature": 0.5, "stopSequences": ["Human:"]}, "additionalModelRequestFields": {"top_k": 250}}`,
+		},
+		{
+			name:    "other family with top_p",
+			params:  ModelParams{ModelID: "meta.llama3-8b-instruct-v1:0", Temperature: 0, TopP: 0.5, TopK: 250, MaxTokens: 100},
+			request: `{"messages": ` + messages + `, "inferenceConfig": {"maxTokens": 100, "temperature": 0, "topP": 0.5}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path string
+			var request []byte
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				request, _ = io.ReadAll(r.Body)
+				w.Header().Set("Content-Type", "application/json")
+				io.WriteString(w, `{
+					"output": {"message": {"role": "assistant", "content": [{"text": "Light travels "}, {"text": "further through the air."}]}},
+					"stopReason": "end_turn",
+					"usage": {"inputTokens": 31, "outputTokens": 7, "totalTokens": 38},
+					"metrics": {"latencyMs": 412}
+				}`)
+			})
+
+			resp, err := client.Converse(context.Background(), conversation, tt.params)
+			if err != nil {
+				t.Fatalf("Converse() error = %v", err)
+			}
+
+			if want := "/model/" + tt.params.ModelID + "/converse"; path != want {
+				t.Errorf("request path = %s, want %s", path, want)
+			}
+			var got, want any
+			if err := json.Unmarshal(request, &got); err != nil {
+				t.Fatalf("request body is not JSON: %v", err)
+			}
+			if err := json.Unmarshal([]byte(tt.request), &want); err != nil {
+				t.Fatalf("invalid request in test case: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("request body =\n%s\nwant\n%s", request, tt.request)
+			}
+
+			wantResp := &ModelResponse{
+				Type:       "message",
+				Completion: "Light travels further through the air.",
+				StopReason: "end_turn",
+				Content:    []ContentBlock{{Type: "text", Text: "Light travels "}, {Type: "text", Text: "further through the air."}},
+				Usage:      &Usage{InputTokens: 31, OutputTokens: 7},
+			}
+			if !reflect.DeepEqual(resp, wantResp) {
+				gotJSON, _ := json.Marshal(resp)
+				wantJSON, _ := json.Marshal(wantResp)
+				t.Errorf("Converse() = %s, want %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
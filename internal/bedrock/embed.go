@@ -0,0 +1,119 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+)
+
+// DefaultEmbeddingModelID is used when EMBEDDING_MODEL_ID is not set
+const DefaultEmbeddingModelID = "amazon.titan-embed-text-v2:0"
+
+// Embedder turns text into a vector for similarity search. Client implements it with the
+// Titan and Cohere embedding models.
+type Embedder interface {
+	Embed(ctx context.Context, modelID, text string) ([]float64, error)
+}
+
+type titanEmbedRequest struct {
+	InputText string `json:"inputText"`
+}
+
+type titanEmbedResponse struct {
+	Embedding []float64 `json:"embedding"`
+}
+
+type cohereEmbedRequest struct {
+	Texts     []string `json:"texts"`
+	InputType string   `json:"input_type"`
+}
+
+type cohereEmbedResponse struct {
+	Embeddings [][]float64 `json:"embeddings"`
+}
+
+// EmbeddingModelID returns the embedding model set in EMBEDDING_MODEL_ID, or DefaultEmbeddingModelID
+func EmbeddingModelID() string {
+	if modelID := os.Getenv("EMBEDDING_MODEL_ID"); modelID != "" {
+		return modelID
+	}
+	return DefaultEmbeddingModelID
+}
+
+// Embed returns the embedding of text from an "amazon.titan-embed-*" or "cohere.embed-*" model
+func (c *Client) Embed(ctx context.Context, modelID, text string) ([]float64, error) {
+	body, err := encodeEmbedRequest(modelID, text)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &bedrockruntime.InvokeModelInput{
+		ModelId:     &modelID,
+		Body:        body,
+		ContentType: aws.String("application/json"),
+	}
+
+	ctx, cancel := c.callContext(ctx, ModelParams{})
+	defer cancel()
+
+	var resp *bedrockruntime.InvokeModelOutput
+	err = c.withRetry(ctx, "InvokeModel", modelID, c.limited(ctx, modelID, estimateTokens(0, text), func() (usage *Usage, err error) {
+		if resp, err = c.client.InvokeModel(ctx, input); err != nil {
+			return nil, err
+		}
+		return headerUsage(resp.ResultMetadata), nil
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to invoke embedding model: %w", err)
+	}
+
+	return decodeEmbedResponse(modelID, resp.Body)
+}
+
+func encodeEmbedRequest(modelID, text string) ([]byte, error) {
+	var request any
+	switch id := baseModelID(modelID); {
+	case strings.HasPrefix(id, "amazon.titan-embed-text"):
+		request = titanEmbedRequest{InputText: text}
+	case strings.HasPrefix(id, "cohere.embed"):
+		// Examples and queries are the same kind of text, so both are embedded as documents
+		request = cohereEmbedRequest{Texts: []string{text}, InputType: "search_document"}
+	default:
+		return nil, fmt.Errorf("no embedding adapter for model %q", modelID)
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	return body, nil
+}
+
+func decodeEmbedResponse(modelID string, body []byte) ([]float64, error) {
+	var embedding []float64
+	if strings.HasPrefix(baseModelID(modelID), "cohere.") {
+		var resp cohereEmbedResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		if len(resp.Embeddings) > 0 {
+			embedding = resp.Embeddings[0]
+		}
+	} else {
+		var resp titanEmbedResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		embedding = resp.Embedding
+	}
+
+	if len(embedding) == 0 {
+		return nil, fmt.Errorf("embedding model %q returned no embedding", modelID)
+	}
+	return embedding, nil
+}
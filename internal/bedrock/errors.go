@@ -0,0 +1,106 @@
+package bedrock
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/smithy-go"
+)
+
+// ErrorClass groups Bedrock failures by how callers should react to them
+type ErrorClass string
+
+const (
+	ClassThrottled          ErrorClass = "throttled"
+	ClassValidation         ErrorClass = "validation"
+	ClassAccessDenied       ErrorClass = "access_denied"
+	ClassModelTimeout       ErrorClass = "model_timeout"
+	ClassServiceUnavailable ErrorClass = "service_unavailable"
+	ClassUnknown            ErrorClass = "unknown"
+)
+
+// Sentinel errors for each class, usable with errors.Is on any error returned by Client
+var (
+	ErrThrottled          = errors.New("request throttled")
+	ErrValidation         = errors.New("request rejected as invalid")
+	ErrAccessDenied       = errors.New("access denied")
+	ErrModelTimeout       = errors.New("model timed out")
+	ErrServiceUnavailable = errors.New("service unavailable")
+)
+
+var classSentinels = map[ErrorClass]error{
+	ClassThrottled:          ErrThrottled,
+	ClassValidation:         ErrValidation,
+	ClassAccessDenied:       ErrAccessDenied,
+	ClassModelTimeout:       ErrModelTimeout,
+	ClassServiceUnavailable: ErrServiceUnavailable,
+}
+
+// errorCodeClasses maps Bedrock exception names to their class
+var errorCodeClasses = map[string]ErrorClass{
+	"ThrottlingException":           ClassThrottled,
+	"TooManyRequestsException":      ClassThrottled,
+	"ServiceQuotaExceededException": ClassThrottled, // over the account's quotas: retried with backoff and slows the rate limiter
+	"ValidationException":           ClassValidation,
+	"ResourceNotFoundException":     ClassValidation,
+	"AccessDeniedException":         ClassAccessDenied,
+	"UnrecognizedClientException":   ClassAccessDenied,
+	"ModelTimeoutException":         ClassModelTimeout,
+	"ServiceUnavailableException":   ClassServiceUnavailable,
+	"ModelNotReadyException":        ClassServiceUnavailable,
+	"InternalServerException":       ClassServiceUnavailable,
+	"ModelStreamErrorException":     ClassServiceUnavailable,
+}
+
+// InvokeError is returned by every Client call that fails after the retry policy gave up
+type InvokeError struct {
+	Class    ErrorClass
+	Op       string // Bedrock operation, e.g. "InvokeModel"
+	ModelID  string
+	Attempts int
+	Err      error // underlying SDK error
+}
+
+func (e *InvokeError) Error() string {
+	return fmt.Sprintf("%s %s: %s after %d attempt(s): %v", e.Op, e.ModelID, e.Class, e.Attempts, e.Err)
+}
+
+// Unwrap exposes both the class sentinel and the underlying SDK error to errors.Is / errors.As
+func (e *InvokeError) Unwrap() []error {
+	if sentinel, ok := classSentinels[e.Class]; ok {
+		return []error{sentinel, e.Err}
+	}
+	return []error{e.Err}
+}
+
+// Retryable reports whether another attempt could succeed
+func (e *InvokeError) Retryable() bool {
+	return e.Class.Retryable()
+}
+
+// Retryable reports whether errors of this class are transient
+func (c ErrorClass) Retryable() bool {
+	switch c {
+	case ClassThrottled, ClassModelTimeout, ClassServiceUnavailable:
+		return true
+	default:
+		return false
+	}
+}
+
+// Classify returns the class of an error returned by the Bedrock SDK
+func Classify(err error) ErrorClass {
+	var invokeErr *InvokeError
+	if errors.As(err, &invokeErr) {
+		return invokeErr.Class
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		if class, ok := errorCodeClasses[apiErr.ErrorCode()]; ok {
+			return class
+		}
+	}
+
+	return ClassUnknown
+}
@@ -0,0 +1,45 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err       error
+		want      ErrorClass
+		retryable bool
+	}{
+		{apiError("ThrottlingException"), ClassThrottled, true},
+		{apiError("ServiceQuotaExceededException"), ClassThrottled, true},
+		{apiError("ValidationException"), ClassValidation, false},
+		{apiError("ResourceNotFoundException"), ClassValidation, false},
+		{apiError("AccessDeniedException"), ClassAccessDenied, false},
+		{apiError("ModelTimeoutException"), ClassModelTimeout, true},
+		{apiError("ModelNotReadyException"), ClassServiceUnavailable, true},
+		{fmt.Errorf("failed to invoke model: %w", apiError("InternalServerException")), ClassServiceUnavailable, true},
+		{apiError("SomethingNewException"), ClassUnknown, false},
+		{context.Canceled, ClassUnknown, false},
+	}
+	for _, tt := range tests {
+		got := Classify(tt.err)
+		if got != tt.want || got.Retryable() != tt.retryable {
+			t.Errorf("Classify(%v) = %s (retryable %v), want %s (retryable %v)", tt.err, got, got.Retryable(), tt.want, tt.retryable)
+		}
+	}
+}
+
+func TestInvokeErrorUnwrapsToSentinel(t *testing.T) {
+	cause := apiError("ServiceQuotaExceededException")
+	err := &InvokeError{Class: Classify(cause), Op: "InvokeModel", ModelID: limitedModel, Attempts: 4, Err: cause}
+
+	if !errors.Is(err, ErrThrottled) || !errors.Is(err, cause) {
+		t.Errorf("error %v does not match both ErrThrottled and its cause", err)
+	}
+	if Classify(fmt.Errorf("batch: %w", err)) != ClassThrottled {
+		t.Errorf("Classify() of a wrapped InvokeError = %s, want throttled", Classify(err))
+	}
+}
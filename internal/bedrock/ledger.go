@@ -0,0 +1,113 @@
+package bedrock
+
+import (
+	"context"
+	"sync"
+)
+
+// UsageTotals accumulates token counts and cost over a number of calls
+type UsageTotals struct {
+	Calls        int     `json:"calls"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	Cost         float64 `json:"cost"`     // USD, for calls whose model has a price
+	Unpriced     int     `json:"unpriced"` // calls whose model is missing from the price table
+}
+
+func (t *UsageTotals) add(usage Usage, cost float64, priced bool) {
+	t.Calls++
+	t.InputTokens += usage.InputTokens
+	t.OutputTokens += usage.OutputTokens
+	t.Cost += cost
+	if !priced {
+		t.Unpriced++
+	}
+}
+
+// TechniqueUsage is the running total for one technique
+type TechniqueUsage struct {
+	Technique string
+	UsageTotals
+}
+
+// Ledger keeps a running usage and cost total per technique and for the whole session.
+// It is safe for concurrent use.
+type Ledger struct {
+	prices PriceTable
+
+	mu         sync.Mutex
+	techniques map[string]*UsageTotals
+	order      []string
+	session    UsageTotals
+}
+
+// NewLedger creates an empty ledger that prices calls with prices
+func NewLedger(prices PriceTable) *Ledger {
+	return &Ledger{
+		prices:     prices,
+		techniques: map[string]*UsageTotals{},
+	}
+}
+
+// Record adds one call to the technique's total and the session total.
+// A nil usage still counts the call, with no tokens.
+func (l *Ledger) Record(technique, modelID string, usage *Usage) {
+	var u Usage
+	if usage != nil {
+		u = *usage
+	}
+	cost, priced := l.prices.Cost(modelID, u)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	totals, ok := l.techniques[technique]
+	if !ok {
+		totals = &UsageTotals{}
+		l.techniques[technique] = totals
+		l.order = append(l.order, technique)
+	}
+	totals.add(u, cost, priced)
+	l.session.add(u, cost, priced)
+}
+
+// Techniques returns the per-technique totals in the order techniques were first recorded
+func (l *Ledger) Techniques() []TechniqueUsage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	usage := make([]TechniqueUsage, 0, len(l.order))
+	for _, technique := range l.order {
+		usage = append(usage, TechniqueUsage{Technique: technique, UsageTotals: *l.techniques[technique]})
+	}
+	return usage
+}
+
+// Session returns the totals across every technique
+func (l *Ledger) Session() UsageTotals {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.session
+}
+
+// Meter wraps next so that every successful call is recorded under technique
+func (l *Ledger) Meter(next Invoker, technique string) Invoker {
+	return &meteredInvoker{next: next, ledger: l, technique: technique}
+}
+
+type meteredInvoker struct {
+	next      Invoker
+	ledger    *Ledger
+	technique string
+}
+
+func (m *meteredInvoker) InvokeModel(ctx context.Context, prompt string, params ModelParams) (*ModelResponse, error) {
+	resp, err := m.next.InvokeModel(ctx, prompt, params)
+	if err != nil {
+		return nil, err
+	}
+
+	m.ledger.Record(m.technique, params.ModelID, resp.Usage)
+	return resp, nil
+}
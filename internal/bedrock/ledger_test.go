@@ -0,0 +1,61 @@
+package bedrock
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+type usageInvoker struct {
+	usage *Usage
+}
+
+func (u usageInvoker) InvokeModel(context.Context, string, ModelParams) (*ModelResponse, error) {
+	return &ModelResponse{Completion: "ok", Usage: u.usage}, nil
+}
+
+func TestPriceTableLookup(t *testing.T) {
+	prices := PriceTable{"anthropic.claude-3-haiku-20240307-v1:0": {InputPer1K: 0.25, OutputPer1K: 1.25}}
+
+	for _, modelID := range []string{
+		"anthropic.claude-3-haiku-20240307-v1:0",
+		"us.anthropic.claude-3-haiku-20240307-v1:0",
+		"arn:aws:bedrock:us-east-1::foundation-model/anthropic.claude-3-haiku-20240307-v1:0",
+	} {
+		cost, ok := prices.Cost(modelID, Usage{InputTokens: 2000, OutputTokens: 1000})
+		if !ok || math.Abs(cost-1.75) > 1e-9 {
+			t.Errorf("Cost(%q) = %v, %v, want 1.75, true", modelID, cost, ok)
+		}
+	}
+
+	if _, ok := prices.Cost("amazon.titan-text-express-v1", Usage{}); ok {
+		t.Error("Cost() of an unpriced model reported ok")
+	}
+}
+
+func TestLedgerMeter(t *testing.T) {
+	ledger := NewLedger(PriceTable{"anthropic.claude-v2:1": {InputPer1K: 1, OutputPer1K: 2}})
+	zeroShot := ledger.Meter(usageInvoker{&Usage{InputTokens: 100, OutputTokens: 50}}, "Zero-Shot")
+	fewShot := ledger.Meter(usageInvoker{&Usage{InputTokens: 300, OutputTokens: 10}}, "Few-Shot")
+
+	ctx := context.Background()
+	zeroShot.InvokeModel(ctx, "a", ModelParams{ModelID: "anthropic.claude-v2:1"})
+	zeroShot.InvokeModel(ctx, "b", ModelParams{ModelID: "anthropic.claude-v2:1"})
+	fewShot.InvokeModel(ctx, "c", ModelParams{ModelID: "meta.llama3-8b-instruct-v1:0"})
+
+	techniques := ledger.Techniques()
+	if len(techniques) != 2 || techniques[0].Technique != "Zero-Shot" || techniques[1].Technique != "Few-Shot" {
+		t.Fatalf("Techniques() = %+v, want Zero-Shot then Few-Shot", techniques)
+	}
+	if got := techniques[0].UsageTotals; got.Calls != 2 || got.InputTokens != 200 || got.OutputTokens != 100 || math.Abs(got.Cost-0.4) > 1e-9 {
+		t.Errorf("Zero-Shot totals = %+v, want 2 calls, 200/100 tokens, $0.40", got)
+	}
+	if got := techniques[1].UsageTotals; got.Unpriced != 1 || got.Cost != 0 {
+		t.Errorf("Few-Shot totals = %+v, want 1 unpriced call", got)
+	}
+
+	session := ledger.Session()
+	if session.Calls != 3 || session.InputTokens != 500 || session.OutputTokens != 110 {
+		t.Errorf("Session() = %+v, want 3 calls, 500/110 tokens", session)
+	}
+}
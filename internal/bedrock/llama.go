@@ -0,0 +1,92 @@
+package bedrock
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type llamaRequest struct {
+	Prompt      string  `json:"prompt"`
+	Temperature float64 `json:"temperature"`
+	TopP        float64 `json:"top_p"`
+	MaxGenLen   int     `json:"max_gen_len"`
+}
+
+// llamaResponse is shared by InvokeModel bodies and stream chunks
+type llamaResponse struct {
+	Generation           string  `json:"generation"`
+	PromptTokenCount     int     `json:"prompt_token_count"`
+	GenerationTokenCount int     `json:"generation_token_count"`
+	StopReason           *string `json:"stop_reason"`
+}
+
+// llamaProvider speaks the Meta Llama body format. Llama has no chat fields in its body,
+// so the prompt is wrapped in the model's own instruction template.
+type llamaProvider struct{}
+
+func (llamaProvider) Name() string { return "meta" }
+
+func (llamaProvider) EncodeRequest(prompt string, params ModelParams) ([]byte, error) {
+	return json.Marshal(llamaRequest{
+		Prompt:      llamaPrompt(baseModelID(params.ModelID), params.System, prompt),
+		Temperature: params.Temperature,
+		TopP:        params.TopP,
+		MaxGenLen:   params.MaxTokens,
+	})
+}
+
+func (llamaProvider) DecodeResponse(body []byte) (*ModelResponse, error) {
+	var resp llamaResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	modelResp := &ModelResponse{
+		Type:       "completion",
+		Completion: resp.Generation,
+		Usage: &Usage{
+			InputTokens:  resp.PromptTokenCount,
+			OutputTokens: resp.GenerationTokenCount,
+		},
+	}
+	if resp.StopReason != nil {
+		modelResp.StopReason = *resp.StopReason
+	}
+
+	return modelResp, nil
+}
+
+func (llamaProvider) DecodeChunk(chunk []byte, resp *ModelResponse) (string, error) {
+	var part llamaResponse
+	if err := json.Unmarshal(chunk, &part); err != nil {
+		return "", err
+	}
+
+	resp.Type = "completion"
+	if part.StopReason != nil {
+		resp.StopReason = *part.StopReason
+	}
+
+	return part.Generation, nil
+}
+
+// llamaPrompt applies the Llama 3 header template, or the [INST] template for Llama 2
+func llamaPrompt(modelID, system, prompt string) string {
+	if strings.HasPrefix(modelID, "meta.llama2") {
+		if system != "" {
+			return fmt.Sprintf("<s>[INST] <<SYS>>\n%s\n<</SYS>>\n\n%s [/INST]", system, prompt)
+		}
+		return fmt.Sprintf("<s>[INST] %s [/INST]", prompt)
+	}
+
+	var b strings.Builder
+	b.WriteString("<|begin_of_text|>")
+	if system != "" {
+		fmt.Fprintf(&b, "<|start_header_id|>system<|end_header_id|>\n\n%s<|eot_id|>", system)
+	}
+	fmt.Fprintf(&b, "<|start_header_id|>user<|end_header_id|>\n\n%s<|eot_id|>", prompt)
+	b.WriteString("<|start_header_id|>assistant<|end_header_id|>\n\n")
+
+	return b.String()
+}
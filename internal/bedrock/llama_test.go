@@ -0,0 +1,45 @@
+package bedrock
+
+import "testing"
+
+func TestLlamaAdapter(t *testing.T) {
+	runAdapterCases(t, llamaProvider{}, []adapterCase{
+		{
+			// The Llama body has no top_k or stop sequences, so they are not sent
+			name:    "llama 3 request",
+			prompt:  "Why is the sky blue?",
+			params:  ModelParams{ModelID: "meta.llama3-8b-instruct-v1:0", System: "You are terse.", Temperature: 0.5, TopP: 0.9, TopK: 250, MaxTokens: 300, StopSequences: []string{"User:"}},
+			request: `{"prompt": "<|begin_of_text|><|start_header_id|>system<|end_header_id|>\n\nYou are terse.<|eot_id|><|start_header_id|>user<|end_header_id|>\n\nWhy is the sky blue?<|eot_id|><|start_header_id|>assistant<|end_header_id|>\n\n", "temperature": 0.5, "top_p": 0.9, "max_gen_len": 300}`,
+		},
+		{
+			name:    "llama 3 request through an inference profile",
+			prompt:  "Why is the sky blue?",
+			params:  ModelParams{ModelID: "us.meta.llama3-1-8b-instruct-v1:0", Temperature: 0, TopP: 1, MaxTokens: 100},
+			request: `{"prompt": "<|begin_of_text|><|start_header_id|>user<|end_header_id|>\n\nWhy is the sky blue?<|eot_id|><|start_header_id|>assistant<|end_header_id|>\n\n", "temperature": 0, "top_p": 1, "max_gen_len": 100}`,
+		},
+		{
+			name:    "llama 2 request",
+			prompt:  "Why is the sky blue?",
+			params:  ModelParams{ModelID: "meta.llama2-13b-chat-v1", System: "You are terse.", Temperature: 0.5, TopP: 0.9, MaxTokens: 300},
+			request: `{"prompt": "<s>[INST] <<SYS>>\nYou are terse.\n<</SYS>>\n\nWhy is the sky blue? [/INST]", "temperature": 0.5, "top_p": 0.9, "max_gen_len": 300}`,
+		},
+		{
+			name:     "response",
+			response: `{"generation": " Because of Rayleigh scattering.", "prompt_token_count": 28, "generation_token_count": 7, "stop_reason": "stop"}`,
+			want:     &ModelResponse{Type: "completion", Completion: " Because of Rayleigh scattering.", StopReason: "stop", Usage: &Usage{InputTokens: 28, OutputTokens: 7}},
+		},
+		{
+			name:     "response cut at max tokens",
+			response: `{"generation": " Because of", "prompt_token_count": 28, "generation_token_count": 3, "stop_reason": "length"}`,
+			want:     &ModelResponse{Type: "completion", Completion: " Because of", StopReason: "length", Usage: &Usage{InputTokens: 28, OutputTokens: 3}},
+		},
+		{
+			name: "stream",
+			chunks: []string{
+				`{"generation": " Because of", "prompt_token_count": 28, "generation_token_count": 3, "stop_reason": null}`,
+				`{"generation": " Rayleigh scattering.", "prompt_token_count": null, "generation_token_count": 7, "stop_reason": "stop", "amazon-bedrock-invocationMetrics": {"inputTokenCount": 28, "outputTokenCount": 7, "invocationLatency": 730, "firstByteLatency": 212}}`,
+			},
+			want: &ModelResponse{Type: "completion", Completion: " Because of Rayleigh scattering.", StopReason: "stop", Usage: &Usage{InputTokens: 28, OutputTokens: 7}},
+		},
+	})
+}
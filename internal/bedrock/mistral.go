@@ -0,0 +1,78 @@
+package bedrock
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// mistralMaxTopK is the largest top_k Mistral models accept
+const mistralMaxTopK = 200
+
+type mistralRequest struct {
+	Prompt      string   `json:"prompt"`
+	MaxTokens   int      `json:"max_tokens"`
+	Temperature float64  `json:"temperature"`
+	TopP        float64  `json:"top_p"`
+	TopK        int      `json:"top_k,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+}
+
+// mistralResponse is shared by InvokeModel bodies and stream chunks
+type mistralResponse struct {
+	Outputs []struct {
+		Text       string  `json:"text"`
+		StopReason *string `json:"stop_reason"`
+	} `json:"outputs"`
+}
+
+// mistralProvider speaks the Mistral / Mixtral body format
+type mistralProvider struct{}
+
+func (mistralProvider) Name() string { return "mistral" }
+
+func (mistralProvider) EncodeRequest(prompt string, params ModelParams) ([]byte, error) {
+	instruction := prompt
+	if params.System != "" {
+		instruction = params.System + "\n\n" + prompt
+	}
+
+	return json.Marshal(mistralRequest{
+		Prompt:      fmt.Sprintf("<s>[INST] %s [/INST]", instruction),
+		MaxTokens:   params.MaxTokens,
+		Temperature: params.Temperature,
+		TopP:        params.TopP,
+		TopK:        min(params.TopK, mistralMaxTopK),
+		Stop:        params.StopSequences,
+	})
+}
+
+func (mistralProvider) DecodeResponse(body []byte) (*ModelResponse, error) {
+	modelResp := &ModelResponse{Type: "completion"}
+	text, err := mistralProvider{}.DecodeChunk(body, modelResp)
+	if err != nil {
+		return nil, err
+	}
+
+	modelResp.Completion = text
+	return modelResp, nil
+}
+
+func (mistralProvider) DecodeChunk(chunk []byte, resp *ModelResponse) (string, error) {
+	var part mistralResponse
+	if err := json.Unmarshal(chunk, &part); err != nil {
+		return "", err
+	}
+
+	resp.Type = "completion"
+
+	var text strings.Builder
+	for _, output := range part.Outputs {
+		text.WriteString(output.Text)
+		if output.StopReason != nil {
+			resp.StopReason = *output.StopReason
+		}
+	}
+
+	return text.String(), nil
+}
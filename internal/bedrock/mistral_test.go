@@ -0,0 +1,39 @@
+package bedrock
+
+import "testing"
+
+func TestMistralAdapter(t *testing.T) {
+	runAdapterCases(t, mistralProvider{}, []adapterCase{
+		{
+			name:    "request",
+			prompt:  "Why is the sky blue?",
+			params:  ModelParams{System: "You are terse.", Temperature: 0.5, TopP: 0.9, TopK: 50, MaxTokens: 300, StopSequences: []string{"</s>"}},
+			request: `{"prompt": "<s>[INST] You are terse.\n\nWhy is the sky blue? [/INST]", "max_tokens": 300, "temperature": 0.5, "top_p": 0.9, "top_k": 50, "stop": ["</s>"]}`,
+		},
+		{
+			name:    "request with top_k above the model's limit",
+			prompt:  "Why is the sky blue?",
+			params:  ModelParams{Temperature: 0, TopP: 1, TopK: 250, MaxTokens: 100},
+			request: `{"prompt": "<s>[INST] Why is the sky blue? [/INST]", "max_tokens": 100, "temperature": 0, "top_p": 1, "top_k": 200}`,
+		},
+		{
+			// Mistral bodies carry no token counts; the client takes them from the response headers
+			name:     "response",
+			response: `{"outputs": [{"text": " Because of Rayleigh scattering.", "stop_reason": "stop"}]}`,
+			want:     &ModelResponse{Type: "completion", Completion: " Because of Rayleigh scattering.", StopReason: "stop"},
+		},
+		{
+			name:     "response cut at max tokens",
+			response: `{"outputs": [{"text": " Because of", "stop_reason": "length"}]}`,
+			want:     &ModelResponse{Type: "completion", Completion: " Because of", StopReason: "length"},
+		},
+		{
+			name: "stream",
+			chunks: []string{
+				`{"outputs": [{"text": " Because of", "stop_reason": null}]}`,
+				`{"outputs": [{"text": " Rayleigh scattering.", "stop_reason": "stop"}], "amazon-bedrock-invocationMetrics": {"inputTokenCount": 19, "outputTokenCount": 7, "invocationLatency": 640, "firstByteLatency": 188}}`,
+			},
+			want: &ModelResponse{Type: "completion", Completion: " Because of Rayleigh scattering.", StopReason: "stop", Usage: &Usage{InputTokens: 19, OutputTokens: 7}},
+		},
+	})
+}
@@ -0,0 +1,69 @@
+package bedrock
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Provider translates a prompt plus ModelParams into one model family's request body
+// and normalizes that family's responses into ModelResponse
+type Provider interface {
+	// Name identifies the model family, e.g. "anthropic" or "meta"
+	Name() string
+	// EncodeRequest builds the InvokeModel request body
+	EncodeRequest(prompt string, params ModelParams) ([]byte, error)
+	// DecodeResponse parses an InvokeModel response body
+	DecodeResponse(body []byte) (*ModelResponse, error)
+	// DecodeChunk folds one InvokeModelWithResponseStream chunk into resp and returns the text it carried
+	DecodeChunk(chunk []byte, resp *ModelResponse) (string, error)
+}
+
+// providers maps model ID prefixes to their adapters. The first matching prefix wins,
+// so more specific prefixes must come first.
+var providers = []struct {
+	prefix   string
+	provider Provider
+}{
+	{"anthropic.claude-v2", anthropicTextProvider{}},
+	{"anthropic.claude-instant", anthropicTextProvider{}},
+	{"anthropic.", anthropicMessagesProvider{}},
+	{"amazon.titan-text", titanProvider{}},
+	{"amazon.titan-tg1", titanProvider{}},
+	{"meta.llama", llamaProvider{}},
+	{"mistral.", mistralProvider{}},
+	{"cohere.command-r", cohereChatProvider{}},
+	{"cohere.command", cohereProvider{}},
+}
+
+// inferenceProfilePrefixes are the geography prefixes of cross-region inference profile IDs,
+// e.g. "us.anthropic.claude-3-5-sonnet-20240620-v1:0"
+var inferenceProfilePrefixes = []string{"us.", "us-gov.", "eu.", "apac.", "jp.", "au.", "ca.", "global."}
+
+// ProviderFor returns the body adapter for the given model ID
+func ProviderFor(modelID string) (Provider, error) {
+	id := baseModelID(modelID)
+	for _, p := range providers {
+		if strings.HasPrefix(id, p.prefix) {
+			return p.provider, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no provider adapter for model %q", modelID)
+}
+
+// baseModelID strips ARN and cross-region inference profile prefixes from a model ID,
+// leaving the "<provider>.<model>" form
+func baseModelID(modelID string) string {
+	id := modelID
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		id = id[i+1:]
+	}
+
+	for _, prefix := range inferenceProfilePrefixes {
+		if strings.HasPrefix(id, prefix) {
+			return strings.TrimPrefix(id, prefix)
+		}
+	}
+
+	return id
+}
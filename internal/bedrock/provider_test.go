@@ -0,0 +1,101 @@
+package bedrock
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestProviderFor(t *testing.T) {
+	tests := []struct {
+		modelID string
+		want    Provider
+	}{
+		{"anthropic.claude-v2:1", anthropicTextProvider{}},
+		{"anthropic.claude-instant-v1", anthropicTextProvider{}},
+		{"anthropic.claude-3-haiku-20240307-v1:0", anthropicMessagesProvider{}},
+		{"us.anthropic.claude-3-5-sonnet-20240620-v1:0", anthropicMessagesProvider{}},
+		{"arn:aws:bedrock:us-east-1::foundation-model/amazon.titan-text-express-v1", titanProvider{}},
+		{"meta.llama3-8b-instruct-v1:0", llamaProvider{}},
+		{"mistral.mixtral-8x7b-instruct-v0:1", mistralProvider{}},
+		{"cohere.command-r-plus-v1:0", cohereChatProvider{}},
+		{"cohere.command-text-v14", cohereProvider{}},
+	}
+	for _, tt := range tests {
+		if got, err := ProviderFor(tt.modelID); err != nil || got != tt.want {
+			t.Errorf("ProviderFor(%q) = %T, %v, want %T", tt.modelID, got, err, tt.want)
+		}
+	}
+
+	if _, err := ProviderFor("ai21.j2-ultra-v1"); err == nil {
+		t.Error("ProviderFor() of an unsupported model error = nil, want error")
+	}
+}
+
+// adapterCase is one use of a Provider: EncodeRequest when request is set, DecodeChunk over every
+// chunk in order when chunks is set, and DecodeResponse of response otherwise
+type adapterCase struct {
+	name string
+
+	prompt  string
+	params  ModelParams
+	request string // exact JSON body, so misspelled or extra fields fail
+
+	response string
+	chunks   []string
+	want     *ModelResponse
+}
+
+func runAdapterCases(t *testing.T, provider Provider, tests []adapterCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			switch {
+			case tt.request != "":
+				body, err := provider.EncodeRequest(tt.prompt, tt.params)
+				if err != nil {
+					t.Fatalf("EncodeRequest() error = %v", err)
+				}
+
+				var got, want any
+				if err := json.Unmarshal(body, &got); err != nil {
+					t.Fatalf("EncodeRequest() returned invalid JSON: %v", err)
+				}
+				if err := json.Unmarshal([]byte(tt.request), &want); err != nil {
+					t.Fatalf("invalid request in test case: %v", err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("EncodeRequest() =\n%s\nwant\n%s", body, tt.request)
+				}
+
+			case tt.chunks != nil:
+				// decodeChunk also picks up the invocation metrics Bedrock adds to the last chunk
+				got := &ModelResponse{}
+				for _, chunk := range tt.chunks {
+					if _, err := decodeChunk(provider, []byte(chunk), got); err != nil {
+						t.Fatalf("DecodeChunk(%s) error = %v", chunk, err)
+					}
+				}
+				assertModelResponse(t, got, tt.want)
+
+			default:
+				got, err := provider.DecodeResponse([]byte(tt.response))
+				if err != nil {
+					t.Fatalf("DecodeResponse() error = %v", err)
+				}
+				assertModelResponse(t, got, tt.want)
+			}
+		})
+	}
+}
+
+func assertModelResponse(t *testing.T, got, want *ModelResponse) {
+	t.Helper()
+
+	if !reflect.DeepEqual(got, want) {
+		gotJSON, _ := json.Marshal(got)
+		wantJSON, _ := json.Marshal(want)
+		t.Errorf("response = %s, want %s", gotJSON, wantJSON)
+	}
+}
@@ -0,0 +1,326 @@
+package bedrock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimits are the per-minute quotas a RateLimiter keeps one model under; zero means unlimited
+type RateLimits struct {
+	RequestsPerMinute int
+	TokensPerMinute   int // input plus output tokens
+}
+
+const (
+	// burstWindow is how many seconds of quota a model may spend at once after being idle.
+	// Bedrock's quotas are per minute, but spending a whole minute's worth in one burst is what gets throttled.
+	burstWindow = 10 * time.Second
+
+	// Adaptive slowdown: a throttled call halves the model's rate, down to minSlowdown of its limits,
+	// and every successful call wins back recoveryStep of them
+	minSlowdown  = 1.0 / 16
+	recoveryStep = 0.02
+)
+
+// RateStats are the queueing figures of one model
+type RateStats struct {
+	ModelID   string
+	Calls     int           // calls let through, including retries
+	Queued    int           // calls that had to wait for their quota or a free slot
+	Wait      time.Duration // total time calls spent waiting
+	MaxWait   time.Duration // longest single wait
+	Throttled int           // calls Bedrock throttled despite the limiter
+	Rate      float64       // current share of the limits after adaptive slowdown, 1 when at full speed
+}
+
+// MeanWait returns the average wait of the calls let through
+func (s RateStats) MeanWait() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.Wait / time.Duration(s.Calls)
+}
+
+// RateLimiter paces calls to stay under per-model request and token quotas and caps the calls in
+// flight across all models. Every call first reserves its estimated tokens; the estimate is replaced
+// by the tokens Bedrock reports once the call ends. A throttled call slows the model down, and
+// successful calls speed it back up. It is safe for concurrent use.
+type RateLimiter struct {
+	defaults RateLimits
+	slots    chan struct{} // nil when concurrency is not capped
+
+	mu     sync.Mutex
+	models map[string]*modelLimiter
+	order  []string
+	limits map[string]RateLimits
+	now    func() time.Time
+}
+
+// modelLimiter holds one model's buckets and statistics; callers hold the limiter's lock
+type modelLimiter struct {
+	requests bucket
+	tokens   bucket
+	slowdown float64
+	stats    RateStats
+}
+
+// NewRateLimiter creates a limiter that applies limits to every model and lets at most maxConcurrent
+// calls run at once; zero means no cap
+func NewRateLimiter(limits RateLimits, maxConcurrent int) *RateLimiter {
+	l := &RateLimiter{
+		defaults: limits,
+		models:   map[string]*modelLimiter{},
+		limits:   map[string]RateLimits{},
+		now:      time.Now,
+	}
+	if maxConcurrent > 0 {
+		l.slots = make(chan struct{}, maxConcurrent)
+	}
+	return l
+}
+
+// SetLimits replaces the limits of one model, e.g. a model with a raised quota
+func (l *RateLimiter) SetLimits(modelID string, limits RateLimits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limits[modelID] = limits
+	if m, ok := l.models[modelID]; ok {
+		now := l.now()
+		m.requests.configure(limits.RequestsPerMinute, now)
+		m.tokens.configure(limits.TokensPerMinute, now)
+	}
+}
+
+// Reservation is one call's place in the limiter, returned by Wait
+type Reservation struct {
+	limiter *RateLimiter
+	modelID string
+	tokens  float64 // reserved from the model's token bucket
+	slot    bool
+	once    sync.Once
+}
+
+// Wait blocks until modelID's quotas allow a call expected to use tokens, and a concurrency slot is
+// free. The call must report back with Done. A nil limiter lets every call through at once.
+func (l *RateLimiter) Wait(ctx context.Context, modelID string, tokens int) (*Reservation, error) {
+	if l == nil {
+		return nil, nil
+	}
+
+	start := l.now()
+	delay, reserved := l.reserve(modelID, tokens, start)
+	reservation := &Reservation{limiter: l, modelID: modelID, tokens: reserved}
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			l.refund(modelID, reserved)
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+			reservation.slot = true
+		case <-ctx.Done():
+			l.refund(modelID, reserved)
+			return nil, ctx.Err()
+		}
+	}
+
+	l.record(modelID, l.now().Sub(start))
+	return reservation, nil
+}
+
+// Done frees the call's slot, settles its tokens with the usage Bedrock reported, and adapts the
+// model's rate to whether the call was throttled. Only the first Done of a reservation counts.
+func (r *Reservation) Done(usage *Usage, err error) {
+	if r == nil {
+		return
+	}
+	r.once.Do(func() {
+		if r.slot {
+			<-r.limiter.slots
+		}
+		r.limiter.settle(r.modelID, r.tokens, usage, err)
+	})
+}
+
+// Stats returns the queueing figures per model, in the order the models were first called
+func (l *RateLimiter) Stats() []RateStats {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := make([]RateStats, 0, len(l.order))
+	for _, modelID := range l.order {
+		m := l.models[modelID]
+		s := m.stats
+		s.Rate = m.slowdown
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// model returns modelID's limiter, creating it with full buckets on first use
+func (l *RateLimiter) model(modelID string, now time.Time) *modelLimiter {
+	if m, ok := l.models[modelID]; ok {
+		return m
+	}
+
+	limits, ok := l.limits[modelID]
+	if !ok {
+		limits = l.defaults
+	}
+	m := &modelLimiter{slowdown: 1, stats: RateStats{ModelID: modelID}}
+	m.requests.configure(limits.RequestsPerMinute, now)
+	m.tokens.configure(limits.TokensPerMinute, now)
+
+	l.models[modelID] = m
+	l.order = append(l.order, modelID)
+	return m
+}
+
+// reserve takes a request and tokens from modelID's buckets and returns how long the call must wait
+// until both are back in credit, with the tokens it took. Taking them up front queues calls in
+// arrival order. A call larger than the token bucket only takes a full one, or it could never run.
+func (l *RateLimiter) reserve(modelID string, tokens int, now time.Time) (time.Duration, float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	m := l.model(modelID, now)
+	reserved := float64(tokens)
+	if m.tokens.perSecond > 0 {
+		reserved = min(reserved, m.tokens.capacity)
+	}
+	return max(m.requests.take(1, m.slowdown, now), m.tokens.take(reserved, m.slowdown, now)), reserved
+}
+
+// refund returns the quota of a call that gave up waiting
+func (l *RateLimiter) refund(modelID string, tokens float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	m := l.models[modelID]
+	m.requests.give(1)
+	m.tokens.give(tokens)
+}
+
+func (l *RateLimiter) record(modelID string, wait time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s := &l.models[modelID].stats
+	s.Calls++
+	if wait > time.Millisecond {
+		s.Queued++
+	}
+	s.Wait += wait
+	s.MaxWait = max(s.MaxWait, wait)
+}
+
+func (l *RateLimiter) settle(modelID string, reserved float64, usage *Usage, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	m := l.models[modelID]
+	if usage != nil {
+		m.tokens.give(reserved - float64(usage.InputTokens+usage.OutputTokens))
+	}
+
+	switch {
+	case err != nil && Classify(err) == ClassThrottled:
+		m.stats.Throttled++
+		m.slowdown = max(m.slowdown/2, minSlowdown)
+		// Bedrock is already over quota, so the next call waits for a fresh request
+		m.requests.drain()
+	case err == nil:
+		m.slowdown = min(m.slowdown+recoveryStep, 1)
+	}
+}
+
+// bucket is a token bucket refilled at a per-minute rate and holding up to burstWindow of it.
+// Its balance may go negative: calls are let through as soon as it is back to zero.
+type bucket struct {
+	perSecond float64 // zero means unlimited
+	capacity  float64
+	balance   float64
+	last      time.Time
+}
+
+func (b *bucket) configure(perMinute int, now time.Time) {
+	b.perSecond = float64(perMinute) / 60
+	b.capacity = max(b.perSecond*burstWindow.Seconds(), 1)
+	b.balance = b.capacity
+	b.last = now
+}
+
+// take spends n at the bucket's rate scaled by slowdown and returns the wait until the balance is
+// no longer negative
+func (b *bucket) take(n, slowdown float64, now time.Time) time.Duration {
+	if b.perSecond == 0 {
+		return 0
+	}
+
+	rate := b.perSecond * slowdown
+	if now.After(b.last) {
+		b.balance = min(b.balance+now.Sub(b.last).Seconds()*rate, b.capacity)
+		b.last = now
+	}
+
+	b.balance -= n
+	if b.balance >= 0 {
+		return 0
+	}
+	return time.Duration(-b.balance / rate * float64(time.Second))
+}
+
+// give returns n to the bucket, or takes it when negative
+func (b *bucket) give(n float64) {
+	if b.perSecond == 0 {
+		return
+	}
+	b.balance = min(b.balance+n, b.capacity)
+}
+
+// drain empties the bucket, keeping any debt
+func (b *bucket) drain() {
+	if b.perSecond == 0 {
+		return
+	}
+	b.balance = min(b.balance, 0)
+}
+
+// estimateTokens guesses a call's tokens before it is sent: about four characters per input
+// token, plus every output token the call may generate
+func estimateTokens(maxTokens int, texts ...string) int {
+	chars := 0
+	for _, text := range texts {
+		chars += len(text)
+	}
+	return chars/4 + maxTokens
+}
+
+// limited wraps one attempt of a call to modelID so that it waits for the client's rate limiter
+// first and reports the tokens it used, or its error, back to it afterwards
+func (c *Client) limited(ctx context.Context, modelID string, tokens int, call func() (*Usage, error)) func() error {
+	return func() error {
+		reservation, err := c.limiter.Wait(ctx, modelID, tokens)
+		if err != nil {
+			return err
+		}
+
+		usage, err := call()
+		reservation.Done(usage, err)
+		return err
+	}
+}
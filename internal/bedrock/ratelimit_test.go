@@ -0,0 +1,195 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+)
+
+const limitedModel = "anthropic.claude-3-haiku-20240307-v1:0"
+
+// frozenLimiter returns a limiter whose clock stands still unless the test moves it
+func frozenLimiter(limits RateLimits, maxConcurrent int) (*RateLimiter, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewRateLimiter(limits, maxConcurrent)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestRateLimiterPacesRequests(t *testing.T) {
+	// 60 requests per minute: a burst of 10 seconds' worth, then one per second
+	l, now := frozenLimiter(RateLimits{RequestsPerMinute: 60}, 0)
+
+	for i := range 10 {
+		if delay, _ := l.reserve(limitedModel, 0, *now); delay != 0 {
+			t.Fatalf("request %d waits %v, want the burst to go through", i+1, delay)
+		}
+	}
+	for _, want := range []time.Duration{time.Second, 2 * time.Second} {
+		if delay, _ := l.reserve(limitedModel, 0, *now); delay != want {
+			t.Errorf("reserve() waits %v, want %v", delay, want)
+		}
+	}
+	if delay, _ := l.reserve("anthropic.claude-v2:1", 0, *now); delay != 0 {
+		t.Errorf("another model waits %v, want its own quota", delay)
+	}
+
+	// The queue drains as time passes
+	*now = now.Add(5 * time.Second)
+	if delay, _ := l.reserve(limitedModel, 0, *now); delay != 0 {
+		t.Errorf("reserve() after 5s waits %v, want none", delay)
+	}
+}
+
+func TestRateLimiterSettlesTokens(t *testing.T) {
+	// 600 tokens per minute: a bucket of 100, refilled at 10 per second
+	l, now := frozenLimiter(RateLimits{TokensPerMinute: 600}, 0)
+	ctx := context.Background()
+
+	reservation, err := l.Wait(ctx, limitedModel, 80)
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	// The call used 20 tokens instead of the 80 reserved, so 60 go back
+	reservation.Done(&Usage{InputTokens: 15, OutputTokens: 5}, nil)
+	reservation.Done(&Usage{}, nil)
+
+	if delay, _ := l.reserve(limitedModel, 90, *now); delay != time.Second {
+		t.Errorf("reserve(90) waits %v, want 1s for the 10 missing tokens", delay)
+	}
+
+	// A call larger than the bucket waits for a full one instead of forever
+	if delay, reserved := l.reserve(limitedModel, 5000, *now); reserved != 100 || delay != 11*time.Second {
+		t.Errorf("reserve(5000) = %v, %v, want 11s for a full bucket of 100", delay, reserved)
+	}
+}
+
+func TestRateLimiterAdaptiveSlowdown(t *testing.T) {
+	l, now := frozenLimiter(RateLimits{RequestsPerMinute: 60}, 0)
+	ctx := context.Background()
+
+	reservation, err := l.Wait(ctx, limitedModel, 0)
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	reservation.Done(nil, &InvokeError{Class: ClassThrottled, Err: apiError("ThrottlingException")})
+
+	// Half the rate, and the burst is gone: the next request waits 2s instead of none
+	if delay, _ := l.reserve(limitedModel, 0, *now); delay != 2*time.Second {
+		t.Errorf("reserve() after throttling waits %v, want 2s", delay)
+	}
+
+	*now = now.Add(time.Minute)
+	for range 5 {
+		reservation, err := l.Wait(ctx, limitedModel, 0)
+		if err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+		reservation.Done(nil, nil)
+	}
+
+	stats := l.Stats()
+	if len(stats) != 1 || stats[0].Throttled != 1 || stats[0].Calls != 6 {
+		t.Fatalf("Stats() = %+v, want 6 calls with 1 throttled", stats)
+	}
+	if rate := stats[0].Rate; rate < 0.59 || rate > 0.61 {
+		t.Errorf("rate = %v, want 0.5 plus 5 recovery steps", rate)
+	}
+
+	// Failures other than throttling leave the rate alone
+	reservation, _ = l.Wait(ctx, limitedModel, 0)
+	reservation.Done(nil, errors.New("validation"))
+	if rate := l.Stats()[0].Rate; rate < 0.59 || rate > 0.61 {
+		t.Errorf("rate after a validation error = %v, want it unchanged", rate)
+	}
+}
+
+func TestRateLimiterStreamThrottledMidway(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeStreamChunk(t, w, `{"type": "content_block_delta", "index": 0, "delta": {"type": "text_delta", "text": "Hel"}}`)
+		writeStreamMessage(t, w, []byte(`{"message": "Too many tokens, please wait"}`), eventstream.Headers{
+			{Name: ":message-type", Value: eventstream.StringValue("exception")},
+			{Name: ":exception-type", Value: eventstream.StringValue("throttlingException")},
+		})
+	})
+	l, now := frozenLimiter(RateLimits{RequestsPerMinute: 60}, 1)
+	client.limiter = l
+
+	resp, err := client.InvokeModelStream(context.Background(), "Hello", ModelParams{ModelID: limitedModel, MaxTokens: 10}, nil)
+	if !errors.Is(err, ErrThrottled) || resp.Completion != "Hel" {
+		t.Fatalf("InvokeModelStream() = %q, %v, want the partial text and ErrThrottled", resp.Completion, err)
+	}
+
+	// The throttled stream slows the model down and frees its slot
+	if stats := l.Stats(); len(stats) != 1 || stats[0].Throttled != 1 || stats[0].Rate != 0.5 {
+		t.Errorf("Stats() = %+v, want 1 throttled call at half rate", stats)
+	}
+	if delay, _ := l.reserve(limitedModel, 0, *now); delay != 2*time.Second {
+		t.Errorf("reserve() after the throttled stream waits %v, want 2s", delay)
+	}
+	if len(l.slots) != 0 {
+		t.Errorf("%d slot(s) still taken after the stream ended", len(l.slots))
+	}
+}
+
+func TestRateLimiterConcurrencyCap(t *testing.T) {
+	l := NewRateLimiter(RateLimits{}, 1)
+	ctx := context.Background()
+
+	first, err := l.Wait(ctx, limitedModel, 0)
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	// A caller that gives up while queued is not let through
+	cancelled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Wait(cancelled, limitedModel, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() with a full pool error = %v, want the deadline", err)
+	}
+
+	admitted := make(chan *Reservation)
+	go func() {
+		reservation, _ := l.Wait(ctx, "anthropic.claude-v2:1", 0)
+		admitted <- reservation
+	}()
+
+	select {
+	case <-admitted:
+		t.Fatal("a second call ran while the only slot was taken")
+	case <-time.After(20 * time.Millisecond):
+	}
+	first.Done(nil, nil)
+	second := <-admitted
+	second.Done(nil, nil)
+
+	stats := l.Stats()
+	if len(stats) != 2 || stats[1].Queued != 1 || stats[1].MaxWait < 20*time.Millisecond {
+		t.Errorf("Stats() = %+v, want the second model's call queued for the slot", stats)
+	}
+}
+
+func TestRateLimiterFromEnv(t *testing.T) {
+	t.Setenv("BEDROCK_RPM", "")
+	t.Setenv("BEDROCK_TPM", "")
+	t.Setenv("BEDROCK_MAX_CONCURRENCY", "")
+	if l, err := rateLimiterFromEnv(); l != nil || err != nil {
+		t.Errorf("rateLimiterFromEnv() = %v, %v, want no limiter without settings", l, err)
+	}
+
+	t.Setenv("BEDROCK_RPM", "50")
+	t.Setenv("BEDROCK_MAX_CONCURRENCY", "4")
+	l, err := rateLimiterFromEnv()
+	if err != nil || l == nil || l.defaults.RequestsPerMinute != 50 || cap(l.slots) != 4 {
+		t.Errorf("rateLimiterFromEnv() = %+v, %v, want 50 RPM and 4 slots", l, err)
+	}
+
+	t.Setenv("BEDROCK_TPM", "lots")
+	if _, err := rateLimiterFromEnv(); err == nil {
+		t.Error("rateLimiterFromEnv() accepted BEDROCK_TPM=lots")
+	}
+}
@@ -0,0 +1,116 @@
+package bedrock
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// RetryPolicy controls how failed calls are retried. Only retryable error classes
+// (throttled, model timeout, service unavailable) are retried.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts including the first; 1 disables retries
+	BaseDelay   time.Duration // delay before the first retry, doubled on every following one
+	MaxDelay    time.Duration // upper bound for a single delay
+	Jitter      float64       // fraction of each delay that is randomized (0.0 to 1.0)
+	Budget      *RetryBudget  // retries shared across calls; nil means unlimited
+}
+
+// DefaultRetryPolicy returns the policy used by NewClient
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    20 * time.Second,
+		Jitter:      0.5,
+		Budget:      NewRetryBudget(20, 0.1),
+	}
+}
+
+// delay returns the backoff before the given retry (1 for the first retry)
+func (p RetryPolicy) delay(retry int) time.Duration {
+	d := p.BaseDelay << (retry - 1)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+
+	if p.Jitter > 0 {
+		spread := time.Duration(float64(d) * min(p.Jitter, 1))
+		d = d - spread + time.Duration(rand.Int64N(int64(spread)+1))
+	}
+
+	return d
+}
+
+// RetryBudget caps retries across all calls of a client, so a sustained outage or quota exhaustion
+// fails fast instead of multiplying the load. Every retry spends one token and every successful call
+// earns back a fraction of one, up to the initial size.
+type RetryBudget struct {
+	mu        sync.Mutex
+	tokens    float64
+	maxTokens float64
+	refill    float64
+}
+
+// NewRetryBudget creates a budget allowing size retries, refilled by refill tokens per success
+func NewRetryBudget(size int, refill float64) *RetryBudget {
+	return &RetryBudget{
+		tokens:    float64(size),
+		maxTokens: float64(size),
+		refill:    refill,
+	}
+}
+
+func (b *RetryBudget) withdraw() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (b *RetryBudget) deposit() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = min(b.tokens+b.refill, b.maxTokens)
+}
+
+// withRetry runs call under the client's retry policy and wraps the final error in an InvokeError
+func (c *Client) withRetry(ctx context.Context, op, modelID string, call func() error) error {
+	policy := c.retry
+	attempts := max(policy.MaxAttempts, 1)
+
+	for attempt := 1; ; attempt++ {
+		err := call()
+		if err == nil {
+			policy.Budget.deposit()
+			return nil
+		}
+
+		class := Classify(err)
+		if !class.Retryable() || attempt >= attempts || ctx.Err() != nil || !policy.Budget.withdraw() {
+			return &InvokeError{Class: class, Op: op, ModelID: modelID, Attempts: attempt, Err: err}
+		}
+
+		timer := time.NewTimer(policy.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &InvokeError{Class: class, Op: op, ModelID: modelID, Attempts: attempt, Err: err}
+		case <-timer.C:
+		}
+	}
+}
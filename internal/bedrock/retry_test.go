@@ -0,0 +1,70 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+)
+
+func apiError(code string) error {
+	return &smithy.GenericAPIError{Code: code, Message: code}
+}
+
+func TestWithRetryRetriesThrottling(t *testing.T) {
+	c := &Client{retry: RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}}
+
+	calls := 0
+	err := c.withRetry(context.Background(), "InvokeModel", "anthropic.claude-v2:1", func() error {
+		calls++
+		if calls < 3 {
+			return apiError("ThrottlingException")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
+
+func TestWithRetryStopsOnValidation(t *testing.T) {
+	c := &Client{retry: RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}}
+
+	calls := 0
+	err := c.withRetry(context.Background(), "InvokeModel", "anthropic.claude-v2:1", func() error {
+		calls++
+		return apiError("ValidationException")
+	})
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("error %v does not match ErrValidation", err)
+	}
+
+	var invokeErr *InvokeError
+	if !errors.As(err, &invokeErr) || invokeErr.Retryable() {
+		t.Errorf("want non-retryable InvokeError, got %v", err)
+	}
+}
+
+func TestWithRetryBudget(t *testing.T) {
+	c := &Client{retry: RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond, Budget: NewRetryBudget(1, 0)}}
+
+	calls := 0
+	err := c.withRetry(context.Background(), "Converse", "anthropic.claude-v2:1", func() error {
+		calls++
+		return apiError("ServiceUnavailableException")
+	})
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2 (one retry from the budget)", calls)
+	}
+	if !errors.Is(err, ErrServiceUnavailable) {
+		t.Errorf("error %v does not match ErrServiceUnavailable", err)
+	}
+}
@@ -0,0 +1,121 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// StreamHandler receives each piece of generated text as soon as it is decoded
+type StreamHandler func(text string)
+
+// invocationMetrics is appended by Bedrock to the last chunk of every stream
+type invocationMetrics struct {
+	Metrics *struct {
+		InputTokenCount  int `json:"inputTokenCount"`
+		OutputTokenCount int `json:"outputTokenCount"`
+	} `json:"amazon-bedrock-invocationMetrics"`
+}
+
+// InvokeModelStream sends a single prompt to the model and streams the generated text to onText
+// as it arrives. The assembled response is returned once the stream ends; cancelling ctx, or hitting
+// the per-call timeout, stops the stream and returns the context error along with the text received so far.
+func (c *Client) InvokeModelStream(ctx context.Context, prompt string, params ModelParams, onText StreamHandler) (modelResp *ModelResponse, err error) {
+	provider, err := ProviderFor(params.ModelID)
+	if err != nil {
+		return nil, err
+	}
+
+	bodyBytes, err := provider.EncodeRequest(prompt, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	input := &bedrockruntime.InvokeModelWithResponseStreamInput{
+		ModelId:     &params.ModelID,
+		Body:        bodyBytes,
+		ContentType: aws.String("application/json"),
+	}
+
+	ctx, cancel := c.callContext(ctx, params)
+	defer cancel()
+
+	// Only opening the stream is retried; once text has been handed to onText a retry would repeat it.
+	// The stream holds its rate limiter reservation until it ends, when its token counts arrive.
+	var (
+		resp        *bedrockruntime.InvokeModelWithResponseStreamOutput
+		reservation *Reservation
+	)
+	err = c.withRetry(ctx, "InvokeModelWithResponseStream", params.ModelID, func() (err error) {
+		if reservation, err = c.limiter.Wait(ctx, params.ModelID, estimateTokens(params.MaxTokens, prompt)); err != nil {
+			return err
+		}
+		if resp, err = c.client.InvokeModelWithResponseStream(ctx, input); err != nil {
+			reservation.Done(nil, err)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to invoke model with response stream: %w", err)
+	}
+
+	stream := resp.GetStream()
+	defer stream.Close()
+
+	modelResp = &ModelResponse{}
+	// A stream throttled midway must slow the limiter down like a throttled call
+	defer func() { reservation.Done(modelResp.Usage, err) }()
+	for {
+		select {
+		case <-ctx.Done():
+			return modelResp, ctx.Err()
+		case event, ok := <-stream.Events():
+			if !ok {
+				if err := stream.Err(); err != nil {
+					streamErr := &InvokeError{Class: Classify(err), Op: "InvokeModelWithResponseStream", ModelID: params.ModelID, Attempts: 1, Err: err}
+					return modelResp, fmt.Errorf("failed to read response stream: %w", streamErr)
+				}
+				return modelResp, nil
+			}
+
+			chunk, ok := event.(*types.ResponseStreamMemberChunk)
+			if !ok {
+				continue
+			}
+
+			text, err := decodeChunk(provider, chunk.Value.Bytes, modelResp)
+			if err != nil {
+				return modelResp, fmt.Errorf("failed to decode stream chunk: %w", err)
+			}
+
+			if text != "" && onText != nil {
+				onText(text)
+			}
+		}
+	}
+}
+
+// decodeChunk runs the provider's chunk decoder and then picks up the invocation metrics
+// Bedrock attaches to the final chunk of every provider's stream
+func decodeChunk(provider Provider, chunk []byte, resp *ModelResponse) (string, error) {
+	text, err := provider.DecodeChunk(chunk, resp)
+	if err != nil {
+		return "", err
+	}
+
+	resp.Completion += text
+
+	var metrics invocationMetrics
+	if err := json.Unmarshal(chunk, &metrics); err == nil && metrics.Metrics != nil {
+		resp.Usage = &Usage{
+			InputTokens:  metrics.Metrics.InputTokenCount,
+			OutputTokens: metrics.Metrics.OutputTokenCount,
+		}
+	}
+
+	return text, nil
+}
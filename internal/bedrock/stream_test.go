@@ -0,0 +1,141 @@
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+)
+
+// newTestClient points a client at a test server that answers every call with handler
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return &Client{client: bedrockruntime.New(bedrockruntime.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "test", SecretAccessKey: "test"}, nil
+		}),
+		Retryer: aws.NopRetryer{},
+	})}
+}
+
+// writeStreamChunk sends chunk as one stream event, the way Bedrock wraps a model's chunks
+func writeStreamChunk(t *testing.T, w http.ResponseWriter, chunk string) {
+	t.Helper()
+
+	payload, _ := json.Marshal(map[string][]byte{"bytes": []byte(chunk)})
+	writeStreamMessage(t, w, payload, eventstream.Headers{
+		{Name: ":message-type", Value: eventstream.StringValue("event")},
+		{Name: ":event-type", Value: eventstream.StringValue("chunk")},
+	})
+}
+
+func writeStreamMessage(t *testing.T, w http.ResponseWriter, payload []byte, headers eventstream.Headers) {
+	t.Helper()
+
+	w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+	headers.Set(":content-type", eventstream.StringValue("application/json"))
+	var buf bytes.Buffer
+	if err := eventstream.NewEncoder().Encode(&buf, eventstream.Message{Headers: headers, Payload: payload}); err != nil {
+		t.Errorf("Encode() error = %v", err)
+		return
+	}
+	w.Write(buf.Bytes())
+	w.(http.Flusher).Flush()
+}
+
+func TestInvokeModelStream(t *testing.T) {
+	tests := []struct {
+		name    string
+		modelID string
+		chunks  []string
+		want    *ModelResponse
+	}{
+		{
+			// Usage comes from the invocation metrics Bedrock adds to the last chunk
+			name:    "text completion",
+			modelID: "anthropic.claude-v2:1",
+			chunks: []string{
+				`{"completion": " Because of", "stop_reason": null, "stop": null}`,
+				`{"completion": " Rayleigh scattering.", "stop_reason": "stop_sequence", "stop": "\n\nHuman:", "amazon-bedrock-invocationMetrics": {"inputTokenCount": 14, "outputTokenCount": 6, "invocationLatency": 512, "firstByteLatency": 301}}`,
+			},
+			want: &ModelResponse{Type: "completion", Completion: " Because of Rayleigh scattering.", StopReason: "stop_sequence", Stop: "\n\nHuman:", Usage: &Usage{InputTokens: 14, OutputTokens: 6}},
+		},
+		{
+			name:    "messages",
+			modelID: "anthropic.claude-3-haiku-20240307-v1:0",
+			chunks: []string{
+				`{"type": "message_start", "message": {"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-3-haiku-20240307", "content": [], "stop_reason": null, "stop_sequence": null, "usage": {"input_tokens": 14, "output_tokens": 1}}}`,
+				`{"type": "content_block_start", "index": 0, "content_block": {"type": "text", "text": ""}}`,
+				`{"type": "content_block_delta", "index": 0, "delta": {"type": "text_delta", "text": "Rayleigh"}}`,
+				`{"type": "content_block_delta", "index": 0, "delta": {"type": "text_delta", "text": " scattering."}}`,
+				`{"type": "content_block_stop", "index": 0}`,
+				`{"type": "message_delta", "delta": {"stop_reason": "end_turn", "stop_sequence": null}, "usage": {"output_tokens": 6}}`,
+				`{"type": "message_stop", "amazon-bedrock-invocationMetrics": {"inputTokenCount": 14, "outputTokenCount": 6, "invocationLatency": 480, "firstByteLatency": 250}}`,
+			},
+			want: &ModelResponse{Type: "message", Completion: "Rayleigh scattering.", StopReason: "end_turn", Usage: &Usage{InputTokens: 14, OutputTokens: 6}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				for _, chunk := range tt.chunks {
+					writeStreamChunk(t, w, chunk)
+				}
+			})
+
+			var streamed string
+			got, err := client.InvokeModelStream(context.Background(), "Why is the sky blue?", ModelParams{ModelID: tt.modelID, MaxTokens: 100}, func(text string) {
+				streamed += text
+			})
+			if err != nil {
+				t.Fatalf("InvokeModelStream() error = %v", err)
+			}
+			if streamed != tt.want.Completion {
+				t.Errorf("streamed text = %q, want %q", streamed, tt.want.Completion)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				gotJSON, _ := json.Marshal(got)
+				wantJSON, _ := json.Marshal(tt.want)
+				t.Errorf("InvokeModelStream() = %s, want %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func TestInvokeModelStreamCancel(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeStreamChunk(t, w, `{"type": "message_start", "message": {"type": "message", "usage": {"input_tokens": 14, "output_tokens": 1}}}`)
+		writeStreamChunk(t, w, `{"type": "content_block_delta", "index": 0, "delta": {"type": "text_delta", "text": "Rayleigh"}}`)
+		// The model keeps generating until the caller hangs up
+		<-r.Context().Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	resp, err := client.InvokeModelStream(ctx, "Why is the sky blue?", ModelParams{ModelID: "anthropic.claude-3-haiku-20240307-v1:0", MaxTokens: 100}, func(string) {
+		cancel()
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("InvokeModelStream() error = %v, want context.Canceled", err)
+	}
+	// The text and usage received before cancelling are kept
+	if resp == nil || resp.Completion != "Rayleigh" || resp.Usage == nil || resp.Usage.InputTokens != 14 {
+		t.Errorf("InvokeModelStream() = %+v, want the partial response", resp)
+	}
+}
@@ -0,0 +1,94 @@
+package bedrock
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type titanRequest struct {
+	InputText            string                    `json:"inputText"`
+	TextGenerationConfig titanTextGenerationConfig `json:"textGenerationConfig"`
+}
+
+type titanTextGenerationConfig struct {
+	MaxTokenCount int      `json:"maxTokenCount"`
+	Temperature   float64  `json:"temperature"`
+	TopP          float64  `json:"topP"`
+	StopSequences []string `json:"stopSequences,omitempty"`
+}
+
+type titanResponse struct {
+	InputTextTokenCount int `json:"inputTextTokenCount"`
+	Results             []struct {
+		TokenCount       int    `json:"tokenCount"`
+		OutputText       string `json:"outputText"`
+		CompletionReason string `json:"completionReason"`
+	} `json:"results"`
+}
+
+type titanChunk struct {
+	OutputText                string  `json:"outputText"`
+	CompletionReason          *string `json:"completionReason"`
+	InputTextTokenCount       int     `json:"inputTextTokenCount"`
+	TotalOutputTextTokenCount int     `json:"totalOutputTextTokenCount"`
+}
+
+// titanProvider speaks the Amazon Titan Text body format
+type titanProvider struct{}
+
+func (titanProvider) Name() string { return "amazon" }
+
+func (titanProvider) EncodeRequest(prompt string, params ModelParams) ([]byte, error) {
+	// Titan has no separate system field, so the system prompt leads the conversation
+	inputText := fmt.Sprintf("User: %s\nBot:", prompt)
+	if params.System != "" {
+		inputText = params.System + "\n\n" + inputText
+	}
+
+	return json.Marshal(titanRequest{
+		InputText: inputText,
+		TextGenerationConfig: titanTextGenerationConfig{
+			MaxTokenCount: params.MaxTokens,
+			Temperature:   params.Temperature,
+			TopP:          params.TopP,
+			StopSequences: params.StopSequences,
+		},
+	})
+}
+
+func (titanProvider) DecodeResponse(body []byte) (*ModelResponse, error) {
+	var resp titanResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	modelResp := &ModelResponse{
+		Type:  "completion",
+		Usage: &Usage{InputTokens: resp.InputTextTokenCount},
+	}
+
+	var completion strings.Builder
+	for _, result := range resp.Results {
+		completion.WriteString(result.OutputText)
+		modelResp.StopReason = result.CompletionReason
+		modelResp.Usage.OutputTokens += result.TokenCount
+	}
+	modelResp.Completion = completion.String()
+
+	return modelResp, nil
+}
+
+func (titanProvider) DecodeChunk(chunk []byte, resp *ModelResponse) (string, error) {
+	var part titanChunk
+	if err := json.Unmarshal(chunk, &part); err != nil {
+		return "", err
+	}
+
+	resp.Type = "completion"
+	if part.CompletionReason != nil {
+		resp.StopReason = *part.CompletionReason
+	}
+
+	return part.OutputText, nil
+}
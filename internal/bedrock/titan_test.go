@@ -0,0 +1,38 @@
+package bedrock
+
+import "testing"
+
+func TestTitanAdapter(t *testing.T) {
+	runAdapterCases(t, titanProvider{}, []adapterCase{
+		{
+			name:    "request",
+			prompt:  "Why is the sky blue?",
+			params:  ModelParams{Temperature: 0.5, TopP: 0.9, TopK: 250, MaxTokens: 300, StopSequences: []string{"User:"}},
+			request: `{"inputText": "User: Why is the sky blue?\nBot:", "textGenerationConfig": {"maxTokenCount": 300, "temperature": 0.5, "topP": 0.9, "stopSequences": ["User:"]}}`,
+		},
+		{
+			name:    "request with system prompt",
+			prompt:  "Why is the sky blue?",
+			params:  ModelParams{System: "You are terse.", Temperature: 0, TopP: 1, MaxTokens: 100},
+			request: `{"inputText": "You are terse.\n\nUser: Why is the sky blue?\nBot:", "textGenerationConfig": {"maxTokenCount": 100, "temperature": 0, "topP": 1}}`,
+		},
+		{
+			name:     "response",
+			response: `{"inputTextTokenCount": 9, "results": [{"tokenCount": 8, "outputText": "\nBecause of Rayleigh scattering.", "completionReason": "FINISH"}]}`,
+			want:     &ModelResponse{Type: "completion", Completion: "\nBecause of Rayleigh scattering.", StopReason: "FINISH", Usage: &Usage{InputTokens: 9, OutputTokens: 8}},
+		},
+		{
+			name:     "response cut at max tokens",
+			response: `{"inputTextTokenCount": 9, "results": [{"tokenCount": 3, "outputText": "\nBecause of", "completionReason": "LENGTH"}]}`,
+			want:     &ModelResponse{Type: "completion", Completion: "\nBecause of", StopReason: "LENGTH", Usage: &Usage{InputTokens: 9, OutputTokens: 3}},
+		},
+		{
+			name: "stream",
+			chunks: []string{
+				`{"outputText": "\nBecause of", "index": 0, "totalOutputTextTokenCount": 3, "completionReason": null, "inputTextTokenCount": 9}`,
+				`{"outputText": " Rayleigh scattering.", "index": 0, "totalOutputTextTokenCount": 8, "completionReason": "FINISH", "inputTextTokenCount": null, "amazon-bedrock-invocationMetrics": {"inputTokenCount": 9, "outputTokenCount": 8, "invocationLatency": 1020, "firstByteLatency": 401}}`,
+			},
+			want: &ModelResponse{Type: "completion", Completion: "\nBecause of Rayleigh scattering.", StopReason: "FINISH", Usage: &Usage{InputTokens: 9, OutputTokens: 8}},
+		},
+	})
+}
@@ -0,0 +1,97 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// StopReasonToolUse is the stop reason of a Converse response that asks for tool calls
+const StopReasonToolUse = string(types.StopReasonToolUse)
+
+// ToolSpec declares a tool the model may call through the Converse API
+type ToolSpec struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"input_schema"` // JSON Schema of the tool's input object
+}
+
+// ToolCall is a tool_use block: the model asking for a tool to run with the given input
+type ToolCall struct {
+	ID    string          `json:"id"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input"` // JSON object matching the tool's InputSchema
+}
+
+// ToolResult is a tool_result block answering the ToolCall with the same ID
+type ToolResult struct {
+	ToolCallID string `json:"tool_call_id"`
+	Content    string `json:"content"`
+	IsError    bool   `json:"is_error,omitempty"` // the tool failed; Content explains why
+}
+
+// Converser sends multi-turn conversations. Client implements it with the Converse API;
+// bedrocktest.Fake implements it in-process for offline tests.
+type Converser interface {
+	Converse(ctx context.Context, messages []Message, params ModelParams, tools ...ToolSpec) (*ModelResponse, error)
+}
+
+func toToolConfiguration(tools []ToolSpec) *types.ToolConfiguration {
+	if len(tools) == 0 {
+		return nil
+	}
+
+	config := &types.ToolConfiguration{}
+	for _, tool := range tools {
+		config.Tools = append(config.Tools, &types.ToolMemberToolSpec{Value: types.ToolSpecification{
+			Name:        aws.String(tool.Name),
+			Description: aws.String(tool.Description),
+			InputSchema: &types.ToolInputSchemaMemberJson{Value: document.NewLazyDocument(tool.InputSchema)},
+		}})
+	}
+	return config
+}
+
+func toToolUseBlock(call ToolCall) (types.ContentBlock, error) {
+	var input any = map[string]any{}
+	if len(call.Input) > 0 {
+		if err := json.Unmarshal(call.Input, &input); err != nil {
+			return nil, fmt.Errorf("invalid input for tool call %s: %w", call.ID, err)
+		}
+	}
+
+	return &types.ContentBlockMemberToolUse{Value: types.ToolUseBlock{
+		ToolUseId: aws.String(call.ID),
+		Name:      aws.String(call.Name),
+		Input:     document.NewLazyDocument(input),
+	}}, nil
+}
+
+func toToolResultBlock(result ToolResult) types.ContentBlock {
+	status := types.ToolResultStatusSuccess
+	if result.IsError {
+		status = types.ToolResultStatusError
+	}
+
+	return &types.ContentBlockMemberToolResult{Value: types.ToolResultBlock{
+		ToolUseId: aws.String(result.ToolCallID),
+		Content:   []types.ToolResultContentBlock{&types.ToolResultContentBlockMemberText{Value: result.Content}},
+		Status:    status,
+	}}
+}
+
+func fromToolUseBlock(block types.ToolUseBlock) (ToolCall, error) {
+	call := ToolCall{ID: aws.ToString(block.ToolUseId), Name: aws.ToString(block.Name), Input: json.RawMessage("{}")}
+	if block.Input != nil {
+		input, err := block.Input.MarshalSmithyDocument()
+		if err != nil {
+			return ToolCall{}, fmt.Errorf("failed to decode input of tool call %s: %w", call.ID, err)
+		}
+		call.Input = input
+	}
+	return call, nil
+}
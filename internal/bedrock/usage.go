@@ -0,0 +1,95 @@
+package bedrock
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// Token count headers Bedrock sets on every InvokeModel response
+const (
+	inputTokenCountHeader  = "X-Amzn-Bedrock-Input-Token-Count"
+	outputTokenCountHeader = "X-Amzn-Bedrock-Output-Token-Count"
+)
+
+// Price is the on-demand price of a model in USD per 1,000 tokens
+type Price struct {
+	InputPer1K  float64
+	OutputPer1K float64
+}
+
+// PriceTable maps base model IDs (no region or inference-profile prefix) to their price
+type PriceTable map[string]Price
+
+// DefaultPrices holds the us-east-1 on-demand prices of the models this project supports.
+// Prices change over time; check the Bedrock pricing page before relying on the totals.
+var DefaultPrices = PriceTable{
+	"anthropic.claude-v2":                       {InputPer1K: 0.008, OutputPer1K: 0.024},
+	"anthropic.claude-v2:1":                     {InputPer1K: 0.008, OutputPer1K: 0.024},
+	"anthropic.claude-instant-v1":               {InputPer1K: 0.0008, OutputPer1K: 0.0024},
+	"anthropic.claude-3-haiku-20240307-v1:0":    {InputPer1K: 0.00025, OutputPer1K: 0.00125},
+	"anthropic.claude-3-sonnet-20240229-v1:0":   {InputPer1K: 0.003, OutputPer1K: 0.015},
+	"anthropic.claude-3-opus-20240229-v1:0":     {InputPer1K: 0.015, OutputPer1K: 0.075},
+	"anthropic.claude-3-5-haiku-20241022-v1:0":  {InputPer1K: 0.0008, OutputPer1K: 0.004},
+	"anthropic.claude-3-5-sonnet-20240620-v1:0": {InputPer1K: 0.003, OutputPer1K: 0.015},
+	"anthropic.claude-3-5-sonnet-20241022-v2:0": {InputPer1K: 0.003, OutputPer1K: 0.015},
+	"amazon.titan-text-lite-v1":                 {InputPer1K: 0.00015, OutputPer1K: 0.0002},
+	"amazon.titan-text-express-v1":              {InputPer1K: 0.0002, OutputPer1K: 0.0006},
+	"amazon.titan-text-premier-v1:0":            {InputPer1K: 0.0005, OutputPer1K: 0.0015},
+	"meta.llama2-13b-chat-v1":                   {InputPer1K: 0.00075, OutputPer1K: 0.001},
+	"meta.llama2-70b-chat-v1":                   {InputPer1K: 0.00195, OutputPer1K: 0.00256},
+	"meta.llama3-8b-instruct-v1:0":              {InputPer1K: 0.0003, OutputPer1K: 0.0006},
+	"meta.llama3-70b-instruct-v1:0":             {InputPer1K: 0.00265, OutputPer1K: 0.0035},
+	"mistral.mistral-7b-instruct-v0:2":          {InputPer1K: 0.00015, OutputPer1K: 0.0002},
+	"mistral.mixtral-8x7b-instruct-v0:1":        {InputPer1K: 0.00045, OutputPer1K: 0.0007},
+	"mistral.mistral-large-2402-v1:0":           {InputPer1K: 0.004, OutputPer1K: 0.012},
+	"cohere.command-text-v14":                   {InputPer1K: 0.0015, OutputPer1K: 0.002},
+	"cohere.command-light-text-v14":             {InputPer1K: 0.0003, OutputPer1K: 0.0006},
+	"cohere.command-r-v1:0":                     {InputPer1K: 0.0005, OutputPer1K: 0.0015},
+	"cohere.command-r-plus-v1:0":                {InputPer1K: 0.003, OutputPer1K: 0.015},
+}
+
+// Lookup returns the price of modelID. Cross-region inference profile IDs and ARNs
+// resolve to the price of the underlying model.
+func (t PriceTable) Lookup(modelID string) (Price, bool) {
+	if price, ok := t[modelID]; ok {
+		return price, true
+	}
+
+	price, ok := t[baseModelID(modelID)]
+	return price, ok
+}
+
+// Cost returns the price of usage for modelID in USD, and false if the model has no price
+func (t PriceTable) Cost(modelID string, usage Usage) (float64, bool) {
+	price, ok := t.Lookup(modelID)
+	if !ok {
+		return 0, false
+	}
+
+	return float64(usage.InputTokens)/1000*price.InputPer1K + float64(usage.OutputTokens)/1000*price.OutputPer1K, true
+}
+
+// headerUsage reads the token counts from the raw HTTP response of an InvokeModel call
+func headerUsage(metadata middleware.Metadata) *Usage {
+	raw, ok := awsmiddleware.GetRawResponse(metadata).(*smithyhttp.Response)
+	if !ok {
+		return nil
+	}
+
+	return usageFromHeaders(raw.Header)
+}
+
+func usageFromHeaders(header http.Header) *Usage {
+	input, inputErr := strconv.Atoi(strings.TrimSpace(header.Get(inputTokenCountHeader)))
+	output, outputErr := strconv.Atoi(strings.TrimSpace(header.Get(outputTokenCountHeader)))
+	if inputErr != nil || outputErr != nil {
+		return nil
+	}
+
+	return &Usage{InputTokens: input, OutputTokens: output}
+}
@@ -0,0 +1,332 @@
+package eval
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"aws-bedrock-prompt-engineering/internal/prompting"
+)
+
+// Matrix compares techniques and models on one dataset, one report per technique × model
+type Matrix struct {
+	Dataset string    `json:"dataset"`
+	Metric  string    `json:"metric"`  // the dataset's first metric, reported as accuracy
+	Reports []*Report `json:"reports"` // technique-major, in the order they were compared
+	Skipped []string  `json:"skipped"` // techniques the dataset has no prompt for
+
+	// Pairwise[i] is the judge's comparison of Reports[i] with the baseline Reports[0], which
+	// has none; empty unless JudgePairwise ran
+	Pairwise []*Pairwise `json:"pairwise,omitempty"`
+	Judge    string      `json:"judge,omitempty"` // the grader model of the pairwise comparisons
+}
+
+// Pairwise verdicts, from the challenger's side
+const (
+	PairwiseWin  = "win"
+	PairwiseTie  = "tie"
+	PairwiseLoss = "loss"
+)
+
+// PairwiseCase is the judge's verdict on one case of a pairwise comparison
+type PairwiseCase struct {
+	ID         string   `json:"id"`
+	Verdict    string   `json:"verdict,omitempty"` // PairwiseWin, PairwiseTie or PairwiseLoss for the challenger
+	Consistent bool     `json:"consistent"`        // both orderings agreed
+	Rationales []string `json:"rationales,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Pairwise compares a challenger report's outputs with a baseline's, case by case
+type Pairwise struct {
+	Baseline     string         `json:"baseline"` // "technique on model"
+	Challenger   string         `json:"challenger"`
+	Cases        []PairwiseCase `json:"cases"`
+	Wins         int            `json:"wins"`
+	Ties         int            `json:"ties"`
+	Losses       int            `json:"losses"`
+	Inconsistent int            `json:"inconsistent"` // verdicts that flipped when the outputs were swapped, counted as ties
+	Errors       int            `json:"errors"`       // cases that failed on either side or could not be judged
+}
+
+// WinRate is the challenger's share of the judged cases, with ties counting half
+func (p *Pairwise) WinRate() float64 {
+	judged := p.Wins + p.Ties + p.Losses
+	if judged == 0 {
+		return 0
+	}
+	return (float64(p.Wins) + float64(p.Ties)/2) / float64(judged)
+}
+
+// Compare runs ds with every technique on every model. A technique runs the dataset's variant
+// for it, or the dataset's prompt when that prompt is written for the technique; techniques with
+// neither are skipped. An empty model ID uses the technique's default model.
+func (r *Runner) Compare(ctx context.Context, ds *Dataset, techniques []prompting.Technique, modelIDs []string) (*Matrix, error) {
+	if len(modelIDs) == 0 {
+		modelIDs = []string{""}
+	}
+
+	matrix := &Matrix{Dataset: ds.Name, Metric: ds.Metrics[0]}
+	for _, technique := range techniques {
+		name := technique.Info().Name
+		if !r.supports(ds, name) {
+			matrix.Skipped = append(matrix.Skipped, name)
+			continue
+		}
+
+		for _, modelID := range modelIDs {
+			report, err := r.Run(ctx, ds, technique, modelID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compare %s with %s: %w", name, modelLabel(modelID), err)
+			}
+			matrix.Reports = append(matrix.Reports, report)
+		}
+	}
+	return matrix, nil
+}
+
+// JudgePairwise has the runner's judge compare every report of m with the first one, the
+// baseline, case by case against the dataset's rubric
+func (r *Runner) JudgePairwise(ctx context.Context, ds *Dataset, m *Matrix) error {
+	if r.Judge == nil {
+		return fmt.Errorf("dataset %q: pairwise comparison needs a judge", ds.Name)
+	}
+	for _, c := range ds.Cases {
+		if ds.rubric(c) == "" {
+			return fmt.Errorf("dataset %q: case %q has no rubric to judge with", ds.Name, c.ID)
+		}
+	}
+	if len(m.Reports) < 2 {
+		return fmt.Errorf("dataset %q: pairwise comparison needs at least two reports", ds.Name)
+	}
+
+	baseline := m.Reports[0]
+	pairwise := make([]*Pairwise, len(m.Reports))
+	for i, challenger := range m.Reports[1:] {
+		p, err := r.pairwise(ctx, ds, baseline, challenger)
+		if err != nil {
+			return err
+		}
+		pairwise[i+1] = p
+	}
+
+	m.Pairwise, m.Judge = pairwise, r.Judge.ModelID()
+	return nil
+}
+
+func (r *Runner) pairwise(ctx context.Context, ds *Dataset, baseline, challenger *Report) (*Pairwise, error) {
+	p := &Pairwise{
+		Baseline:   reportLabel(baseline),
+		Challenger: reportLabel(challenger),
+		Cases:      make([]PairwiseCase, len(ds.Cases)),
+	}
+
+	slots := make(chan struct{}, max(r.Concurrency, 1))
+	var wg sync.WaitGroup
+	for i, c := range ds.Cases {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			p.Cases[i] = r.judgeCase(ctx, ds, c, baseline.Cases[i], challenger.Cases[i])
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, c := range p.Cases {
+		switch {
+		case c.Error != "":
+			p.Errors++
+			continue
+		case c.Verdict == PairwiseWin:
+			p.Wins++
+		case c.Verdict == PairwiseLoss:
+			p.Losses++
+		default:
+			p.Ties++
+		}
+		if !c.Consistent {
+			p.Inconsistent++
+		}
+	}
+	return p, nil
+}
+
+// judgeCase shows the baseline's output as A and the challenger's as B; the judge also swaps them
+func (r *Runner) judgeCase(ctx context.Context, ds *Dataset, c Case, baseline, challenger CaseResult) PairwiseCase {
+	result := PairwiseCase{ID: c.ID}
+	if baseline.Error != "" || challenger.Error != "" {
+		result.Error = "case failed"
+		return result
+	}
+
+	preference, err := r.Judge.Pairwise(ctx, baseline.Prompt, ds.rubric(c), baseline.Completion, challenger.Completion)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Verdict = map[string]string{WinnerA: PairwiseLoss, WinnerB: PairwiseWin, Tie: PairwiseTie}[preference.Winner]
+	result.Consistent, result.Rationales = preference.Consistent, preference.Rationales
+	return result
+}
+
+func reportLabel(r *Report) string {
+	return r.Technique + " on " + r.ModelID
+}
+
+// supports reports whether ds has a prompt written for the named technique
+func (r *Runner) supports(ds *Dataset, technique string) bool {
+	if _, ok := ds.Variants[technique]; ok {
+		return true
+	}
+	if ds.Technique != "" {
+		return ds.Technique == technique
+	}
+	spec, ok := r.library.Get(ds.Prompt)
+	return ok && spec.Technique == technique
+}
+
+func modelLabel(modelID string) string {
+	if modelID == "" {
+		return "the default model"
+	}
+	return modelID
+}
+
+// matrixRow is one technique × model of a matrix with the compared figures
+type matrixRow struct {
+	report   *Report
+	accuracy MetricSummary
+	pairwise *Pairwise // nil for the baseline and when there was no pairwise comparison
+}
+
+func (m *Matrix) rows() []matrixRow {
+	rows := make([]matrixRow, len(m.Reports))
+	for i, report := range m.Reports {
+		accuracy, _ := report.Metric(m.Metric)
+		rows[i] = matrixRow{report: report, accuracy: accuracy}
+		if i < len(m.Pairwise) {
+			rows[i].pairwise = m.Pairwise[i]
+		}
+	}
+	return rows
+}
+
+// priced reports whether the row's cost covers all of its calls
+func (row matrixRow) priced() bool {
+	return row.report.Usage.Unpriced == 0
+}
+
+// WriteMarkdown writes one table per matrix with accuracy, latency, tokens and cost for each
+// technique × model
+func WriteMarkdown(w io.Writer, matrices []*Matrix) error {
+	var b strings.Builder
+	for i, m := range matrices {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		cases := 0
+		if len(m.Reports) > 0 {
+			cases = len(m.Reports[0].Cases)
+		}
+
+		fmt.Fprintf(&b, "### %s\n\n", m.Dataset)
+		fmt.Fprintf(&b, "Accuracy is the mean %s over %d cases; latency is per case; tokens and cost cover every call.\n\n", m.Metric, cases)
+		b.WriteString("| Technique | Model | Prompt | Accuracy | Errors | Latency | Input tokens | Output tokens | Cost |")
+		if m.Pairwise != nil {
+			b.WriteString(" Preferred |")
+		}
+		b.WriteString("\n|-----------|-------|--------|---------:|-------:|--------:|-------------:|--------------:|-----:|")
+		if m.Pairwise != nil {
+			b.WriteString("----------:|")
+		}
+		b.WriteString("\n")
+
+		inconsistent := 0
+		for _, row := range m.rows() {
+			r := row.report
+			cost := fmt.Sprintf("$%.4f", r.Usage.Cost)
+			if !row.priced() {
+				cost += " (unpriced calls)"
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %.1f%% | %d | %s | %d | %d | %s |",
+				r.Technique, r.ModelID, r.Prompt, row.accuracy.Mean*100, r.Errors,
+				r.Latency.Round(time.Millisecond), r.Usage.InputTokens, r.Usage.OutputTokens, cost)
+			switch {
+			case m.Pairwise == nil:
+			case row.pairwise == nil:
+				b.WriteString(" baseline |")
+			default:
+				p := row.pairwise
+				fmt.Fprintf(&b, " %.0f%% (%d-%d-%d) |", p.WinRate()*100, p.Wins, p.Ties, p.Losses)
+				inconsistent += p.Inconsistent
+			}
+			b.WriteString("\n")
+		}
+		if m.Pairwise != nil {
+			fmt.Fprintf(&b, "\nPreferred is how often %s preferred the output to the baseline's, as wins-ties-losses;"+
+				" %d verdict(s) flipped when the outputs were swapped and count as ties.\n", m.Judge, inconsistent)
+		}
+		if len(m.Skipped) > 0 {
+			fmt.Fprintf(&b, "\nNo prompt for: %s\n", strings.Join(m.Skipped, ", "))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteCSV writes every matrix as rows of one CSV table with a header, in raw units for spreadsheets.
+// The pairwise columns are empty for baselines and matrices without a pairwise comparison.
+func WriteCSV(w io.Writer, matrices []*Matrix) error {
+	writer := csv.NewWriter(w)
+	header := []string{"dataset", "technique", "model_id", "prompt", "metric", "accuracy", "perfect", "scored",
+		"errors", "mean_latency_ms", "calls", "input_tokens", "output_tokens", "cost_usd", "unpriced_calls",
+		"win_rate", "wins", "ties", "losses"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, m := range matrices {
+		for _, row := range m.rows() {
+			r := row.report
+			record := []string{
+				m.Dataset, r.Technique, r.ModelID, r.Prompt, m.Metric,
+				strconv.FormatFloat(row.accuracy.Mean, 'f', 4, 64),
+				strconv.Itoa(row.accuracy.Perfect),
+				strconv.Itoa(row.accuracy.Scored),
+				strconv.Itoa(r.Errors),
+				strconv.FormatInt(r.Latency.Milliseconds(), 10),
+				strconv.Itoa(r.Usage.Calls),
+				strconv.Itoa(r.Usage.InputTokens),
+				strconv.Itoa(r.Usage.OutputTokens),
+				strconv.FormatFloat(r.Usage.Cost, 'f', 6, 64),
+				strconv.Itoa(r.Usage.Unpriced),
+			}
+			if p := row.pairwise; p != nil {
+				record = append(record, strconv.FormatFloat(p.WinRate(), 'f', 4, 64),
+					strconv.Itoa(p.Wins), strconv.Itoa(p.Ties), strconv.Itoa(p.Losses))
+			} else {
+				record = append(record, "", "", "", "")
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,111 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+	"aws-bedrock-prompt-engineering/internal/bedrock/bedrocktest"
+	"aws-bedrock-prompt-engineering/internal/prompting"
+)
+
+var compareDataset = []byte(`
+name: sentiment
+prompt: sentiment-analysis
+variants:
+  zero-shot: text-classification
+  chain-of-thought: sentiment-reasoning
+metrics: [label_accuracy]
+labels: [positive, negative, neutral]
+cases:
+  - id: sarcasm
+    inputs: {text: "Oh great, another outage."}
+    expected: negative
+  - id: happy
+    inputs: {text: Works perfectly.}
+    expected: positive
+`)
+
+func TestRunnerCompare(t *testing.T) {
+	ds, err := ParseDataset("sentiment.yaml", compareDataset)
+	if err != nil {
+		t.Fatalf("ParseDataset() error = %v", err)
+	}
+
+	usage := &bedrock.Usage{InputTokens: 1000, OutputTokens: 100}
+	fake := bedrocktest.NewFake().
+		// Zero-shot takes the sarcasm at face value; chain-of-thought sees through it after weighing "positive"
+		RespondWith(`(?s)Classification:$`, &bedrock.ModelResponse{Completion: "positive", Usage: usage}).
+		RespondWith(`(?s)another outage.*Reasoning:$`, &bedrock.ModelResponse{Completion: "1. \"Great\" looks positive.\n2. An outage is bad, so it is sarcastic.\nAnswer: negative", Usage: usage}).
+		RespondWith(`(?s)Works perfectly.*Reasoning:$`, &bedrock.ModelResponse{Completion: "1. Nothing is wrong.\nAnswer: positive", Usage: usage}).
+		RespondWith(`(?s)another outage.*Sentiment:$`, &bedrock.ModelResponse{Completion: "negative", Usage: usage}).
+		RespondWith(`(?s)Works perfectly.*Sentiment:$`, &bedrock.ModelResponse{Completion: "positive", Usage: usage})
+
+	techniques := []prompting.Technique{prompting.ZeroShot, prompting.FewShot, prompting.ChainOfThought, prompting.TreeOfThoughts}
+	models := []string{testModelID, "example.unpriced-model"}
+	matrix, err := NewRunner(fake, prompting.DefaultEvalLibrary()).Compare(context.Background(), ds, techniques, models)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+
+	if strings.Join(matrix.Skipped, ",") != prompting.TechniqueTreeOfThoughts {
+		t.Errorf("Skipped = %v, want the technique without a prompt", matrix.Skipped)
+	}
+	if len(matrix.Reports) != 6 {
+		t.Fatalf("got %d reports, want 3 techniques × 2 models", len(matrix.Reports))
+	}
+
+	want := []struct {
+		technique, prompt, model string
+		accuracy                 float64
+	}{
+		{prompting.TechniqueZeroShot, "text-classification", testModelID, 0.5},
+		{prompting.TechniqueZeroShot, "text-classification", "example.unpriced-model", 0.5},
+		{prompting.TechniqueFewShot, "sentiment-analysis", testModelID, 1},
+		{prompting.TechniqueFewShot, "sentiment-analysis", "example.unpriced-model", 1},
+		{prompting.TechniqueChainOfThought, "sentiment-reasoning", testModelID, 1},
+		{prompting.TechniqueChainOfThought, "sentiment-reasoning", "example.unpriced-model", 1},
+	}
+	for i, w := range want {
+		report := matrix.Reports[i]
+		accuracy, _ := report.Metric(MetricLabelAccuracy)
+		if report.Technique != w.technique || report.Prompt != w.prompt || report.ModelID != w.model || accuracy.Mean != w.accuracy {
+			t.Errorf("report %d = %s / %s / %s at %.2f, want %s / %s / %s at %.2f", i,
+				report.Technique, report.Prompt, report.ModelID, accuracy.Mean, w.technique, w.prompt, w.model, w.accuracy)
+		}
+	}
+
+	var markdown bytes.Buffer
+	if err := WriteMarkdown(&markdown, []*Matrix{matrix}); err != nil {
+		t.Fatalf("WriteMarkdown() error = %v", err)
+	}
+	for _, want := range []string{
+		"### sentiment",
+		"| zero-shot | " + testModelID + " | text-classification | 50.0% | 0 |",
+		"| 2000 | 200 | $0.0008 |",
+		"(unpriced calls)",
+		"No prompt for: tree-of-thoughts",
+	} {
+		if !strings.Contains(markdown.String(), want) {
+			t.Errorf("Markdown is missing %q:\n%s", want, markdown.String())
+		}
+	}
+
+	var out bytes.Buffer
+	if err := WriteCSV(&out, []*Matrix{matrix}); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("CSV does not parse: %v", err)
+	}
+	if len(records) != 7 {
+		t.Fatalf("got %d CSV records, want a header and 6 rows", len(records))
+	}
+	if got := strings.Join(records[1], ","); got != "sentiment,zero-shot,"+testModelID+",text-classification,label_accuracy,0.5000,1,2,0,0,2,2000,200,0.000750,0,,,," {
+		t.Errorf("first CSV row = %s", got)
+	}
+}
@@ -0,0 +1,189 @@
+// Package eval scores prompting techniques against golden datasets: each case fills a library
+// prompt, the technique runs it, and metrics compare the output with the expected answer.
+package eval
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"aws-bedrock-prompt-engineering/internal/prompting"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Dataset is a golden set of cases for one library prompt
+type Dataset struct {
+	Name      string            `yaml:"name"`      // defaults to the file name without extension
+	Prompt    string            `yaml:"prompt"`    // library prompt the cases' inputs are rendered into
+	Technique string            `yaml:"technique"` // technique to run; defaults to the prompt's technique
+	Variants  map[string]string `yaml:"variants"`  // prompts for the same task by technique, e.g. {zero-shot: text-classification}
+	Metrics   []string          `yaml:"metrics"`   // metric names, e.g. "label_accuracy"
+	Labels    []string          `yaml:"labels"`    // allowed labels for label_accuracy; defaults to the expected values
+	Rubric    string            `yaml:"rubric"`    // what a good output does, for the judge metric
+	Cases     []Case            `yaml:"cases"`
+
+	Source string `yaml:"-"` // file the dataset was loaded from
+}
+
+// Case is one input of a dataset with what a correct output looks like. Each metric reads
+// the expectation it needs and skips cases without one.
+type Case struct {
+	ID       string             `yaml:"id"`       // defaults to "case-N"
+	Inputs   map[string]string  `yaml:"inputs"`   // values for the prompt's variables
+	Expected string             `yaml:"expected"` // expected answer, label or number
+	Pattern  string             `yaml:"pattern"`  // regular expression a correct completion matches
+	Entities []prompting.Entity `yaml:"entities"` // expected entities; parsed from Expected when empty
+	Rubric   string             `yaml:"rubric"`   // criteria for this case, added to the dataset's rubric
+
+	pattern *regexp.Regexp
+}
+
+// LoadDatasets reads every .yaml, .yml and .json file in fsys, each holding one dataset,
+// in lexical order
+func LoadDatasets(fsys fs.FS) ([]*Dataset, error) {
+	var datasets []*Dataset
+	names := map[string]string{}
+
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch path.Ext(name) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		ds, err := ParseDataset(name, data)
+		if err != nil {
+			return err
+		}
+		if existing, ok := names[ds.Name]; ok {
+			return fmt.Errorf("%s: dataset %q is already defined in %s", name, ds.Name, existing)
+		}
+
+		names[ds.Name] = name
+		datasets = append(datasets, ds)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load datasets: %w", err)
+	}
+
+	return datasets, nil
+}
+
+// ParseDataset decodes and validates one dataset file. The file name supplies the default dataset name.
+func ParseDataset(file string, data []byte) (*Dataset, error) {
+	// JSON is valid YAML, so one decoder covers both formats
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	ds := &Dataset{}
+	if err := decoder.Decode(ds); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	ds.Source = file
+	if ds.Name == "" {
+		ds.Name = strings.TrimSuffix(path.Base(file), path.Ext(file))
+	}
+	if err := ds.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return ds, nil
+}
+
+func (ds *Dataset) validate() error {
+	switch {
+	case ds.Prompt == "":
+		return fmt.Errorf("dataset %q has no prompt", ds.Name)
+	case len(ds.Metrics) == 0:
+		return fmt.Errorf("dataset %q has no metrics", ds.Name)
+	case len(ds.Cases) == 0:
+		return fmt.Errorf("dataset %q has no cases", ds.Name)
+	}
+
+	for technique, prompt := range ds.Variants {
+		if prompt == "" {
+			return fmt.Errorf("dataset %q: %s variant has no prompt", ds.Name, technique)
+		}
+	}
+	for _, name := range ds.Metrics {
+		if _, ok := MetricFor(name); !ok {
+			return fmt.Errorf("dataset %q: unknown metric %q (registered: %s)", ds.Name, name, strings.Join(MetricNames(), ", "))
+		}
+	}
+
+	ids := map[string]bool{}
+	for i := range ds.Cases {
+		c := &ds.Cases[i]
+		if c.ID == "" {
+			c.ID = fmt.Sprintf("case-%d", i+1)
+		}
+		if ids[c.ID] {
+			return fmt.Errorf("dataset %q: case %q is defined twice", ds.Name, c.ID)
+		}
+		ids[c.ID] = true
+
+		if c.Pattern != "" {
+			pattern, err := regexp.Compile(c.Pattern)
+			if err != nil {
+				return fmt.Errorf("dataset %q: case %q: invalid pattern: %w", ds.Name, c.ID, err)
+			}
+			c.pattern = pattern
+		}
+	}
+
+	if slices.Contains(ds.Metrics, MetricJudge) {
+		for _, c := range ds.Cases {
+			if ds.rubric(c) == "" {
+				return fmt.Errorf("dataset %q: case %q: the judge metric needs a rubric", ds.Name, c.ID)
+			}
+		}
+	}
+	return nil
+}
+
+// rubric returns the dataset's rubric followed by the case's own criteria
+func (ds *Dataset) rubric(c Case) string {
+	return strings.TrimSpace(strings.TrimSpace(ds.Rubric) + "\n" + strings.TrimSpace(c.Rubric))
+}
+
+// PromptFor returns the library prompt that runs the dataset with the named technique:
+// its variant for the technique, or the dataset's prompt
+func (ds *Dataset) PromptFor(technique string) string {
+	if prompt, ok := ds.Variants[technique]; ok {
+		return prompt
+	}
+	return ds.Prompt
+}
+
+// labels returns the labels label_accuracy accepts: the declared ones, or every expected value
+func (ds *Dataset) labels() []string {
+	if len(ds.Labels) > 0 {
+		return ds.Labels
+	}
+
+	var labels []string
+	seen := map[string]bool{}
+	for _, c := range ds.Cases {
+		label := strings.ToLower(strings.TrimSpace(c.Expected))
+		if label != "" && !seen[label] {
+			seen[label] = true
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
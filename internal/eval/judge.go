@@ -0,0 +1,285 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+	"aws-bedrock-prompt-engineering/internal/prompting"
+)
+
+// Pairwise verdicts
+const (
+	WinnerA = "A"
+	WinnerB = "B"
+	Tie     = "tie"
+)
+
+// Grade is a judge's score for one output
+type Grade struct {
+	Score     int    `json:"score"` // from 1 to the judge's scale
+	Rationale string `json:"rationale"`
+}
+
+// Preference is a judge's verdict on two outputs for the same task
+type Preference struct {
+	Winner     string   `json:"winner"`     // WinnerA, WinnerB or Tie
+	Consistent bool     `json:"consistent"` // both orderings picked the same winner
+	Rationales []string `json:"rationales"` // with A shown first, then with B shown first
+}
+
+// Judge grades open-ended outputs, such as stories or plans, by asking a grader model to apply a rubric
+type Judge struct {
+	client bedrock.Invoker
+	params bedrock.ModelParams
+	ledger *bedrock.Ledger
+
+	Scale  int // grades run from 1 to Scale
+	Reasks int // re-asks after a verdict that does not parse
+}
+
+// NewJudge creates a judge that grades with modelID, or with JUDGE_MODEL_ID and then MODEL_ID
+// when it is empty. The grader runs at temperature 0 so the same output gets the same grade.
+func NewJudge(client bedrock.Invoker, modelID string) *Judge {
+	params := bedrock.GetDefaultClaudeParams()
+	params.Temperature = 0
+	if modelID == "" {
+		modelID = os.Getenv("JUDGE_MODEL_ID")
+	}
+	if modelID != "" {
+		params.ModelID = modelID
+	}
+
+	ledger := bedrock.NewLedger(bedrock.DefaultPrices)
+	return &Judge{
+		client: ledger.Meter(client, "judge"),
+		params: params,
+		ledger: ledger,
+		Scale:  5,
+		Reasks: 2,
+	}
+}
+
+// ModelID returns the grader model
+func (j *Judge) ModelID() string {
+	return j.params.ModelID
+}
+
+// Usage returns the tokens and cost of every grading call so far
+func (j *Judge) Usage() bedrock.UsageTotals {
+	return j.ledger.Session()
+}
+
+// metric returns the judge metric for one run: it grades each output against the case's rubric,
+// with the expected answer, if any, as the reference, and scales the grade to 0–1
+func (j *Judge) metric(ctx context.Context) Metric {
+	return NewMetric(MetricJudge, func(ds *Dataset, c Case, out Output) Score {
+		grade, err := j.Grade(ctx, out.Prompt, ds.rubric(c), c.Expected, out.Completion)
+		if err != nil {
+			return Score{Detail: err.Error()}
+		}
+		value := float64(grade.Score-1) / float64(max(j.Scale-1, 1))
+		return Score{Value: value, Detail: fmt.Sprintf("%d/%d: %s", grade.Score, j.Scale, grade.Rationale)}
+	})
+}
+
+var gradeTemplate = prompting.MustTemplate("judge grade", `You are grading the output of another model. Read the task, the rubric and the output, then grade the output from 1 (fails the rubric) to {{scale}} (fully meets it). Judge only against the rubric, not against length or style it does not ask for.
+
+<task>
+{{task}}
+</task>
+
+<rubric>
+{{rubric}}
+</rubric>
+{{reference}}
+<output>
+{{output}}
+</output>
+
+Reply with a JSON object only: {"rationale": "<one or two sentences>", "score": <1 to {{scale}}>}`,
+	prompting.Variable{Name: "scale", Type: prompting.TypeInt, Required: true},
+	prompting.Variable{Name: "task", Type: prompting.TypeText, Required: true},
+	prompting.Variable{Name: "rubric", Type: prompting.TypeText, Required: true, Raw: true},
+	prompting.Variable{Name: "reference", Type: prompting.TypeText, Raw: true},
+	prompting.Variable{Name: "output", Type: prompting.TypeText, Required: true},
+)
+
+var pairwiseTemplate = prompting.MustTemplate("judge pairwise", `You are comparing two outputs of other models for the same task. Read the task and the rubric, then decide which output meets the rubric better. Their order says nothing about their quality, and neither does their length.
+
+<task>
+{{task}}
+</task>
+
+<rubric>
+{{rubric}}
+</rubric>
+
+<output_a>
+{{a}}
+</output_a>
+
+<output_b>
+{{b}}
+</output_b>
+
+Reply with a JSON object only: {"rationale": "<one or two sentences>", "winner": "A", "B" or "tie"}`,
+	prompting.Variable{Name: "task", Type: prompting.TypeText, Required: true},
+	prompting.Variable{Name: "rubric", Type: prompting.TypeText, Required: true, Raw: true},
+	prompting.Variable{Name: "a", Type: prompting.TypeText, Required: true},
+	prompting.Variable{Name: "b", Type: prompting.TypeText, Required: true},
+)
+
+// Grade scores output for the task against rubric. A reference answer, when given, is shown
+// to the grader as an example of a good output.
+func (j *Judge) Grade(ctx context.Context, task, rubric, reference, output string) (*Grade, error) {
+	if reference != "" {
+		reference = "\n<reference>\n" + prompting.EscapeInput(reference) + "\n</reference>\n"
+	}
+	prompt, err := gradeTemplate.Render(map[string]string{
+		"scale":     strconv.Itoa(j.Scale),
+		"task":      task,
+		"rubric":    rubric,
+		"reference": reference,
+		"output":    output,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := prompting.InvokeParsed(ctx, j.client, prompt, j.params, gradeParser{scale: j.Scale}, j.Reasks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to grade output: %w", err)
+	}
+	return &parsed.Value, nil
+}
+
+// Pairwise asks which of two outputs meets the rubric better. Graders tend to favour the output
+// they read first, so the outputs are compared in both orders: a winner must win both, and
+// orderings that disagree count as a tie.
+func (j *Judge) Pairwise(ctx context.Context, task, rubric, a, b string) (*Preference, error) {
+	type verdict struct {
+		winner, rationale string
+		err               error
+	}
+	var verdicts [2]verdict
+
+	var wg sync.WaitGroup
+	for i, pair := range [2][2]string{{a, b}, {b, a}} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			winner, rationale, err := j.prefer(ctx, task, rubric, pair[0], pair[1])
+			verdicts[i] = verdict{winner, rationale, err}
+		}()
+	}
+	wg.Wait()
+
+	for _, v := range verdicts {
+		if v.err != nil {
+			return nil, fmt.Errorf("failed to compare outputs: %w", v.err)
+		}
+	}
+
+	// The second ordering showed b as A, so its verdict is swapped back
+	swapped := map[string]string{WinnerA: WinnerB, WinnerB: WinnerA, Tie: Tie}[verdicts[1].winner]
+	preference := &Preference{
+		Winner:     Tie,
+		Consistent: verdicts[0].winner == swapped,
+		Rationales: []string{verdicts[0].rationale, verdicts[1].rationale},
+	}
+	if preference.Consistent {
+		preference.Winner = verdicts[0].winner
+	}
+	return preference, nil
+}
+
+// prefer returns the winner of one ordering, as shown to the grader
+func (j *Judge) prefer(ctx context.Context, task, rubric, a, b string) (string, string, error) {
+	prompt, err := pairwiseTemplate.Render(map[string]string{"task": task, "rubric": rubric, "a": a, "b": b})
+	if err != nil {
+		return "", "", err
+	}
+
+	parsed, err := prompting.InvokeParsed(ctx, j.client, prompt, j.params, preferenceParser{}, j.Reasks)
+	if err != nil {
+		return "", "", err
+	}
+	return parsed.Value.winner, parsed.Value.rationale, nil
+}
+
+var (
+	jsonObjectPattern = regexp.MustCompile(`(?s)\{.*\}`)
+	scoreLinePattern  = regexp.MustCompile(`(?i)\bscore\W+(\d+)`)
+	winnerLinePattern = regexp.MustCompile(`(?i)\bwinner\W+(a|b|tie)\b`)
+)
+
+// gradeParser reads a grade from the grader's JSON reply, or from a "Score: 4" line when the
+// grader ignored the format
+type gradeParser struct {
+	scale int
+}
+
+func (p gradeParser) Parse(completion string) (Grade, error) {
+	var grade Grade
+	if object := jsonObjectPattern.FindString(completion); object == "" || json.Unmarshal([]byte(object), &grade) != nil {
+		match := scoreLinePattern.FindStringSubmatch(completion)
+		if match == nil {
+			return Grade{}, &prompting.ParseError{Format: "grade", Completion: completion, Reason: "the reply has no score"}
+		}
+		grade = Grade{Rationale: strings.TrimSpace(completion)}
+		grade.Score, _ = strconv.Atoi(match[1])
+	}
+
+	if grade.Score < 1 || grade.Score > p.scale {
+		reason := fmt.Sprintf("the score %d is not between 1 and %d", grade.Score, p.scale)
+		return Grade{}, &prompting.ParseError{Format: "grade", Completion: completion, Reason: reason}
+	}
+	return grade, nil
+}
+
+func (p gradeParser) Instructions() string {
+	return fmt.Sprintf(`Reply with a JSON object only, such as {"rationale": "...", "score": 3}, with a whole-number score from 1 to %d.`, p.scale)
+}
+
+type preference struct {
+	winner, rationale string
+}
+
+// preferenceParser reads the winner from the grader's JSON reply, or from a "Winner: A" line
+type preferenceParser struct{}
+
+func (preferenceParser) Parse(completion string) (preference, error) {
+	var reply struct {
+		Winner    string `json:"winner"`
+		Rationale string `json:"rationale"`
+	}
+	if object := jsonObjectPattern.FindString(completion); object == "" || json.Unmarshal([]byte(object), &reply) != nil {
+		match := winnerLinePattern.FindStringSubmatch(completion)
+		if match == nil {
+			return preference{}, &prompting.ParseError{Format: "preference", Completion: completion, Reason: "the reply names no winner"}
+		}
+		reply.Winner, reply.Rationale = match[1], strings.TrimSpace(completion)
+	}
+
+	switch winner := strings.TrimSpace(reply.Winner); {
+	case strings.EqualFold(winner, WinnerA):
+		return preference{WinnerA, reply.Rationale}, nil
+	case strings.EqualFold(winner, WinnerB):
+		return preference{WinnerB, reply.Rationale}, nil
+	case strings.EqualFold(winner, Tie):
+		return preference{Tie, reply.Rationale}, nil
+	}
+	reason := fmt.Sprintf("the winner %q is not A, B or tie", reply.Winner)
+	return preference{}, &prompting.ParseError{Format: "preference", Completion: completion, Reason: reason}
+}
+
+func (preferenceParser) Instructions() string {
+	return `Reply with a JSON object only, such as {"rationale": "...", "winner": "A"}, where winner is "A", "B" or "tie".`
+}
@@ -0,0 +1,178 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock/bedrocktest"
+	"aws-bedrock-prompt-engineering/internal/prompting"
+)
+
+func TestGradeParser(t *testing.T) {
+	tests := []struct {
+		name       string
+		completion string
+		want       int
+		wantErr    bool
+	}{
+		{"json", `{"rationale": "Vivid, ends on tension.", "score": 4}`, 4, false},
+		{"json in prose", "Here is my grade:\n```json\n{\"score\": 2, \"rationale\": \"Restates the prompt.\"}\n```", 2, false},
+		{"score line", "The opening is vivid.\nScore: 5", 5, false},
+		{"out of range", `{"score": 9, "rationale": "great"}`, 0, true},
+		{"no score", "I liked it.", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grade, err := gradeParser{scale: 5}.Parse(tt.completion)
+			if tt.wantErr {
+				var parseErr *prompting.ParseError
+				if !errors.As(err, &parseErr) {
+					t.Fatalf("Parse() error = %v, want a *ParseError", err)
+				}
+				return
+			}
+			if err != nil || grade.Score != tt.want {
+				t.Errorf("Parse() = %+v, %v, want score %d", grade, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestJudgePairwiseSwapsPositions(t *testing.T) {
+	t.Setenv("MODEL_ID", testModelID)
+
+	t.Run("consistent", func(t *testing.T) {
+		fake := bedrocktest.NewFake().
+			Respond(`(?s)<output_a>\nstrong`, `{"rationale": "A is vivid.", "winner": "A"}`).
+			Respond(`(?s)<output_b>\nstrong`, `{"rationale": "B is vivid.", "winner": "B"}`)
+
+		preference, err := NewJudge(fake, "").Pairwise(context.Background(), "task", "rubric", "weak opening", "strong opening")
+		if err != nil {
+			t.Fatalf("Pairwise() error = %v", err)
+		}
+		if preference.Winner != WinnerB || !preference.Consistent || len(preference.Rationales) != 2 {
+			t.Errorf("Pairwise() = %+v, want a consistent win for B", preference)
+		}
+		if got := len(fake.Calls()); got != 2 {
+			t.Errorf("made %d calls, want one per ordering", got)
+		}
+	})
+
+	t.Run("position bias", func(t *testing.T) {
+		fake := bedrocktest.NewFake().Default(`{"rationale": "The first one.", "winner": "A"}`)
+
+		preference, err := NewJudge(fake, "").Pairwise(context.Background(), "task", "rubric", "one", "two")
+		if err != nil {
+			t.Fatalf("Pairwise() error = %v", err)
+		}
+		if preference.Winner != Tie || preference.Consistent {
+			t.Errorf("Pairwise() = %+v, want an inconsistent tie", preference)
+		}
+	})
+}
+
+var judgedDataset = []byte(`
+name: stories
+prompt: creative-writing
+metrics: [judge]
+rubric: Builds on the premise and ends on tension.
+cases:
+  - id: vivid
+    inputs: {premise: A lighthouse keeper finds a door}
+  - id: flat
+    inputs: {premise: A rainy Tuesday}
+    rubric: Mentions the rain.
+`)
+
+func TestRunnerJudgeMetric(t *testing.T) {
+	t.Setenv("MODEL_ID", testModelID)
+
+	ds, err := ParseDataset("stories.yaml", judgedDataset)
+	if err != nil {
+		t.Fatalf("ParseDataset() error = %v", err)
+	}
+	// Grader rules come first: the grading prompt quotes the candidate's prompt
+	fake := bedrocktest.NewFake().
+		Respond(`(?s)^You are grading.*<output>\nThe door hummed`, `{"rationale": "Vivid and tense.", "score": 5}`).
+		Respond(`(?s)^You are grading.*Mentions the rain`, "not sure").
+		Respond(`A lighthouse keeper finds a door`, "The door hummed in the dark.").
+		Respond(`A rainy Tuesday`, "It was Tuesday.")
+
+	runner := NewRunner(fake, prompting.DefaultEvalLibrary())
+	runner.Judge = NewJudge(fake, "anthropic.claude-3-5-sonnet-20240620-v1:0")
+	report, err := runner.Run(context.Background(), ds, nil, "")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	vivid, flat := report.Cases[0].Scores[0], report.Cases[1].Scores[0]
+	if vivid.Value != 1 || !strings.Contains(vivid.Detail, "5/5: Vivid and tense.") {
+		t.Errorf("vivid score = %+v, want 5/5", vivid)
+	}
+	// A grader that never answers in the format is re-asked, then the case scores 0
+	if flat.Value != 0 || !strings.Contains(flat.Detail, "the reply has no score") {
+		t.Errorf("flat score = %+v, want a grading failure", flat)
+	}
+
+	var graderCalls int
+	for _, call := range fake.Calls() {
+		if call.Params.ModelID == "anthropic.claude-3-5-sonnet-20240620-v1:0" {
+			graderCalls++
+			if call.Params.Temperature != 0 {
+				t.Errorf("grader temperature = %v, want 0", call.Params.Temperature)
+			}
+		}
+	}
+	if usage := runner.Judge.Usage(); graderCalls != 4 || usage.Calls != 4 {
+		t.Errorf("grader calls = %d, judge usage = %d, want 1 + 3 with re-asks", graderCalls, usage.Calls)
+	}
+	if report.Usage.Calls != 2 {
+		t.Errorf("report usage = %d calls, want only the candidates' 2", report.Usage.Calls)
+	}
+}
+
+func TestRunnerJudgePairwise(t *testing.T) {
+	t.Setenv("MODEL_ID", testModelID)
+
+	ds, err := ParseDataset("stories.yaml", judgedDataset)
+	if err != nil {
+		t.Fatalf("ParseDataset() error = %v", err)
+	}
+	fake := bedrocktest.NewFake().
+		// The challenger's output wins whichever side it is shown on
+		Respond(`(?s)^You are comparing.*<output_a>\nVivid`, `{"rationale": "A is vivid.", "winner": "A"}`).
+		Respond(`(?s)^You are comparing.*<output_b>\nVivid`, `{"rationale": "B is vivid.", "winner": "B"}`).
+		// The models run one after the other: the baseline's opening is flat, the challenger's vivid
+		RespondSequence(`A lighthouse keeper`, "Flat opening.", "Vivid opening.").
+		Fail(`A rainy Tuesday`, errors.New("ThrottlingException"))
+
+	runner := NewRunner(fake, prompting.DefaultEvalLibrary())
+	matrix, err := runner.Compare(context.Background(), ds, []prompting.Technique{prompting.FewShot}, []string{testModelID, "anthropic.claude-3-5-sonnet-20240620-v1:0"})
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	if err := runner.JudgePairwise(context.Background(), ds, matrix); err != nil {
+		t.Fatalf("JudgePairwise() error = %v", err)
+	}
+
+	if matrix.Pairwise[0] != nil {
+		t.Errorf("the baseline has a pairwise comparison: %+v", matrix.Pairwise[0])
+	}
+	p := matrix.Pairwise[1]
+	if p.Wins != 1 || p.Errors != 1 || p.Inconsistent != 0 || p.WinRate() != 1 {
+		t.Errorf("pairwise = %+v, want one consistent win and one failed case", p)
+	}
+
+	var markdown bytes.Buffer
+	if err := WriteMarkdown(&markdown, []*Matrix{matrix}); err != nil {
+		t.Fatalf("WriteMarkdown() error = %v", err)
+	}
+	for _, want := range []string{" Preferred |", " baseline |", " 100% (1-0-0) |", "0 verdict(s) flipped"} {
+		if !strings.Contains(markdown.String(), want) {
+			t.Errorf("Markdown is missing %q:\n%s", want, markdown.String())
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"aws-bedrock-prompt-engineering/internal/prompting"
+)
+
+// Built-in metric names
+const (
+	MetricExactMatch    = "exact_match"
+	MetricLabelAccuracy = "label_accuracy"
+	MetricNumericMatch  = "numeric_match"
+	MetricRegex         = "regex"
+	MetricEntityF1      = "entity_f1"
+	MetricJudge         = "judge"
+)
+
+// Output is what running one case produced
+type Output struct {
+	Prompt     string // the rendered prompt, as first sent to the model
+	Completion string
+	Parsed     any // the completion parsed per the prompt's output block, if it has one
+}
+
+// Score is one metric's verdict on one case, from 0 (wrong) to 1 (right)
+type Score struct {
+	Metric  string  `json:"metric"`
+	Value   float64 `json:"value"`
+	Detail  string  `json:"detail,omitempty"`  // what was compared, e.g. `got "positive", want "negative"`
+	Skipped bool    `json:"skipped,omitempty"` // the case has no expectation for this metric
+}
+
+// Metric scores a case's output against the case's expectations
+type Metric interface {
+	Name() string
+	Score(ds *Dataset, c Case, out Output) Score
+}
+
+// MetricFunc adapts a function to the Metric interface
+type MetricFunc struct {
+	name  string
+	score func(ds *Dataset, c Case, out Output) Score
+}
+
+// NewMetric creates a metric from a scoring function. The returned score's Metric field is filled in.
+func NewMetric(name string, score func(ds *Dataset, c Case, out Output) Score) *MetricFunc {
+	return &MetricFunc{name: name, score: score}
+}
+
+func (m *MetricFunc) Name() string {
+	return m.name
+}
+
+func (m *MetricFunc) Score(ds *Dataset, c Case, out Output) Score {
+	score := m.score(ds, c, out)
+	score.Metric = m.name
+	return score
+}
+
+// registry holds the registered metrics in registration order, built-ins first
+var registry = newMetricRegistry(
+	NewMetric(MetricExactMatch, exactMatch),
+	NewMetric(MetricLabelAccuracy, labelAccuracy),
+	NewMetric(MetricNumericMatch, numericMatch),
+	NewMetric(MetricRegex, regexMatch),
+	NewMetric(MetricEntityF1, entityF1),
+	NewMetric(MetricJudge, unjudged),
+)
+
+type metricRegistry struct {
+	mu      sync.RWMutex
+	metrics []Metric
+	byName  map[string]Metric
+}
+
+func newMetricRegistry(builtins ...Metric) *metricRegistry {
+	r := &metricRegistry{byName: map[string]Metric{}}
+	for _, m := range builtins {
+		r.register(m)
+	}
+	return r
+}
+
+func (r *metricRegistry) register(m Metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := m.Name()
+	if name == "" {
+		panic("eval: RegisterMetric called with a metric without a name")
+	}
+	if _, ok := r.byName[name]; ok {
+		panic(fmt.Sprintf("eval: metric %q registered twice", name))
+	}
+
+	r.metrics = append(r.metrics, m)
+	r.byName[name] = m
+}
+
+// RegisterMetric makes a metric available to datasets under its name. It panics if the name
+// is empty or already taken, so it is meant to be called from init functions.
+func RegisterMetric(m Metric) {
+	registry.register(m)
+}
+
+// MetricFor returns the metric registered under name
+func MetricFor(name string) (Metric, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	m, ok := registry.byName[name]
+	return m, ok
+}
+
+// MetricNames returns the registered metric names in registration order
+func MetricNames() []string {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	names := make([]string, len(registry.metrics))
+	for i, m := range registry.metrics {
+		names[i] = m.Name()
+	}
+	return names
+}
+
+// unjudged stands in for the judge metric outside a Runner, which grades with its Judge
+func unjudged(ds *Dataset, c Case, out Output) Score {
+	return skipped("no judge configured")
+}
+
+func skipped(reason string) Score {
+	return Score{Skipped: true, Detail: reason}
+}
+
+func verdict(ok bool, detail string) Score {
+	if ok {
+		return Score{Value: 1, Detail: detail}
+	}
+	return Score{Value: 0, Detail: detail}
+}
+
+// exactMatch compares the whole completion with the expected answer, ignoring case,
+// surrounding whitespace and trailing punctuation
+func exactMatch(ds *Dataset, c Case, out Output) Score {
+	if c.Expected == "" {
+		return skipped("no expected answer")
+	}
+
+	got, want := normalizeText(out.Completion), normalizeText(c.Expected)
+	return verdict(got == want, fmt.Sprintf("got %q, want %q", got, want))
+}
+
+// labelAccuracy compares the predicted label with the expected one. The prediction is the
+// parsed output of a label prompt, or the first of the dataset's labels the completion's final
+// answer names, so reasoning that weighs other labels first is not held against it.
+func labelAccuracy(ds *Dataset, c Case, out Output) Score {
+	if c.Expected == "" {
+		return skipped("no expected label")
+	}
+
+	label, ok := out.Parsed.(string)
+	if !ok {
+		parser := prompting.NewLabelParser(ds.labels()...)
+		var err error
+		if line, found := prompting.FinalAnswer(out.Completion); found {
+			label, err = parser.Parse(line)
+			ok = err == nil
+		}
+		if !ok {
+			if label, err = parser.Parse(out.Completion); err != nil {
+				return Score{Detail: fmt.Sprintf("no label found, want %q", c.Expected)}
+			}
+		}
+	}
+
+	return verdict(strings.EqualFold(label, strings.TrimSpace(c.Expected)), fmt.Sprintf("got %q, want %q", label, c.Expected))
+}
+
+// numericMatch compares the number in the completion's final answer with the expected number.
+// Like self-consistency voting, a dollar amount is looked for when the expected answer is one.
+func numericMatch(ds *Dataset, c Case, out Output) Score {
+	want, ok := parseNumber(c.Expected)
+	if !ok {
+		return skipped("no expected number")
+	}
+
+	extract := prompting.NumberAnswer
+	if strings.Contains(c.Expected, "$") {
+		extract = prompting.DollarAnswer
+	}
+
+	// Prefer the number in the conclusion over one mentioned earlier in the reasoning
+	answer, ok := "", false
+	if line, found := prompting.FinalAnswer(out.Completion); found {
+		answer, ok = extract(line)
+	}
+	if !ok {
+		answer, ok = extract(out.Completion)
+	}
+	got, parsed := parseNumber(answer)
+	if !ok || !parsed {
+		return Score{Detail: fmt.Sprintf("no number found, want %s", c.Expected)}
+	}
+
+	return verdict(math.Abs(got-want) <= 1e-9*math.Max(1, math.Abs(want)), fmt.Sprintf("got %s, want %s", answer, c.Expected))
+}
+
+// regexMatch checks the completion against the case's pattern
+func regexMatch(ds *Dataset, c Case, out Output) Score {
+	if c.pattern == nil {
+		return skipped("no pattern")
+	}
+	return verdict(c.pattern.MatchString(out.Completion), fmt.Sprintf("pattern %s", c.Pattern))
+}
+
+// entityF1 is the F1 score of the predicted entities against the expected ones. Entities match
+// when their types and texts are equal, ignoring case and surrounding punctuation.
+func entityF1(ds *Dataset, c Case, out Output) Score {
+	expected := c.Entities
+	if len(expected) == 0 {
+		if c.Expected == "" {
+			return skipped("no expected entities")
+		}
+		var err error
+		if expected, err = prompting.NewEntityParser().Parse(c.Expected); err != nil {
+			return skipped("expected entities do not parse")
+		}
+	}
+
+	predicted, ok := out.Parsed.([]prompting.Entity)
+	if !ok {
+		predicted, _ = prompting.NewEntityParser().Parse(out.Completion)
+	}
+
+	want := map[string]bool{}
+	for _, entity := range expected {
+		want[entityKey(entity)] = true
+	}
+	got := map[string]bool{}
+	for _, entity := range predicted {
+		got[entityKey(entity)] = true
+	}
+
+	correct := 0
+	for key := range got {
+		if want[key] {
+			correct++
+		}
+	}
+
+	if len(got) == 0 && len(want) == 0 {
+		return Score{Value: 1, Detail: "no entities expected or found"}
+	}
+	precision, recall := ratio(correct, len(got)), ratio(correct, len(want))
+	f1 := 0.0
+	if precision+recall > 0 {
+		f1 = 2 * precision * recall / (precision + recall)
+	}
+	return Score{Value: f1, Detail: fmt.Sprintf("precision %.2f, recall %.2f", precision, recall)}
+}
+
+func entityKey(entity prompting.Entity) string {
+	return strings.ToUpper(strings.TrimSpace(entity.Type)) + "\x00" + normalizeText(entity.Text)
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+// normalizeText lower-cases text, collapses whitespace and drops surrounding quotes and trailing punctuation
+func normalizeText(text string) string {
+	text = strings.Join(strings.Fields(strings.ToLower(text)), " ")
+	return strings.Trim(strings.TrimRight(text, ".!?;:,"), `"'`)
+}
+
+// parseNumber reads a number such as "42", "$1,200.50" or "-3.5"
+func parseNumber(text string) (float64, bool) {
+	text = strings.NewReplacer("$", "", ",", "").Replace(strings.TrimSpace(text))
+	value, err := strconv.ParseFloat(text, 64)
+	return value, err == nil
+}
@@ -0,0 +1,74 @@
+package eval
+
+import (
+	"math"
+	"testing"
+
+	"aws-bedrock-prompt-engineering/internal/prompting"
+)
+
+func TestMetrics(t *testing.T) {
+	ds := &Dataset{Labels: []string{"positive", "negative", "neutral"}}
+	entities := []prompting.Entity{{Type: "PERSON", Text: "Steve Jobs"}, {Type: "LOCATION", Text: "Cupertino"}}
+
+	tests := []struct {
+		name   string
+		metric string
+		c      Case
+		out    Output
+		want   float64
+		skip   bool
+	}{
+		{"exact match ignores case and punctuation", MetricExactMatch, Case{Expected: "Tokyo"}, Output{Completion: " tokyo.\n"}, 1, false},
+		{"exact match", MetricExactMatch, Case{Expected: "Tokyo"}, Output{Completion: "Tokyo, Japan"}, 0, false},
+		{"exact match without expectation", MetricExactMatch, Case{}, Output{Completion: "Tokyo"}, 0, true},
+		{"label from parsed output", MetricLabelAccuracy, Case{Expected: "negative"}, Output{Completion: "Negative!", Parsed: "negative"}, 1, false},
+		{"label from completion", MetricLabelAccuracy, Case{Expected: "neutral"}, Output{Completion: "Sentiment: Neutral."}, 1, false},
+		{"wrong label", MetricLabelAccuracy, Case{Expected: "neutral"}, Output{Completion: "positive"}, 0, false},
+		{"dollar answer in conclusion", MetricNumericMatch, Case{Expected: "$42"}, Output{Completion: "Pizza costs $36.\nTherefore, Tom has $42.00 left."}, 1, false},
+		{"number answer", MetricNumericMatch, Case{Expected: "1,200"}, Output{Completion: "Step 1: 600 * 2\nAnswer: 1200"}, 1, false},
+		{"wrong number", MetricNumericMatch, Case{Expected: "11"}, Output{Completion: "Answer: 12 weeks"}, 0, false},
+		{"regex", MetricRegex, Case{Pattern: `(?i)\btokyo\b`}, Output{Completion: "The capital is Tokyo."}, 1, false},
+		{"regex without pattern", MetricRegex, Case{}, Output{Completion: "Tokyo"}, 0, true},
+		{"entities from parsed output", MetricEntityF1, Case{Entities: entities}, Output{Parsed: entities}, 1, false},
+		{"entities from completion", MetricEntityF1, Case{Entities: entities}, Output{Completion: "- PERSON: steve jobs\n- ORGANIZATION: Apple"}, 0.5, false},
+		{"entities from expected text", MetricEntityF1, Case{Expected: "- PERSON: Steve Jobs"}, Output{Completion: "- PERSON: Steve Jobs"}, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metric, _ := MetricFor(tt.metric)
+			score := metric.Score(ds, validCase(t, tt.c), tt.out)
+			if score.Metric != tt.metric || score.Skipped != tt.skip || math.Abs(score.Value-tt.want) > 1e-9 {
+				t.Errorf("Score() = %+v, want %v (skipped %t)", score, tt.want, tt.skip)
+			}
+		})
+	}
+}
+
+// validCase validates a one-case dataset and returns the case with its pattern compiled
+func validCase(t *testing.T, c Case) Case {
+	t.Helper()
+	ds := &Dataset{Name: "t", Prompt: "p", Metrics: []string{MetricRegex}, Cases: []Case{c}}
+	if err := ds.validate(); err != nil {
+		t.Fatalf("validate() error = %v", err)
+	}
+	return ds.Cases[0]
+}
+
+func TestRegisterMetric(t *testing.T) {
+	length := NewMetric("short_answer", func(ds *Dataset, c Case, out Output) Score {
+		return verdict(len(out.Completion) <= 20, "")
+	})
+	RegisterMetric(length)
+
+	if _, err := ParseDataset("short.yaml", []byte("{prompt: p, metrics: [short_answer], cases: [{expected: x}]}")); err != nil {
+		t.Fatalf("ParseDataset() with a registered metric error = %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a metric name twice should panic")
+		}
+	}()
+	RegisterMetric(length)
+}
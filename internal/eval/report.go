@@ -0,0 +1,159 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+)
+
+// CaseResult is the outcome of one case
+type CaseResult struct {
+	ID         string              `json:"id"`
+	Expected   string              `json:"expected,omitempty"`
+	Prompt     string              `json:"prompt,omitempty"`
+	Completion string              `json:"completion,omitempty"`
+	Parsed     any                 `json:"parsed,omitempty"`
+	Scores     []Score             `json:"scores"`
+	Error      string              `json:"error,omitempty"`
+	Latency    time.Duration       `json:"latency_ns"`
+	Usage      bedrock.UsageTotals `json:"usage"`
+}
+
+// MetricSummary aggregates one metric over the cases it scored
+type MetricSummary struct {
+	Metric  string  `json:"metric"`
+	Mean    float64 `json:"mean"`    // mean score, 0 to 1
+	Perfect int     `json:"perfect"` // cases scoring 1
+	Scored  int     `json:"scored"`  // cases the metric applied to
+}
+
+// Report is the per-case and aggregate outcome of running a dataset
+type Report struct {
+	Dataset   string              `json:"dataset"`
+	Prompt    string              `json:"prompt"`
+	Technique string              `json:"technique"`
+	ModelID   string              `json:"model_id"`
+	Cases     []CaseResult        `json:"cases"`
+	Metrics   []MetricSummary     `json:"metrics"`
+	Errors    int                 `json:"errors"`          // cases that failed to run
+	Latency   time.Duration       `json:"mean_latency_ns"` // mean latency per case
+	Usage     bedrock.UsageTotals `json:"usage"`           // totals over every case
+}
+
+func (r *Report) summarize(metrics []Metric) {
+	var latency time.Duration
+	for _, c := range r.Cases {
+		if c.Error != "" {
+			r.Errors++
+		}
+		latency += c.Latency
+		r.Usage.Calls += c.Usage.Calls
+		r.Usage.InputTokens += c.Usage.InputTokens
+		r.Usage.OutputTokens += c.Usage.OutputTokens
+		r.Usage.Cost += c.Usage.Cost
+		r.Usage.Unpriced += c.Usage.Unpriced
+	}
+	if len(r.Cases) > 0 {
+		r.Latency = latency / time.Duration(len(r.Cases))
+	}
+
+	for i, m := range metrics {
+		summary := MetricSummary{Metric: m.Name()}
+		total := 0.0
+		for _, c := range r.Cases {
+			score := c.Scores[i]
+			if score.Skipped {
+				continue
+			}
+			summary.Scored++
+			total += score.Value
+			if score.Value == 1 {
+				summary.Perfect++
+			}
+		}
+		if summary.Scored > 0 {
+			summary.Mean = total / float64(summary.Scored)
+		}
+		r.Metrics = append(r.Metrics, summary)
+	}
+}
+
+// Metric returns the summary of the named metric
+func (r *Report) Metric(name string) (MetricSummary, bool) {
+	for _, summary := range r.Metrics {
+		if summary.Metric == name {
+			return summary, true
+		}
+	}
+	return MetricSummary{}, false
+}
+
+// WriteText writes a table of the cases' scores followed by the aggregate scores and usage
+func (r *Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "📏 %s: %s with %s on %s\n", r.Dataset, r.Prompt, r.Technique, r.ModelID)
+	fmt.Fprintln(&b, strings.Repeat("-", 80))
+	for _, c := range r.Cases {
+		status := "✅"
+		switch {
+		case c.Error != "":
+			status = "❌"
+		case !c.passed():
+			status = "⚠️ "
+		}
+		fmt.Fprintf(&b, "%s %-28s", status, c.ID)
+		for _, score := range c.Scores {
+			if score.Skipped {
+				fmt.Fprintf(&b, " %s=-", score.Metric)
+			} else {
+				fmt.Fprintf(&b, " %s=%.2f", score.Metric, score.Value)
+			}
+		}
+		fmt.Fprintf(&b, " (%s)\n", c.Latency.Round(time.Millisecond))
+
+		if c.Error != "" {
+			fmt.Fprintf(&b, "   error: %s\n", c.Error)
+			continue
+		}
+		for _, score := range c.Scores {
+			if !score.Skipped && score.Value < 1 && score.Detail != "" {
+				fmt.Fprintf(&b, "   %s: %s\n", score.Metric, score.Detail)
+			}
+		}
+	}
+
+	fmt.Fprintln(&b, strings.Repeat("-", 80))
+	for _, summary := range r.Metrics {
+		fmt.Fprintf(&b, "%-16s %6.1f%% (%d/%d perfect)\n", summary.Metric, summary.Mean*100, summary.Perfect, summary.Scored)
+	}
+	fmt.Fprintf(&b, "%d cases, %d errors, %s mean latency, %d calls, %d input / %d output tokens, $%.4f\n",
+		len(r.Cases), r.Errors, r.Latency.Round(time.Millisecond), r.Usage.Calls, r.Usage.InputTokens, r.Usage.OutputTokens, r.Usage.Cost)
+	if r.Usage.Unpriced > 0 {
+		fmt.Fprintf(&b, "⚠️  %d call(s) used a model without a price and are not included in the cost\n", r.Usage.Unpriced)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON writes reports as an indented JSON array
+func WriteJSON(w io.Writer, reports []*Report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(reports)
+}
+
+// passed reports whether every metric that applied scored 1
+func (c CaseResult) passed() bool {
+	for _, score := range c.Scores {
+		if !score.Skipped && score.Value < 1 {
+			return false
+		}
+	}
+	return true
+}
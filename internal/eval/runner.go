@@ -0,0 +1,151 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+	"aws-bedrock-prompt-engineering/internal/prompting"
+)
+
+// Runner runs datasets through prompting techniques and scores the results
+type Runner struct {
+	client  bedrock.Invoker
+	library *prompting.Library
+
+	Concurrency int                // cases run at the same time
+	Prices      bedrock.PriceTable // prices the reported token usage
+	Judge       *Judge             // grades the judge metric and pairwise comparisons
+}
+
+// NewRunner creates a runner that renders dataset cases with library prompts and sends them to client
+func NewRunner(client bedrock.Invoker, library *prompting.Library) *Runner {
+	return &Runner{
+		client:      client,
+		library:     library,
+		Concurrency: 4,
+		Prices:      bedrock.DefaultPrices,
+		Judge:       NewJudge(client, ""),
+	}
+}
+
+// Run executes every case of ds with technique, or the dataset's technique when nil, and scores
+// the outputs with the dataset's metrics. A technique the dataset has a variant for runs the
+// variant's prompt. modelID replaces the technique's default model and the prompt's model_id when set.
+// Every case's inputs are validated before anything is sent; a case that fails to run is reported
+// in its result and scores 0 on every metric.
+func (r *Runner) Run(ctx context.Context, ds *Dataset, technique prompting.Technique, modelID string) (*Report, error) {
+	prompt := ds.Prompt
+	if technique != nil {
+		prompt = ds.PromptFor(technique.Info().Name)
+	}
+	spec, ok := r.library.Get(prompt)
+	if !ok {
+		return nil, fmt.Errorf("dataset %q: prompt %q is not in the prompt library", ds.Name, prompt)
+	}
+	if technique == nil {
+		name := ds.Technique
+		if name == "" {
+			name = spec.Technique
+		}
+		technique = prompting.TechniqueFor(name)
+	}
+
+	params := technique.DefaultParams()
+	if modelID != "" {
+		// Techniques apply the prompt's own params last, so the requested model replaces its
+		// model_id too; otherwise results would be reported for a model that was never called
+		params.ModelID = modelID
+		withModel := *spec
+		withModel.Params.ModelID = &modelID
+		spec = &withModel
+	}
+
+	var metrics []Metric
+	for _, name := range ds.Metrics {
+		m, ok := MetricFor(name)
+		if !ok {
+			return nil, fmt.Errorf("dataset %q: unknown metric %q", ds.Name, name)
+		}
+		if name == MetricJudge && r.Judge != nil {
+			m = r.Judge.metric(ctx)
+		}
+		metrics = append(metrics, m)
+	}
+
+	for _, c := range ds.Cases {
+		if _, err := spec.Render(r.values(spec, c)); err != nil {
+			return nil, fmt.Errorf("dataset %q: case %q: %w", ds.Name, c.ID, err)
+		}
+	}
+
+	report := &Report{
+		Dataset:   ds.Name,
+		Prompt:    spec.Name,
+		Technique: technique.Info().Name,
+		ModelID:   spec.Params.Apply(params).ModelID,
+		Cases:     make([]CaseResult, len(ds.Cases)),
+	}
+
+	concurrency := max(r.Concurrency, 1)
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, c := range ds.Cases {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			report.Cases[i] = r.runCase(ctx, ds, spec, technique, params, c, metrics)
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	report.summarize(metrics)
+	return report, nil
+}
+
+// values are the case's inputs on top of the prompt's sample inputs, so datasets only
+// list the variables they vary
+func (r *Runner) values(spec *prompting.PromptSpec, c Case) map[string]string {
+	values := maps.Clone(spec.Inputs)
+	if values == nil {
+		values = map[string]string{}
+	}
+	maps.Copy(values, c.Inputs)
+	return values
+}
+
+func (r *Runner) runCase(ctx context.Context, ds *Dataset, spec *prompting.PromptSpec, technique prompting.Technique, params bedrock.ModelParams, c Case, metrics []Metric) CaseResult {
+	result := CaseResult{ID: c.ID, Expected: c.Expected}
+
+	// Multi-step techniques make several calls per case; the ledger counts them all
+	ledger := bedrock.NewLedger(r.Prices)
+	start := time.Now()
+	executed, err := technique.Execute(ctx, ledger.Meter(r.client, c.ID), spec, params, r.values(spec, c))
+	result.Latency = time.Since(start)
+	result.Usage = ledger.Session()
+
+	if err != nil {
+		result.Error = err.Error()
+		for _, m := range metrics {
+			result.Scores = append(result.Scores, Score{Metric: m.Name(), Detail: "case failed"})
+		}
+		return result
+	}
+
+	out := Output{Prompt: executed.Prompt, Completion: executed.Response.Completion, Parsed: executed.Output}
+	result.Prompt, result.Completion, result.Parsed = out.Prompt, out.Completion, out.Parsed
+	for _, m := range metrics {
+		result.Scores = append(result.Scores, m.Score(ds, c, out))
+	}
+	return result
+}
@@ -0,0 +1,208 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock/bedrocktest"
+	"aws-bedrock-prompt-engineering/internal/prompting"
+	"aws-bedrock-prompt-engineering/prompts"
+)
+
+const testModelID = "anthropic.claude-3-haiku-20240307-v1:0"
+
+var sentimentDataset = []byte(`
+prompt: sentiment-analysis
+metrics: [label_accuracy, exact_match]
+cases:
+  - id: angry
+    inputs: {text: It broke after a day.}
+    expected: negative
+  - id: happy
+    inputs: {text: Works perfectly.}
+    expected: positive
+  - id: flaky
+    inputs: {text: The app keeps crashing.}
+    expected: negative
+  - id: bland
+    inputs: {text: It is a phone.}
+    expected: neutral
+`)
+
+func TestRunnerScoresCases(t *testing.T) {
+	t.Setenv("MODEL_ID", testModelID)
+
+	ds, err := ParseDataset("sentiment.yaml", sentimentDataset)
+	if err != nil {
+		t.Fatalf("ParseDataset() error = %v", err)
+	}
+	fake := bedrocktest.NewFake().
+		Respond(`broke after a day`, "Negative.").
+		Respond(`Works perfectly`, "positive").
+		Fail(`keeps crashing`, errors.New("ThrottlingException")).
+		Respond(`It is a phone`, "I think it is positive")
+
+	report, err := NewRunner(fake, prompting.DefaultEvalLibrary()).Run(context.Background(), ds, nil, "")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if report.Dataset != "sentiment" || report.Technique != prompting.TechniqueFewShot || report.ModelID != testModelID {
+		t.Errorf("report header = %s / %s / %s", report.Dataset, report.Technique, report.ModelID)
+	}
+	// Results keep dataset order even though cases run concurrently
+	var ids []string
+	for _, c := range report.Cases {
+		ids = append(ids, c.ID)
+	}
+	if strings.Join(ids, ",") != "angry,happy,flaky,bland" {
+		t.Errorf("case order = %v", ids)
+	}
+
+	accuracy, _ := report.Metric(MetricLabelAccuracy)
+	if accuracy.Mean != 0.5 || accuracy.Perfect != 2 || accuracy.Scored != 4 {
+		t.Errorf("label_accuracy = %+v, want 2 of 4", accuracy)
+	}
+	exact, _ := report.Metric(MetricExactMatch)
+	if exact.Perfect != 2 {
+		t.Errorf("exact_match = %+v, want 2 perfect", exact)
+	}
+	if report.Errors != 1 || report.Cases[2].Error == "" {
+		t.Errorf("Errors = %d, flaky error = %q", report.Errors, report.Cases[2].Error)
+	}
+	if report.Usage.Calls != 3 {
+		t.Errorf("Usage.Calls = %d, want 3 successful calls", report.Usage.Calls)
+	}
+
+	var text bytes.Buffer
+	if err := report.WriteText(&text); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	for _, want := range []string{"label_accuracy     50.0% (2/4 perfect)", `got "positive", want "neutral"`, "ThrottlingException"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text report is missing %q:\n%s", want, text.String())
+		}
+	}
+}
+
+func TestRunnerValidatesBeforeSending(t *testing.T) {
+	ds, err := ParseDataset("bad.yaml", []byte(`{prompt: sentiment-analysis, metrics: [exact_match], cases: [{inputs: {txt: typo}, expected: x}]}`))
+	if err != nil {
+		t.Fatalf("ParseDataset() error = %v", err)
+	}
+	fake := bedrocktest.NewFake().Default("x")
+
+	if _, err := NewRunner(fake, prompting.DefaultEvalLibrary()).Run(context.Background(), ds, nil, ""); err == nil {
+		t.Fatal("Run() with an undeclared input should fail")
+	}
+	if len(fake.Calls()) != 0 {
+		t.Errorf("made %d calls before validation failed", len(fake.Calls()))
+	}
+}
+
+func TestRunnerModelOverridesPrompt(t *testing.T) {
+	library, err := prompting.LoadLibrary(fstest.MapFS{"a.yaml": {Data: []byte(`
+- name: pinned
+  technique: zero-shot
+  template: Say yes.
+  params: {model_id: meta.llama3-8b-instruct-v1:0}
+`)}})
+	if err != nil {
+		t.Fatalf("LoadLibrary() error = %v", err)
+	}
+	ds, err := ParseDataset("pinned.yaml", []byte(`{prompt: pinned, metrics: [exact_match], cases: [{id: yes, expected: "yes"}]}`))
+	if err != nil {
+		t.Fatalf("ParseDataset() error = %v", err)
+	}
+
+	for _, modelID := range []string{testModelID, ""} {
+		want := modelID
+		if want == "" {
+			want = "meta.llama3-8b-instruct-v1:0"
+		}
+
+		fake := bedrocktest.NewFake().Default("yes")
+		report, err := NewRunner(fake, library).Run(context.Background(), ds, nil, modelID)
+		if err != nil {
+			t.Fatalf("Run(%q) error = %v", modelID, err)
+		}
+		if called := fake.Calls()[0].Params.ModelID; called != want || report.ModelID != want {
+			t.Errorf("Run(%q) called %s and reported %s, want %s", modelID, called, report.ModelID, want)
+		}
+	}
+
+	// The library's spec is left as it was
+	if spec, _ := library.Get("pinned"); *spec.Params.ModelID != "meta.llama3-8b-instruct-v1:0" {
+		t.Errorf("spec model_id = %s after Run", *spec.Params.ModelID)
+	}
+}
+
+func TestLoadDatasetsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		want  string
+	}{
+		{"unknown metric", fstest.MapFS{"a.yaml": {Data: []byte("{prompt: p, metrics: [bleu], cases: [{expected: x}]}")}}, `unknown metric "bleu"`},
+		{"duplicate case", fstest.MapFS{"a.yaml": {Data: []byte("{prompt: p, metrics: [regex], cases: [{id: x}, {id: x}]}")}}, `case "x" is defined twice`},
+		{"bad pattern", fstest.MapFS{"a.yaml": {Data: []byte("{prompt: p, metrics: [regex], cases: [{pattern: '('}]}")}}, "invalid pattern"},
+		{"no cases", fstest.MapFS{"a.yaml": {Data: []byte("{prompt: p, metrics: [regex]}")}}, "has no cases"},
+		{"judge without rubric", fstest.MapFS{"a.yaml": {Data: []byte("{prompt: p, metrics: [judge], cases: [{rubric: vivid}, {id: plain}]}")}}, `case "plain": the judge metric needs a rubric`},
+		{"duplicate name", fstest.MapFS{
+			"a.yaml": {Data: []byte("{name: d, prompt: p, metrics: [regex], cases: [{}]}")},
+			"b.json": {Data: []byte(`{"name": "d", "prompt": "p", "metrics": ["regex"], "cases": [{}]}`)},
+		}, `dataset "d" is already defined in a.yaml`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadDatasets(tt.files)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadDatasets() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// The built-in datasets must load and fit their library prompts
+func TestBuiltinDatasets(t *testing.T) {
+	fsys, err := fs.Sub(prompts.Datasets, "datasets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	datasets, err := LoadDatasets(fsys)
+	if err != nil {
+		t.Fatalf("LoadDatasets() error = %v", err)
+	}
+	if len(datasets) == 0 {
+		t.Fatal("no built-in datasets")
+	}
+
+	runner := NewRunner(bedrocktest.NewFake().Default("ok"), prompting.DefaultEvalLibrary())
+	for _, ds := range datasets {
+		prompts := []string{ds.Prompt}
+		for technique, prompt := range ds.Variants {
+			if _, ok := prompting.Lookup(technique); !ok {
+				t.Errorf("dataset %q: variant for unknown technique %q", ds.Name, technique)
+			}
+			prompts = append(prompts, prompt)
+		}
+
+		for _, prompt := range prompts {
+			spec, ok := prompting.DefaultEvalLibrary().Get(prompt)
+			if !ok {
+				t.Errorf("dataset %q: prompt %q is not in the library", ds.Name, prompt)
+				continue
+			}
+			for _, c := range ds.Cases {
+				if _, err := spec.Render(runner.values(spec, c)); err != nil {
+					t.Errorf("dataset %q: prompt %q: case %q: %v", ds.Name, prompt, c.ID, err)
+				}
+			}
+		}
+	}
+}
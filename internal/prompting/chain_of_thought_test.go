@@ -0,0 +1,39 @@
+package prompting
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock/bedrocktest"
+)
+
+func chainOfThoughtCases(fake *bedrocktest.Fake) []exampleCase {
+	c := NewChainOfThoughtPrompt(fake)
+	return []exampleCase{
+		{"ExecuteMathProblemSolving", "Tom is planning a party for 24 people", "failed to execute math problem solving", c.ExecuteMathProblemSolving},
+		{"ExecuteLogicalReasoning", "Can Ms. Johnson tutor international students?", "failed to execute logical reasoning", c.ExecuteLogicalReasoning},
+		{"ExecuteProblemDecomposition", "sustainable office renovation", "failed to execute problem decomposition", c.ExecuteProblemDecomposition},
+		{"ExecuteCodeDebugging", "def find_max_value(data):", "failed to execute code debugging", c.ExecuteCodeDebugging},
+		{"ExecuteDecisionMaking", "invest $50,000 in new equipment", "failed to execute decision making", c.ExecuteDecisionMaking},
+	}
+}
+
+func TestChainOfThoughtExamples(t *testing.T) {
+	t.Setenv("MODEL_ID", testModelID)
+	runExampleCases(t, chainOfThoughtCases, 0.4, 1000)
+}
+
+func TestChainOfThoughtRunAllExamples(t *testing.T) {
+	t.Setenv("MODEL_ID", testModelID)
+
+	fake := bedrocktest.NewFake().
+		Fail(`Tom is planning a party`, errors.New("ModelTimeoutException")).
+		Fail(`Ms. Johnson`, errors.New("ServiceUnavailableException")).
+		Default("scripted reply")
+	NewChainOfThoughtPrompt(fake).RunAllExamples(context.Background())
+
+	if got := len(fake.Calls()); got != 5 {
+		t.Errorf("RunAllExamples() made %d calls, want 5", got)
+	}
+}
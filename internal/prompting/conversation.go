@@ -0,0 +1,105 @@
+package prompting
+
+import (
+	"context"
+	"fmt"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+)
+
+// Conversation keeps the role-tagged history of a multi-turn exchange and sends it
+// through the Converse API, so earlier turns reach the model as real messages
+// instead of a string-concatenated transcript.
+type Conversation struct {
+	client     bedrock.Converser
+	params     bedrock.ModelParams
+	messages   []bedrock.Message
+	tools      *Dispatcher
+	onToolCall func(call bedrock.ToolCall, result bedrock.ToolResult)
+}
+
+// NewConversation starts an empty conversation. params.System, if set, is sent as the system prompt.
+func NewConversation(client bedrock.Converser, params bedrock.ModelParams) *Conversation {
+	return &Conversation{
+		client: client,
+		params: params,
+	}
+}
+
+// WithTools offers the dispatcher's tools to the model. Send then runs the tools the model
+// calls and returns their results to it until it answers. The model must support tool use,
+// e.g. Claude 3 or later.
+func (c *Conversation) WithTools(tools *Dispatcher) *Conversation {
+	c.tools = tools
+	return c
+}
+
+// OnToolCall registers a function called after each tool runs, e.g. to show progress
+func (c *Conversation) OnToolCall(fn func(call bedrock.ToolCall, result bedrock.ToolResult)) *Conversation {
+	c.onToolCall = fn
+	return c
+}
+
+// AddExchange appends a user turn and the assistant reply to it without calling the model.
+// Use it to seed demonstrations, e.g. few-shot examples as prior turns.
+func (c *Conversation) AddExchange(user, assistant string) {
+	c.messages = append(c.messages,
+		bedrock.Message{Role: bedrock.RoleUser, Content: user},
+		bedrock.Message{Role: bedrock.RoleAssistant, Content: assistant},
+	)
+}
+
+// Send appends a user turn, asks the model for a reply and records it in the history. With tools,
+// tool calls and results are recorded too, and the returned response is the model's final answer
+// with the token usage of every call the turn made. If the turn fails, or the model replies with
+// nothing, the history is left unchanged.
+func (c *Conversation) Send(ctx context.Context, text string) (*bedrock.ModelResponse, error) {
+	messages := append(c.messages, bedrock.Message{Role: bedrock.RoleUser, Content: text})
+
+	var specs []bedrock.ToolSpec
+	if c.tools != nil {
+		specs = c.tools.Specs()
+	}
+
+	usage := &bedrock.Usage{}
+	for round := 0; ; round++ {
+		response, err := c.client.Converse(ctx, messages, c.params, specs...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send conversation turn: %w", err)
+		}
+		if response.Usage != nil {
+			usage.InputTokens += response.Usage.InputTokens
+			usage.OutputTokens += response.Usage.OutputTokens
+		}
+
+		messages = append(messages, bedrock.Message{Role: bedrock.RoleAssistant, Content: response.Completion, ToolCalls: response.ToolCalls})
+		if c.tools == nil || response.StopReason != bedrock.StopReasonToolUse || len(response.ToolCalls) == 0 {
+			// Converse rejects an empty assistant turn, so recording one would fail every later turn
+			if response.Completion != "" || len(response.ToolCalls) > 0 {
+				c.messages = messages
+			}
+			response.Usage = usage
+			return response, nil
+		}
+		if round == maxToolRounds {
+			return nil, fmt.Errorf("%w (%d rounds)", ErrToolRounds, maxToolRounds)
+		}
+
+		results := make([]bedrock.ToolResult, len(response.ToolCalls))
+		for i, call := range response.ToolCalls {
+			results[i] = c.tools.Dispatch(ctx, call)
+			if c.onToolCall != nil {
+				c.onToolCall(call, results[i])
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		messages = append(messages, bedrock.Message{Role: bedrock.RoleUser, ToolResults: results})
+	}
+}
+
+// Messages returns the conversation history so far
+func (c *Conversation) Messages() []bedrock.Message {
+	return c.messages
+}
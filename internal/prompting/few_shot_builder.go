@@ -0,0 +1,243 @@
+package prompting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+)
+
+// FewShotBuilder renders a consistent few-shot prompt from a task instruction, labelled
+// input/output examples and a query:
+//
+//	<instruction>
+//
+//	Input: <example input>
+//	Sentiment: <example output>
+//
+//	Input: <query>
+//	Sentiment:
+type FewShotBuilder struct {
+	instruction string
+	examples    []Example
+	inputLabel  string
+	outputLabel string
+	separator   string
+	maxExamples int
+	shuffle     bool
+	seed        uint64
+	balance     bool
+	selector    ExampleSelector
+	selectK     int
+}
+
+// NewFewShotBuilder creates a builder that labels example outputs and the answer with outputLabel,
+// e.g. "Sentiment" or "Category"
+func NewFewShotBuilder(instruction string, examples []Example, outputLabel string) *FewShotBuilder {
+	return &FewShotBuilder{
+		instruction: instruction,
+		examples:    examples,
+		inputLabel:  "Input",
+		outputLabel: outputLabel,
+		separator:   "\n\n",
+	}
+}
+
+// WithInputLabel replaces the default "Input" label of example inputs and the query
+func (b *FewShotBuilder) WithInputLabel(label string) *FewShotBuilder {
+	b.inputLabel = label
+	return b
+}
+
+// WithSeparator sets the text placed between examples, a blank line by default
+func (b *FewShotBuilder) WithSeparator(separator string) *FewShotBuilder {
+	b.separator = separator
+	return b
+}
+
+// WithMaxExamples limits the prompt to n examples; zero keeps them all
+func (b *FewShotBuilder) WithMaxExamples(n int) *FewShotBuilder {
+	b.maxExamples = n
+	return b
+}
+
+// WithShuffle picks and orders examples randomly; the same seed always gives the same prompt
+func (b *FewShotBuilder) WithShuffle(seed uint64) *FewShotBuilder {
+	b.shuffle = true
+	b.seed = seed
+	return b
+}
+
+// WithBalancedLabels spreads the examples evenly across output labels, so no label
+// dominates the demonstrations. Without a maximum every label is cut to the size of the rarest one.
+func (b *FewShotBuilder) WithBalancedLabels() *FewShotBuilder {
+	b.balance = true
+	return b
+}
+
+// WithSelector picks the k examples the selector finds most relevant to each query, in place
+// of the builder's own examples. The other options then apply to those k.
+func (b *FewShotBuilder) WithSelector(selector ExampleSelector, k int) *FewShotBuilder {
+	b.selector = selector
+	b.selectK = k
+	return b
+}
+
+// Examples returns the examples the prompt will contain, in prompt order. Examples picked by
+// a selector depend on the query and are not included.
+func (b *FewShotBuilder) Examples() []Example {
+	return b.arrange(b.examples)
+}
+
+// arrange applies the shuffle, balance and maximum options to pool
+func (b *FewShotBuilder) arrange(pool []Example) []Example {
+	indexes := make([]int, len(pool))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	if b.shuffle {
+		rng := rand.New(rand.NewPCG(b.seed, 0))
+		rng.Shuffle(len(indexes), func(i, j int) { indexes[i], indexes[j] = indexes[j], indexes[i] })
+	}
+
+	var selected []int
+	if b.balance {
+		selected = b.balanced(pool, indexes)
+	} else {
+		selected = indexes
+		if b.maxExamples > 0 && len(selected) > b.maxExamples {
+			selected = selected[:b.maxExamples]
+		}
+	}
+
+	// Without shuffling the examples keep the order they were given in
+	if !b.shuffle {
+		selected = slices.Clone(selected)
+		slices.Sort(selected)
+	}
+
+	examples := make([]Example, len(selected))
+	for i, index := range selected {
+		examples[i] = pool[index]
+	}
+	return examples
+}
+
+// balanced takes examples round-robin across labels, in the order labels first appear
+func (b *FewShotBuilder) balanced(pool []Example, indexes []int) []int {
+	var labels []string
+	groups := map[string][]int{}
+	smallest := len(indexes)
+	for _, index := range indexes {
+		label := strings.TrimSpace(pool[index].Output)
+		if _, ok := groups[label]; !ok {
+			labels = append(labels, label)
+		}
+		groups[label] = append(groups[label], index)
+	}
+	for _, label := range labels {
+		smallest = min(smallest, len(groups[label]))
+	}
+
+	limit := b.maxExamples
+	if limit <= 0 {
+		limit = smallest * len(labels)
+	}
+
+	var selected []int
+	for round := 0; len(selected) < limit; round++ {
+		added := false
+		for _, label := range labels {
+			if round < len(groups[label]) && len(selected) < limit {
+				selected = append(selected, groups[label][round])
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	return selected
+}
+
+// Build renders the prompt for query. The query is escaped with EscapeInput; the instruction
+// and examples come from the caller and are used as given.
+func (b *FewShotBuilder) Build(query string) (string, error) {
+	return b.BuildContext(context.Background(), query)
+}
+
+// BuildContext is Build with a context for the selector, which may call an embedding model
+func (b *FewShotBuilder) BuildContext(ctx context.Context, query string) (string, error) {
+	if strings.TrimSpace(query) == "" {
+		return "", errors.New("few-shot query is empty")
+	}
+	if b.outputLabel == "" {
+		return "", errors.New("few-shot output label is empty")
+	}
+
+	examples := b.examples
+	if b.selector != nil {
+		selected, err := b.selector.Select(ctx, query, b.selectK)
+		if err != nil {
+			return "", fmt.Errorf("failed to select examples: %w", err)
+		}
+		examples = selected
+	}
+
+	var prompt strings.Builder
+	if b.instruction != "" {
+		prompt.WriteString(strings.TrimSpace(b.instruction))
+		prompt.WriteString("\n\n")
+	}
+
+	for i, example := range b.arrange(examples) {
+		if i > 0 {
+			prompt.WriteString(b.separator)
+		}
+		prompt.WriteString(labelled(b.inputLabel, example.Input))
+		prompt.WriteString("\n")
+		prompt.WriteString(labelled(b.outputLabel, example.Output))
+	}
+	if len(examples) > 0 {
+		prompt.WriteString("\n\n")
+	}
+
+	prompt.WriteString(labelled(b.inputLabel, EscapeInput(query)))
+	prompt.WriteString("\n")
+	prompt.WriteString(b.outputLabel + ":")
+
+	return prompt.String(), nil
+}
+
+// labelled writes "Label: value", moving multi-line values onto their own lines
+func labelled(label, value string) string {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "\n") {
+		return label + ":\n" + value
+	}
+	return label + ": " + value
+}
+
+// ExecuteBuilder sends the prompt built for query with the few-shot parameters and prints the response
+func (f *FewShotPrompt) ExecuteBuilder(ctx context.Context, builder *FewShotBuilder, query string) (*bedrock.ModelResponse, error) {
+	prompt, err := builder.BuildContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Println("🎪 Few-Shot Prompting: Example Builder")
+	fmt.Println("Prompt:", prompt)
+	fmt.Println(strings.Repeat("-", 80))
+
+	response, err := f.client.InvokeModel(ctx, prompt, f.params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute few-shot prompt: %w", err)
+	}
+
+	fmt.Printf("Response: %s\n\n", response.Completion)
+	return response, nil
+}
@@ -0,0 +1,67 @@
+package prompting
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock/bedrocktest"
+)
+
+func fewShotCases(fake *bedrocktest.Fake) []exampleCase {
+	f := NewFewShotPrompt(fake)
+	return []exampleCase{
+		{"ExecuteSentimentAnalysis", "The movie was disappointing.", "failed to execute sentiment analysis", f.ExecuteSentimentAnalysis},
+		{"ExecuteEntityExtraction", "Dr. Sarah Johnson from Harvard University", "failed to execute entity extraction", f.ExecuteEntityExtraction},
+		{"ExecuteCodeCompletion", "find the maximum of three numbers", "failed to execute code completion", f.ExecuteCodeCompletion},
+		{"ExecuteEmailClassification", "The verification email never arrived.", "failed to execute email classification", f.ExecuteEmailClassification},
+		{"ExecuteCreativeWriting", "colors have disappeared", "failed to execute creative writing", f.ExecuteCreativeWriting},
+	}
+}
+
+func TestFewShotExamples(t *testing.T) {
+	t.Setenv("MODEL_ID", testModelID)
+	// Temperature is checked separately because creative writing overrides it
+	runExampleCases(t, fewShotCases, 0, 800)
+}
+
+func TestFewShotTemperatures(t *testing.T) {
+	t.Setenv("MODEL_ID", testModelID)
+
+	fake := withScriptedAnswers(bedrocktest.NewFake())
+	f := NewFewShotPrompt(fake)
+
+	if err := f.ExecuteSentimentAnalysis(context.Background()); err != nil {
+		t.Fatalf("ExecuteSentimentAnalysis() error = %v", err)
+	}
+	if err := f.ExecuteCreativeWriting(context.Background()); err != nil {
+		t.Fatalf("ExecuteCreativeWriting() error = %v", err)
+	}
+
+	calls := fake.Calls()
+	if calls[0].Params.Temperature != 0.5 {
+		t.Errorf("sentiment Temperature = %v, want 0.5", calls[0].Params.Temperature)
+	}
+	if calls[1].Params.Temperature != 0.8 {
+		t.Errorf("creative writing Temperature = %v, want 0.8", calls[1].Params.Temperature)
+	}
+
+	// The creative writing override must not leak into later examples
+	if err := f.ExecuteSentimentAnalysis(context.Background()); err != nil {
+		t.Fatalf("ExecuteSentimentAnalysis() error = %v", err)
+	}
+	if got := fake.Calls()[2].Params.Temperature; got != 0.5 {
+		t.Errorf("Temperature after creative writing = %v, want 0.5", got)
+	}
+}
+
+func TestFewShotRunAllExamples(t *testing.T) {
+	t.Setenv("MODEL_ID", testModelID)
+
+	fake := withScriptedAnswers(bedrocktest.NewFake().Fail(`Extract named entities`, errors.New("AccessDeniedException")))
+	NewFewShotPrompt(fake).RunAllExamples(context.Background())
+
+	if got := len(fake.Calls()); got != 5 {
+		t.Errorf("RunAllExamples() made %d calls, want 5", got)
+	}
+}
@@ -0,0 +1,418 @@
+package prompting
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+	"aws-bedrock-prompt-engineering/prompts"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PromptSpec is one entry of the prompt library
+type PromptSpec struct {
+	Name            string            `yaml:"name"`             // unique identifier, e.g. "text-classification"
+	Title           string            `yaml:"title"`            // display name, e.g. "Text Classification"
+	Technique       string            `yaml:"technique"`        // e.g. "zero-shot", "few-shot", "chain-of-thought"
+	Template        string            `yaml:"template"`         // prompt text with {{variable}} and {{examples}} placeholders
+	Variables       []Variable        `yaml:"variables"`        // declarations of the template placeholders
+	Inputs          map[string]string `yaml:"inputs"`           // sample values the demo runs the prompt with
+	Examples        []Example         `yaml:"examples"`         // demonstrations rendered into {{examples}}
+	ExampleTemplate string            `yaml:"example_template"` // how each example is rendered, with {{input}}, {{output}} and {{index}}
+	Params          ParamOverrides    `yaml:"params"`           // per-prompt changes to the technique's ModelParams
+	Expected        string            `yaml:"expected"`         // expected output, when the task has one
+	Answer          string            `yaml:"answer"`           // answer extractor for self-consistency voting, e.g. "dollar"
+	Output          *OutputSpec       `yaml:"output"`           // format the completion is parsed into, e.g. a label from a fixed set
+
+	Source string `yaml:"-"` // library file the spec was loaded from
+
+	template        *Template
+	exampleTemplate *Template
+	parser          OutputParser[any]
+}
+
+// examplesVariable is the reserved placeholder the rendered examples are inserted at
+const examplesVariable = "examples"
+
+// exampleVariables are the placeholders of an example_template. Examples come from the
+// library author, so they are inserted without escaping.
+var exampleVariables = []Variable{
+	{Name: "input", Type: TypeText, Raw: true},
+	{Name: "output", Type: TypeText, Raw: true},
+	{Name: "index", Type: TypeInt, Raw: true},
+}
+
+// Example is an input/output demonstration
+type Example struct {
+	Input  string `yaml:"input"`
+	Output string `yaml:"output"`
+}
+
+// ParamOverrides replaces individual ModelParams fields; unset fields keep the technique default
+type ParamOverrides struct {
+	ModelID       *string  `yaml:"model_id" json:"model_id"`
+	Temperature   *float64 `yaml:"temperature" json:"temperature"`
+	TopP          *float64 `yaml:"top_p" json:"top_p"`
+	TopK          *int     `yaml:"top_k" json:"top_k"`
+	MaxTokens     *int     `yaml:"max_tokens" json:"max_tokens"`
+	System        *string  `yaml:"system" json:"system"`
+	StopSequences []string `yaml:"stop_sequences" json:"stop_sequences"`
+}
+
+// Apply returns params with the overrides applied
+func (o ParamOverrides) Apply(params bedrock.ModelParams) bedrock.ModelParams {
+	if o.ModelID != nil {
+		params.ModelID = *o.ModelID
+	}
+	if o.Temperature != nil {
+		params.Temperature = *o.Temperature
+	}
+	if o.TopP != nil {
+		params.TopP = *o.TopP
+	}
+	if o.TopK != nil {
+		params.TopK = *o.TopK
+	}
+	if o.MaxTokens != nil {
+		params.MaxTokens = *o.MaxTokens
+	}
+	if o.System != nil {
+		params.System = *o.System
+	}
+	if o.StopSequences != nil {
+		params.StopSequences = o.StopSequences
+	}
+
+	return params
+}
+
+// Merge returns o with the fields set in top replacing its own
+func (o ParamOverrides) Merge(top ParamOverrides) ParamOverrides {
+	if top.ModelID != nil {
+		o.ModelID = top.ModelID
+	}
+	if top.Temperature != nil {
+		o.Temperature = top.Temperature
+	}
+	if top.TopP != nil {
+		o.TopP = top.TopP
+	}
+	if top.TopK != nil {
+		o.TopK = top.TopK
+	}
+	if top.MaxTokens != nil {
+		o.MaxTokens = top.MaxTokens
+	}
+	if top.System != nil {
+		o.System = top.System
+	}
+	if top.StopSequences != nil {
+		o.StopSequences = top.StopSequences
+	}
+	return o
+}
+
+// InlineSpec creates a spec for template text that is not in the library. Every placeholder is
+// declared as a required text variable, and values are escaped like any other input.
+func InlineSpec(name, technique, template string) (*PromptSpec, error) {
+	spec := &PromptSpec{Name: name, Technique: technique, Template: template}
+
+	seen := map[string]bool{}
+	for _, match := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			spec.Variables = append(spec.Variables, Variable{Name: match[1], Type: TypeText, Required: true})
+		}
+	}
+
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// Render validates values against the declared variables and fills the template.
+// Pass spec.Inputs to render the prompt the demo uses.
+func (s *PromptSpec) Render(values map[string]string) (string, error) {
+	if s.template == nil {
+		if err := s.compile(); err != nil {
+			return "", err
+		}
+	}
+
+	if _, ok := values[examplesVariable]; ok {
+		return "", &ValidationError{Template: s.Name, Problems: []string{fmt.Sprintf("{{%s}} is filled from the prompt's examples", examplesVariable)}}
+	}
+	if err := s.template.Validate(values); err != nil {
+		return "", err
+	}
+
+	var examples []string
+	for i, example := range s.Examples {
+		all := map[string]string{"input": example.Input, "output": example.Output, "index": strconv.Itoa(i + 1)}
+		exampleValues := map[string]string{}
+		for _, v := range s.exampleTemplate.Variables() {
+			exampleValues[v.Name] = all[v.Name]
+		}
+
+		rendered, err := s.exampleTemplate.Render(exampleValues)
+		if err != nil {
+			return "", err
+		}
+		examples = append(examples, rendered)
+	}
+
+	if s.hasExamples() {
+		values = maps.Clone(values)
+		if values == nil {
+			values = map[string]string{}
+		}
+		values[examplesVariable] = strings.Join(examples, "\n\n")
+	}
+	return s.template.Render(values)
+}
+
+func (s *PromptSpec) hasExamples() bool {
+	return len(s.Examples) > 0
+}
+
+// compile parses the template and example template against their declared variables
+func (s *PromptSpec) compile() error {
+	variables := s.Variables
+	if s.hasExamples() {
+		variables = append(slices.Clip(variables), Variable{Name: examplesVariable, Type: TypeText, Raw: true})
+	}
+
+	template, err := NewTemplate(s.Name, s.Template, variables...)
+	if err != nil {
+		return err
+	}
+
+	var exampleTemplate *Template
+	if s.hasExamples() {
+		exampleTemplate, err = NewTemplate(s.Name+" example", s.ExampleTemplate, usedVariables(s.ExampleTemplate, exampleVariables)...)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.template, s.exampleTemplate = template, exampleTemplate
+	return nil
+}
+
+// usedVariables keeps the variables whose placeholder appears in text, so an example
+// template does not have to use all of input, output and index
+func usedVariables(text string, variables []Variable) []Variable {
+	used := map[string]bool{}
+	for _, match := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+		used[match[1]] = true
+	}
+
+	var declared []Variable
+	for _, v := range variables {
+		if used[v.Name] {
+			declared = append(declared, v)
+		}
+	}
+	return declared
+}
+
+func (s *PromptSpec) validate() error {
+	switch {
+	case s.Name == "":
+		return errors.New("prompt has no name")
+	case s.Technique == "":
+		return fmt.Errorf("prompt %q has no technique", s.Name)
+	case s.Template == "":
+		return fmt.Errorf("prompt %q has no template", s.Name)
+	case len(s.Examples) > 0 && s.ExampleTemplate == "":
+		return fmt.Errorf("prompt %q has examples but no example_template", s.Name)
+	}
+
+	if s.Answer != "" {
+		if _, err := AnswerExtractorFor(s.Answer); err != nil {
+			return fmt.Errorf("prompt %q: %w", s.Name, err)
+		}
+	}
+
+	if s.Output != nil {
+		parser, err := s.Output.Parser()
+		if err != nil {
+			return fmt.Errorf("prompt %q: %w", s.Name, err)
+		}
+		s.parser = parser
+	}
+
+	if s.Title == "" {
+		s.Title = s.Name
+	}
+	return s.compile()
+}
+
+// OutputParser returns the parser for the prompt's declared output, or nil when the completion is free text
+func (s *PromptSpec) OutputParser() OutputParser[any] {
+	return s.parser
+}
+
+// Library is a set of prompt specs loaded from YAML or JSON files
+type Library struct {
+	specs  []*PromptSpec
+	byName map[string]*PromptSpec
+}
+
+// LoadLibrary reads every .yaml, .yml and .json file at the top of fsys. Each file holds a list of
+// prompt specs; specs keep their file order and files are read in lexical order. Subdirectories are
+// skipped, so datasets, variants and other data can live next to the prompts.
+func LoadLibrary(fsys fs.FS) (*Library, error) {
+	lib := &Library{byName: map[string]*PromptSpec{}}
+	if err := lib.load(fsys); err != nil {
+		return nil, err
+	}
+	return lib, nil
+}
+
+// Extend returns a library with l's specs followed by those in fsys, leaving l unchanged.
+// A name that both define is an error.
+func (l *Library) Extend(fsys fs.FS) (*Library, error) {
+	lib := &Library{specs: slices.Clone(l.specs), byName: maps.Clone(l.byName)}
+	if err := lib.load(fsys); err != nil {
+		return nil, err
+	}
+	return lib, nil
+}
+
+func (l *Library) load(fsys fs.FS) error {
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && name != "." {
+			return fs.SkipDir
+		}
+		switch path.Ext(name) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		return l.add(name, data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load prompt library: %w", err)
+	}
+	return nil
+}
+
+func (l *Library) add(file string, data []byte) error {
+	// JSON is valid YAML, so one decoder covers both formats
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var specs []*PromptSpec
+	if err := decoder.Decode(&specs); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+
+	for _, spec := range specs {
+		if err := spec.validate(); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		if existing, ok := l.byName[spec.Name]; ok {
+			return fmt.Errorf("%s: prompt %q is already defined in %s", file, spec.Name, existing.Source)
+		}
+
+		spec.Source = file
+		l.specs = append(l.specs, spec)
+		l.byName[spec.Name] = spec
+	}
+	return nil
+}
+
+// Get returns the spec with the given name
+func (l *Library) Get(name string) (*PromptSpec, bool) {
+	spec, ok := l.byName[name]
+	return spec, ok
+}
+
+// Specs returns every spec in load order
+func (l *Library) Specs() []*PromptSpec {
+	return l.specs
+}
+
+// ByTechnique returns the specs of one technique in load order
+func (l *Library) ByTechnique(technique string) []*PromptSpec {
+	var specs []*PromptSpec
+	for _, spec := range l.specs {
+		if spec.Technique == technique {
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
+
+// Techniques returns the techniques used by the library's prompts: registered techniques first,
+// in registration order, then names only the library knows in the order they appear
+func (l *Library) Techniques() []Technique {
+	seen := map[string]bool{}
+	for _, spec := range l.specs {
+		seen[spec.Technique] = true
+	}
+
+	var techniques []Technique
+	for _, technique := range Techniques() {
+		if name := technique.Info().Name; seen[name] {
+			techniques = append(techniques, technique)
+			delete(seen, name)
+		}
+	}
+	for _, spec := range l.specs {
+		if seen[spec.Technique] {
+			techniques = append(techniques, TechniqueFor(spec.Technique))
+			delete(seen, spec.Technique)
+		}
+	}
+	return techniques
+}
+
+// DefaultLibrary returns the library embedded from the prompts directory
+var DefaultLibrary = sync.OnceValue(func() *Library {
+	lib, err := LoadLibrary(prompts.FS)
+	if err != nil {
+		// The embedded files are part of the build, so this only happens after a bad edit to them
+		panic(err)
+	}
+	return lib
+})
+
+// WithVariants returns lib extended with the prompt variants embedded from prompts/variants,
+// which eval and compare run in place of a dataset's prompt for other techniques
+func WithVariants(lib *Library) (*Library, error) {
+	variants, err := fs.Sub(prompts.Variants, "variants")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load prompt variants: %w", err)
+	}
+	return lib.Extend(variants)
+}
+
+// DefaultEvalLibrary returns the default library with the prompt variants
+var DefaultEvalLibrary = sync.OnceValue(func() *Library {
+	lib, err := WithVariants(DefaultLibrary())
+	if err != nil {
+		panic(err)
+	}
+	return lib
+})
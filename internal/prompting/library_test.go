@@ -0,0 +1,135 @@
+package prompting
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDefaultLibrary(t *testing.T) {
+	lib := DefaultLibrary()
+
+	if got := len(lib.Specs()); got != 18 {
+		t.Errorf("len(Specs()) = %d, want 18", got)
+	}
+
+	var names []string
+	for _, technique := range lib.Techniques() {
+		names = append(names, technique.Info().Name)
+	}
+	if got, want := strings.Join(names, ","), "zero-shot,few-shot,chain-of-thought,tree-of-thoughts,react"; got != want {
+		t.Errorf("Techniques() = %v, want %v", got, want)
+	}
+
+	// Every migrated prompt must render without missing placeholders
+	for _, spec := range lib.Specs() {
+		if _, err := spec.Render(spec.Inputs); err != nil {
+			t.Errorf("Render() error = %v", err)
+		}
+	}
+}
+
+func TestLoadLibraryFromDir(t *testing.T) {
+	// The layout PROMPT_LIBRARY_DIR=prompts loads: datasets and other subdirectories are not prompts
+	lib, err := LoadLibrary(os.DirFS("../../prompts"))
+	if err != nil {
+		t.Fatalf("LoadLibrary(prompts) error = %v", err)
+	}
+	if got, want := len(lib.Specs()), len(DefaultLibrary().Specs()); got != want {
+		t.Errorf("len(Specs()) = %d, want the %d of the embedded library", got, want)
+	}
+}
+
+func TestDefaultEvalLibrary(t *testing.T) {
+	lib := DefaultEvalLibrary()
+	dirLib, err := LoadLibrary(os.DirFS("../../prompts"))
+	if err != nil {
+		t.Fatalf("LoadLibrary(prompts) error = %v", err)
+	}
+
+	for _, name := range []string{"word-problem", "word-problems", "sentiment-reasoning"} {
+		spec, ok := lib.Get(name)
+		if !ok {
+			t.Errorf("Get(%q) not found in the eval library", name)
+			continue
+		}
+		if _, err := spec.Render(spec.Inputs); err != nil {
+			t.Errorf("Render() error = %v", err)
+		}
+		// Variants stay out of the demo menu and RunAllExamples, also when PROMPT_LIBRARY_DIR=prompts
+		if _, ok := DefaultLibrary().Get(name); ok {
+			t.Errorf("variant %q is in the demo library", name)
+		}
+		if _, ok := dirLib.Get(name); ok {
+			t.Errorf("variant %q is in the library loaded from the prompts directory", name)
+		}
+	}
+
+	if _, err := lib.Extend(fstest.MapFS{"dup.yaml": {Data: []byte("- name: word-problem\n  technique: zero-shot\n  template: x\n")}}); err == nil {
+		t.Error("Extend() accepted a name the library already defines")
+	}
+}
+
+func TestRenderExamples(t *testing.T) {
+	spec, ok := DefaultLibrary().Get("code-completion")
+	if !ok {
+		t.Fatal(`Get("code-completion") not found`)
+	}
+
+	prompt, err := spec.Render(spec.Inputs)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	for _, part := range []string{"Example 1:\nInput: Create a function to add two numbers", "Example 3:", "Input: Create a function to find the maximum of three numbers\nOutput:"} {
+		if !strings.Contains(prompt, part) {
+			t.Errorf("Render() = %q, want it to contain %q", prompt, part)
+		}
+	}
+}
+
+func TestLoadLibraryErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		want  string
+	}{
+		{
+			name: "duplicate name",
+			files: fstest.MapFS{
+				"a.yaml": {Data: []byte("- {name: greet, technique: zero-shot, template: Hi}")},
+				"b.json": {Data: []byte(`[{"name": "greet", "technique": "zero-shot", "template": "Hello"}]`)},
+			},
+			want: `prompt "greet" is already defined in a.yaml`,
+		},
+		{
+			name:  "unknown field",
+			files: fstest.MapFS{"a.yaml": {Data: []byte("- {name: greet, technique: zero-shot, template: Hi, temprature: 1}")}},
+			want:  "field temprature not found",
+		},
+		{
+			name:  "undeclared placeholder",
+			files: fstest.MapFS{"a.yaml": {Data: []byte("- {name: greet, technique: zero-shot, template: 'Hi {{name}}'}")}},
+			want:  "placeholder {{name}} is not declared",
+		},
+		{
+			name:  "missing template",
+			files: fstest.MapFS{"a.yaml": {Data: []byte("- {name: greet, technique: zero-shot}")}},
+			want:  `prompt "greet" has no template`,
+		},
+		{
+			name:  "label output without labels",
+			files: fstest.MapFS{"a.yaml": {Data: []byte("- {name: greet, technique: zero-shot, template: Hi, output: {format: label}}")}},
+			want:  `prompt "greet": "label" output has no labels`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadLibrary(tt.files)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadLibrary() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
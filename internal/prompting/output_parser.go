@@ -0,0 +1,475 @@
+package prompting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+)
+
+// Output formats a library prompt can declare in its output block
+const (
+	OutputLabel    = "label"
+	OutputEntities = "entities"
+	OutputCode     = "code"
+)
+
+// defaultReasks is how often a library prompt is re-asked after an unparseable completion
+const defaultReasks = 2
+
+// ParseError reports a completion that does not have the expected shape. Reason is written
+// for the model: InvokeParsed sends it back with a re-ask.
+type ParseError struct {
+	Format     string // e.g. "label"
+	Completion string
+	Reason     string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("failed to parse %s output: %s", e.Format, e.Reason)
+}
+
+// OutputParser turns a completion into a typed value
+type OutputParser[T any] interface {
+	// Parse returns a *ParseError when the completion does not have the expected shape
+	Parse(completion string) (T, error)
+	// Instructions describes the expected format to the model when it is re-asked
+	Instructions() string
+}
+
+// OutputSpec is the output block of a library prompt: the format its completion is parsed into
+type OutputSpec struct {
+	Format   string   `yaml:"format"`   // "label", "entities" or "code"
+	Labels   []string `yaml:"labels"`   // allowed labels of a label output
+	Types    []string `yaml:"types"`    // allowed entity types; any type when empty
+	Language string   `yaml:"language"` // language of a code output, e.g. "python"; any when empty
+}
+
+// Parser returns the parser the spec describes, or an error for an invalid spec
+func (o OutputSpec) Parser() (OutputParser[any], error) {
+	switch {
+	case o.Format != OutputLabel && len(o.Labels) > 0:
+		return nil, fmt.Errorf("labels are only used by %q outputs", OutputLabel)
+	case o.Format != OutputEntities && len(o.Types) > 0:
+		return nil, fmt.Errorf("types are only used by %q outputs", OutputEntities)
+	case o.Format != OutputCode && o.Language != "":
+		return nil, fmt.Errorf("language is only used by %q outputs", OutputCode)
+	}
+
+	switch o.Format {
+	case OutputLabel:
+		if len(o.Labels) == 0 {
+			return nil, fmt.Errorf("%q output has no labels", OutputLabel)
+		}
+		return anyParser[string]{NewLabelParser(o.Labels...)}, nil
+	case OutputEntities:
+		return anyParser[[]Entity]{NewEntityParser(o.Types...)}, nil
+	case OutputCode:
+		return anyParser[CodeBlock]{NewCodeBlockParser(o.Language)}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q (want %s, %s or %s)", o.Format, OutputLabel, OutputEntities, OutputCode)
+	}
+}
+
+// anyParser lets typed parsers be stored in a PromptSpec
+type anyParser[T any] struct {
+	parser OutputParser[T]
+}
+
+func (p anyParser[T]) Parse(completion string) (any, error) {
+	return p.parser.Parse(completion)
+}
+
+func (p anyParser[T]) Instructions() string {
+	return p.parser.Instructions()
+}
+
+// Parsed is a completion parsed into a typed value
+type Parsed[T any] struct {
+	Value    T
+	Response *bedrock.ModelResponse // the completion that parsed, with the token usage of every call
+	Reasks   []string               // why each earlier completion was rejected, in order
+}
+
+var reaskTemplate = MustTemplate("output re-ask", `{{prompt}} {{completion}}
+
+That answer could not be used: {{reason}}. {{instructions}}
+
+Answer again:`,
+	Variable{Name: "prompt", Type: TypeText, Raw: true},
+	Variable{Name: "completion", Type: TypeText, Raw: true},
+	Variable{Name: "reason", Type: TypeText, Raw: true},
+	Variable{Name: "instructions", Type: TypeText, Raw: true},
+)
+
+// InvokeParsed sends prompt and parses the completion. When parsing fails the model is re-asked,
+// with the parse error and the parser's instructions, up to reasks times; the last *ParseError is
+// returned if no completion parses.
+func InvokeParsed[T any](ctx context.Context, client bedrock.Invoker, prompt string, params bedrock.ModelParams, parser OutputParser[T], reasks int) (*Parsed[T], error) {
+	parsed := &Parsed[T]{}
+	usage := &bedrock.Usage{}
+
+	next := prompt
+	for attempt := 0; ; attempt++ {
+		response, err := client.InvokeModel(ctx, next, params)
+		if err != nil {
+			return nil, err
+		}
+		if response.Usage != nil {
+			usage.InputTokens += response.Usage.InputTokens
+			usage.OutputTokens += response.Usage.OutputTokens
+		}
+
+		value, err := parser.Parse(response.Completion)
+		if err == nil {
+			response.Usage = usage
+			parsed.Value, parsed.Response = value, response
+			return parsed, nil
+		}
+
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || attempt == reasks {
+			return nil, err
+		}
+		parsed.Reasks = append(parsed.Reasks, parseErr.Reason)
+
+		// Each re-ask builds on the original prompt, so rejected answers do not pile up
+		next, err = reaskTemplate.Render(map[string]string{
+			"prompt":       prompt,
+			"completion":   strings.TrimSpace(response.Completion),
+			"reason":       parseErr.Reason,
+			"instructions": parser.Instructions(),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// LabelParser picks one label from an allowed set
+type LabelParser struct {
+	labels [][]string // each label's words, lower-cased
+	names  []string
+}
+
+// NewLabelParser creates a parser that accepts the given labels, e.g. "positive", "negative", "neutral"
+func NewLabelParser(labels ...string) *LabelParser {
+	p := &LabelParser{names: labels}
+	for _, label := range labels {
+		p.labels = append(p.labels, words(label))
+	}
+	return p
+}
+
+// Parse returns the first allowed label the completion mentions, ignoring case and punctuation.
+// Only when no label appears verbatim are misspellings and abbreviations considered, such as
+// "postive" or "neg".
+func (p *LabelParser) Parse(completion string) (string, error) {
+	text := words(completion)
+
+	for _, fuzzy := range []bool{false, true} {
+		for i := range text {
+			for j, label := range p.labels {
+				if matchWords(text[i:], label, fuzzy) {
+					return p.names[j], nil
+				}
+			}
+		}
+	}
+
+	return "", &ParseError{Format: OutputLabel, Completion: completion, Reason: fmt.Sprintf("it does not name one of the allowed labels (%s)", strings.Join(p.names, ", "))}
+}
+
+func (p *LabelParser) Instructions() string {
+	return fmt.Sprintf("Reply with exactly one of: %s.", strings.Join(p.names, ", "))
+}
+
+// matchWords reports whether text starts with the words of label
+func matchWords(text, label []string, fuzzy bool) bool {
+	if len(label) == 0 || len(text) < len(label) {
+		return false
+	}
+	for i, word := range label {
+		if text[i] != word && (!fuzzy || !similar(text[i], word)) {
+			return false
+		}
+	}
+	return true
+}
+
+// similar reports whether word is an abbreviation of label of at least three letters, or
+// a misspelling of it: one edit for words of four to seven letters, two for longer ones
+func similar(word, label string) bool {
+	if len(word) >= 3 && len(word) < len(label) && strings.HasPrefix(label, word) {
+		return true
+	}
+
+	allowed := 0
+	switch n := len([]rune(label)); {
+	case n >= 8:
+		allowed = 2
+	case n >= 4:
+		allowed = 1
+	}
+	return allowed > 0 && editDistance(word, label) <= allowed
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current := make([]int, len(rb)+1)
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(prev[j]+1, current[j-1]+1, prev[j-1]+cost)
+		}
+		prev = current
+	}
+	return prev[len(rb)]
+}
+
+// words lower-cases text and splits it into words. Hyphens and underscores join words,
+// so "non-urgent" does not match the label "urgent".
+func words(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_'
+	})
+
+	var result []string
+	for _, field := range fields {
+		if field = strings.Trim(field, "-_"); field != "" {
+			result = append(result, field)
+		}
+	}
+	return result
+}
+
+// Entity is a named entity found in a text
+type Entity struct {
+	Type string `json:"type"` // e.g. "PERSON"
+	Text string `json:"text"` // e.g. "Steve Jobs"
+}
+
+// EntityParser reads entity lists such as "- PERSON: Steve Jobs", "Steve Jobs (PERSON)" or
+// a JSON array of {"type", "text"} objects
+type EntityParser struct {
+	types  *LabelParser
+	names  []string
+	strict bool
+}
+
+var (
+	entityTypeFirst = regexp.MustCompile(`^([A-Za-z][A-Za-z_-]{0,29})\s*:\s*(.+)$`)
+	entityTypeLast  = regexp.MustCompile(`^(.+?)\s*[(\[]([A-Za-z][A-Za-z_-]{0,29})[)\]]$`)
+	listMarker      = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s+`)
+)
+
+// NewEntityParser creates a parser for the given entity types, e.g. "PERSON", "ORGANIZATION".
+// With no types, any type is accepted.
+func NewEntityParser(types ...string) *EntityParser {
+	p := &EntityParser{names: types, strict: len(types) > 0}
+	if p.strict {
+		p.types = NewLabelParser(types...)
+	}
+	return p
+}
+
+// Parse returns the entities in the completion in order, without duplicates. Types are upper-cased
+// and, with allowed types, matched like labels so "Organisation" or "ORG" become "ORGANIZATION".
+// A list item with a type that is not allowed is an error; other unrecognized lines are skipped.
+// A completion of just "none" is an empty list.
+func (p *EntityParser) Parse(completion string) ([]Entity, error) {
+	trimmed := strings.TrimSpace(completion)
+	if slices.Contains([]string{"none", "no entities", "n/a"}, strings.ToLower(strings.TrimRight(trimmed, "."))) {
+		return []Entity{}, nil
+	}
+
+	if strings.HasPrefix(trimmed, "[") {
+		var entities []Entity
+		if err := json.Unmarshal([]byte(trimmed), &entities); err == nil {
+			return p.collect(completion, entities, func(int) bool { return true })
+		}
+	}
+
+	var entities []Entity
+	listed := map[int]bool{}
+	for _, line := range strings.Split(completion, "\n") {
+		item := listMarker.MatchString(line)
+		line = strings.TrimSpace(listMarker.ReplaceAllString(line, ""))
+
+		var entity Entity
+		if match := entityTypeFirst.FindStringSubmatch(line); match != nil {
+			entity = Entity{Type: match[1], Text: match[2]}
+		} else if match := entityTypeLast.FindStringSubmatch(line); match != nil {
+			entity = Entity{Type: match[2], Text: match[1]}
+		} else {
+			continue
+		}
+
+		listed[len(entities)] = item
+		entities = append(entities, entity)
+	}
+	return p.collect(completion, entities, func(i int) bool { return listed[i] })
+}
+
+// collect normalizes and de-duplicates entities. Entities with an unknown type fail the
+// parse when required(i) is true and are dropped otherwise.
+func (p *EntityParser) collect(completion string, entities []Entity, required func(i int) bool) ([]Entity, error) {
+	var result []Entity
+	for i, entity := range entities {
+		entity.Text = strings.Trim(strings.TrimSpace(entity.Text), `"'`)
+		entity.Type = strings.ToUpper(entity.Type)
+		if entity.Text == "" {
+			continue
+		}
+
+		if p.strict {
+			name, err := p.types.Parse(entity.Type)
+			if err != nil {
+				if required(i) {
+					return nil, &ParseError{Format: OutputEntities, Completion: completion, Reason: fmt.Sprintf("%q is not an allowed entity type (%s)", entity.Type, strings.Join(p.names, ", "))}
+				}
+				continue
+			}
+			entity.Type = name
+		}
+
+		if !slices.Contains(result, entity) {
+			result = append(result, entity)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, &ParseError{Format: OutputEntities, Completion: completion, Reason: "it does not list any entities"}
+	}
+	return result, nil
+}
+
+func (p *EntityParser) Instructions() string {
+	types := "TYPE"
+	if p.strict {
+		types = strings.Join(p.names, ", ")
+	}
+	return fmt.Sprintf(`List one entity per line as "- TYPE: text", where TYPE is one of %s, or reply "none".`, types)
+}
+
+// CodeBlock is source code extracted from a completion
+type CodeBlock struct {
+	Language string // fence info string, e.g. "python"; empty when the block has none
+	Code     string
+}
+
+// CodeBlockParser extracts fenced code from a completion
+type CodeBlockParser struct {
+	language string
+}
+
+var fencePattern = regexp.MustCompile("(?m)^[ \t]*(```+|~~~+)[ \t]*([\\w+#.-]*)[^\n]*$")
+
+// NewCodeBlockParser creates a parser for code in language, e.g. "python". With an empty
+// language, the first block is used whatever its language.
+func NewCodeBlockParser(language string) *CodeBlockParser {
+	return &CodeBlockParser{language: canonicalLanguage(language)}
+}
+
+// languageAliases maps common fence info strings to one name per language
+var languageAliases = map[string]string{
+	"py":     "python",
+	"js":     "javascript",
+	"ts":     "typescript",
+	"golang": "go",
+	"sh":     "bash",
+	"shell":  "bash",
+}
+
+func canonicalLanguage(language string) string {
+	language = strings.ToLower(language)
+	if alias, ok := languageAliases[language]; ok {
+		return alias
+	}
+	return language
+}
+
+// Parse returns the first fenced block in the language, or the first block without a
+// language. A completion without fences is taken as code as a whole, since models often
+// answer code prompts with bare code.
+func (p *CodeBlockParser) Parse(completion string) (CodeBlock, error) {
+	fences := fencePattern.FindAllStringSubmatchIndex(completion, -1)
+	if len(fences) == 0 {
+		code := strings.TrimSpace(completion)
+		if code == "" {
+			return CodeBlock{}, &ParseError{Format: OutputCode, Completion: completion, Reason: "it is empty"}
+		}
+		return CodeBlock{Language: p.language, Code: code}, nil
+	}
+
+	var blocks []CodeBlock
+	for i := 0; i < len(fences); i++ {
+		open := fences[i]
+		marker, language := completion[open[2]:open[3]], canonicalLanguage(completion[open[4]:open[5]])
+
+		// The closing fence repeats the opening marker and has no info string
+		closing := -1
+		for j := i + 1; j < len(fences); j++ {
+			if close := fences[j]; completion[close[2]:close[3]] == marker && close[4] == close[5] {
+				closing = j
+				break
+			}
+		}
+		if closing < 0 {
+			return CodeBlock{}, &ParseError{Format: OutputCode, Completion: completion, Reason: "a code block is not closed"}
+		}
+
+		code := strings.Trim(completion[open[1]:fences[closing][0]], "\n")
+		blocks = append(blocks, CodeBlock{Language: language, Code: code})
+		i = closing
+	}
+
+	for _, block := range blocks {
+		if p.language == "" || block.Language == p.language {
+			return block, nil
+		}
+	}
+	for _, block := range blocks {
+		if block.Language == "" {
+			block.Language = p.language
+			return block, nil
+		}
+	}
+	return CodeBlock{}, &ParseError{Format: OutputCode, Completion: completion, Reason: fmt.Sprintf("it has no %s code block", p.language)}
+}
+
+func (p *CodeBlockParser) Instructions() string {
+	return fmt.Sprintf("Reply with the code in a single fenced block starting with ```%s.", p.language)
+}
+
+// formatOutput renders a parsed output for display
+func formatOutput(output any) string {
+	switch output := output.(type) {
+	case []Entity:
+		lines := make([]string, len(output))
+		for i, entity := range output {
+			lines[i] = fmt.Sprintf("- %s: %s", entity.Type, entity.Text)
+		}
+		return "\n" + strings.Join(lines, "\n")
+	case CodeBlock:
+		return fmt.Sprintf("%s code\n%s", output.Language, output.Code)
+	default:
+		return fmt.Sprint(output)
+	}
+}
@@ -0,0 +1,187 @@
+package prompting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+	"aws-bedrock-prompt-engineering/internal/bedrock/bedrocktest"
+)
+
+func TestLabelParser(t *testing.T) {
+	parser := NewLabelParser("urgent", "marketing", "support", "general", "not spam")
+
+	tests := []struct {
+		completion string
+		want       string
+	}{
+		{"support", "support"},
+		{" Support.\n", "support"},
+		{"Category: MARKETING", "marketing"},
+		{"This email is urgent because the server is down.", "urgent"},
+		{"non-urgent, so general", "general"},
+		{"It is not spam", "not spam"},
+		{"suport", "support"},
+		{"mktg? no: market", "marketing"},
+		{"The category is marketingg", "marketing"},
+	}
+	for _, tt := range tests {
+		got, err := parser.Parse(tt.completion)
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q) = %q, %v, want %q", tt.completion, got, err, tt.want)
+		}
+	}
+
+	var parseErr *ParseError
+	if _, err := parser.Parse("I cannot classify this."); !errors.As(err, &parseErr) || parseErr.Format != OutputLabel {
+		t.Errorf("Parse() of an unrelated reply error = %v, want a label ParseError", err)
+	}
+}
+
+func TestEntityParser(t *testing.T) {
+	parser := NewEntityParser("PERSON", "ORGANIZATION", "LOCATION")
+
+	completion := `Here are the entities:
+- PERSON: Dr. Sarah Johnson
+- Organisation: "Harvard University"
+* Boston (LOC)
+- PERSON: Dr. Sarah Johnson
+Note: the conference has no name.`
+	got, err := parser.Parse(completion)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := []Entity{
+		{Type: "PERSON", Text: "Dr. Sarah Johnson"},
+		{Type: "ORGANIZATION", Text: "Harvard University"},
+		{Type: "LOCATION", Text: "Boston"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Parse() = %+v, want %+v", got, want)
+	}
+
+	got, err = parser.Parse(`[{"type": "person", "text": "Steve Jobs"}]`)
+	if err != nil || !slices.Equal(got, []Entity{{Type: "PERSON", Text: "Steve Jobs"}}) {
+		t.Errorf("Parse() of JSON = %+v, %v", got, err)
+	}
+
+	if got, err := parser.Parse("None."); err != nil || len(got) != 0 {
+		t.Errorf("Parse(\"None.\") = %+v, %v, want no entities", got, err)
+	}
+	if _, err := parser.Parse("- DATE: next week"); err == nil || !strings.Contains(err.Error(), `"DATE" is not an allowed entity type`) {
+		t.Errorf("Parse() with an unknown type error = %v", err)
+	}
+	if _, err := parser.Parse("There are no obvious entities here"); err == nil {
+		t.Error("Parse() of prose should fail")
+	}
+}
+
+func TestCodeBlockParser(t *testing.T) {
+	completion := "Here is a shell test and the function:\n\n```bash\ngo test ./...\n```\n\n```py\ndef max3(a, b, c):\n    return max(a, b, c)\n```\nDone."
+
+	block, err := NewCodeBlockParser("python").Parse(completion)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if block.Language != "python" || block.Code != "def max3(a, b, c):\n    return max(a, b, c)" {
+		t.Errorf("Parse() = %+v", block)
+	}
+
+	if block, err := NewCodeBlockParser("").Parse(completion); err != nil || block.Code != "go test ./..." {
+		t.Errorf("Parse() with any language = %+v, %v, want the first block", block, err)
+	}
+	if block, err := NewCodeBlockParser("python").Parse("def f():\n    pass"); err != nil || block.Code != "def f():\n    pass" {
+		t.Errorf("Parse() of bare code = %+v, %v", block, err)
+	}
+	if _, err := NewCodeBlockParser("python").Parse("```python\ndef f():"); err == nil {
+		t.Error("Parse() of an unclosed block should fail")
+	}
+	if _, err := NewCodeBlockParser("go").Parse(completion); err == nil {
+		t.Error("Parse() without a block in the language should fail")
+	}
+}
+
+func TestInvokeParsedReasks(t *testing.T) {
+	fake := bedrocktest.NewFake().RespondSequence(`.*`, "I'd rather not say.", "Hmm.", "Negative!")
+
+	parsed, err := InvokeParsed(context.Background(), fake, "Sentiment:", bedrock.ModelParams{}, NewLabelParser("positive", "negative"), 2)
+	if err != nil {
+		t.Fatalf("InvokeParsed() error = %v", err)
+	}
+	if parsed.Value != "negative" || len(parsed.Reasks) != 2 {
+		t.Errorf("Value = %q after %d re-asks, want negative after 2", parsed.Value, len(parsed.Reasks))
+	}
+
+	calls := fake.Calls()
+	// The re-ask shows the rejected answer, why it was rejected and the expected format
+	reask := calls[1].Prompt
+	for _, want := range []string{"Sentiment: I'd rather not say.", "allowed labels", "Reply with exactly one of: positive, negative."} {
+		if !strings.Contains(reask, want) {
+			t.Errorf("re-ask prompt is missing %q:\n%s", want, reask)
+		}
+	}
+	if strings.Contains(calls[2].Prompt, "rather not say") {
+		t.Error("second re-ask should not repeat the first rejected answer")
+	}
+
+	fake = bedrocktest.NewFake().Default("no idea")
+	_, err = InvokeParsed(context.Background(), fake, "Sentiment:", bedrock.ModelParams{}, NewLabelParser("positive", "negative"), 1)
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || len(fake.Calls()) != 2 {
+		t.Errorf("InvokeParsed() error = %v after %d calls, want a ParseError after 2", err, len(fake.Calls()))
+	}
+}
+
+// wrappingParser wraps the label parser's errors the way a parser built on another one would
+type wrappingParser struct {
+	*LabelParser
+}
+
+func (p wrappingParser) Parse(completion string) (string, error) {
+	label, err := p.LabelParser.Parse(completion)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse sentiment: %w", err)
+	}
+	return label, nil
+}
+
+func TestInvokeParsedReasksWrappedParseError(t *testing.T) {
+	fake := bedrocktest.NewFake().RespondSequence(`.*`, "I'd rather not say.", "Positive.")
+
+	parsed, err := InvokeParsed(context.Background(), fake, "Sentiment:", bedrock.ModelParams{}, wrappingParser{NewLabelParser("positive", "negative")}, 2)
+	if err != nil {
+		t.Fatalf("InvokeParsed() error = %v", err)
+	}
+	if parsed.Value != "positive" || len(parsed.Reasks) != 1 || len(fake.Calls()) != 2 {
+		t.Errorf("Value = %q after %d re-asks and %d calls, want positive after 1 re-ask", parsed.Value, len(parsed.Reasks), len(fake.Calls()))
+	}
+}
+
+func TestFewShotTypedResults(t *testing.T) {
+	t.Setenv("MODEL_ID", testModelID)
+
+	fake := withScriptedAnswers(bedrocktest.NewFake())
+	f := NewFewShotPrompt(fake)
+
+	sentiment, err := f.ClassifySentiment(context.Background(), "Worst purchase ever.")
+	if err != nil || sentiment != "negative" {
+		t.Errorf("ClassifySentiment() = %q, %v, want negative", sentiment, err)
+	}
+	if !strings.Contains(fake.Calls()[0].Prompt, `Text: "Worst purchase ever."`) {
+		t.Errorf("prompt does not contain the text:\n%s", fake.Calls()[0].Prompt)
+	}
+
+	entities, err := f.ExtractEntities(context.Background(), "Dr. Sarah Johnson from Harvard University")
+	if err != nil || len(entities) != 3 || entities[1] != (Entity{Type: "ORGANIZATION", Text: "Harvard University"}) {
+		t.Errorf("ExtractEntities() = %+v, %v", entities, err)
+	}
+
+	category, err := f.ClassifyEmail(context.Background(), "My password reset link is broken.")
+	if err != nil || category != "support" {
+		t.Errorf("ClassifyEmail() = %q, %v, want support", category, err)
+	}
+}
@@ -0,0 +1,244 @@
+package prompting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+	"aws-bedrock-prompt-engineering/internal/tools"
+	"aws-bedrock-prompt-engineering/prompts"
+)
+
+// ErrStepLimit is returned when a ReAct agent reaches its step limit without a final answer
+var ErrStepLimit = errors.New("step limit reached without a final answer")
+
+// observationStop stops generation before the model writes an observation of its own
+const observationStop = "\nObservation:"
+
+var (
+	reactTemplate = MustTemplate("react", `Answer the question by reasoning step by step and using tools.
+
+You can use these tools:
+{{tools}}
+
+Use exactly this format:
+Thought: what you need to find out next
+Action: the tool to use, one of [{{tool_names}}]
+Action Input: the input for the tool
+Observation: the tool's result
+... (Thought, Action, Action Input and Observation can repeat)
+Thought: I now know the final answer
+Final Answer: the answer to the question
+
+Question: {{question}}
+{{scratchpad}}`,
+		Variable{Name: "tools", Type: TypeText, Raw: true},
+		Variable{Name: "tool_names", Raw: true},
+		Variable{Name: "question", Type: TypeText, Raw: true},
+		Variable{Name: "scratchpad", Type: TypeText, Raw: true},
+	)
+
+	actionPattern      = regexp.MustCompile(`(?m)^\s*Action:\s*(.+?)\s*$`)
+	actionInputPattern = regexp.MustCompile(`(?s)Action Input:\s*(.*)$`)
+	finalAnswerPattern = regexp.MustCompile(`(?s)Final Answer:\s*(.*)$`)
+)
+
+// ReActStep is one Thought/Action/Observation turn
+type ReActStep struct {
+	Thought     string
+	Action      string // tool name; empty for the final step or a malformed reply
+	Input       string
+	Observation string
+}
+
+// ReActResult is the outcome of a ReAct run
+type ReActResult struct {
+	Answer   string
+	Steps    []ReActStep
+	Response *bedrock.ModelResponse // the reply that held the final answer
+}
+
+// ReActAgent interleaves reasoning with tool use: the model writes a Thought and an Action,
+// the agent runs the tool and feeds its Observation back, until the model gives a Final Answer
+type ReActAgent struct {
+	tools    *tools.Set
+	MaxSteps int // model calls allowed before giving up
+}
+
+// NewReActAgent creates an agent that can use toolset, limited to 8 steps
+func NewReActAgent(toolset *tools.Set) *ReActAgent {
+	return &ReActAgent{tools: toolset, MaxSteps: 8}
+}
+
+// DefaultTools returns the calculator and the file lookup and search tools over the demo corpus
+// embedded from prompts/corpus
+func DefaultTools() *tools.Set {
+	corpus, err := fs.Sub(prompts.Corpus, "corpus")
+	if err != nil {
+		panic(fmt.Sprintf("prompting: embedded corpus: %v", err))
+	}
+
+	set, err := tools.NewSet(tools.Calculator{}, tools.NewFileLookup(corpus), tools.NewCorpusSearch(corpus))
+	if err != nil {
+		panic(fmt.Sprintf("prompting: default tools: %v", err))
+	}
+	return set
+}
+
+// Run answers question. Tool errors, unknown tools and malformed replies are fed back as
+// observations so the model can recover. When the step limit is reached, the steps so far are
+// returned with ErrStepLimit.
+func (a *ReActAgent) Run(ctx context.Context, client bedrock.Invoker, question string, params bedrock.ModelParams) (*ReActResult, error) {
+	var descriptions strings.Builder
+	for _, tool := range a.tools.Tools() {
+		fmt.Fprintf(&descriptions, "%s: %s\n", tool.Name(), tool.Description())
+	}
+
+	params.StopSequences = append(params.StopSequences[:len(params.StopSequences):len(params.StopSequences)], observationStop)
+
+	result := &ReActResult{}
+	var scratchpad strings.Builder
+	for range a.MaxSteps {
+		prompt, err := reactTemplate.Render(map[string]string{
+			"tools":      strings.TrimSpace(descriptions.String()),
+			"tool_names": strings.Join(a.tools.Names(), ", "),
+			"question":   question,
+			"scratchpad": scratchpad.String(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := client.InvokeModel(ctx, prompt, params)
+		if err != nil {
+			return result, fmt.Errorf("failed to run step %d: %w", len(result.Steps)+1, err)
+		}
+		// Models whose request body has no stop sequences, such as Llama, write on past the action
+		// with an observation of their own, so everything from it on is dropped
+		completion, _, _ := strings.Cut(response.Completion, observationStop)
+		reply := strings.TrimSpace(strings.TrimSuffix(completion, strings.TrimSpace(observationStop)))
+
+		step := ReActStep{Thought: parseThought(reply)}
+		if answer := finalAnswerPattern.FindStringSubmatch(reply); answer != nil && !actionPattern.MatchString(reply) {
+			result.Steps = append(result.Steps, step)
+			result.Answer = strings.TrimSpace(answer[1])
+			result.Response = response
+			return result, nil
+		}
+
+		step.Action = firstSubmatch(actionPattern, reply)
+		step.Input = strings.Trim(firstSubmatch(actionInputPattern, reply), "\"'` \n")
+		step.Observation = a.observe(ctx, step)
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		result.Steps = append(result.Steps, step)
+
+		fmt.Fprintf(&scratchpad, "Thought: %s\nAction: %s\nAction Input: %s\nObservation: %s\n",
+			step.Thought, step.Action, step.Input, EscapeInput(step.Observation))
+	}
+
+	return result, fmt.Errorf("%w (%d steps)", ErrStepLimit, a.MaxSteps)
+}
+
+// observe runs the step's tool and returns what the model should see next
+func (a *ReActAgent) observe(ctx context.Context, step ReActStep) string {
+	if step.Action == "" {
+		return `Invalid format. Reply with "Action:" and "Action Input:" lines, or with "Final Answer:".`
+	}
+
+	tool, ok := a.tools.Get(step.Action)
+	if !ok {
+		return fmt.Sprintf("Unknown tool %q. Use one of: %s.", step.Action, strings.Join(a.tools.Names(), ", "))
+	}
+
+	observation, err := tool.Run(ctx, step.Input)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	return observation
+}
+
+// parseThought returns the text before the reply's action or final answer
+func parseThought(reply string) string {
+	end := len(reply)
+	for _, marker := range []string{"Action:", "Final Answer:"} {
+		if i := strings.Index(reply, marker); i >= 0 && i < end {
+			end = i
+		}
+	}
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(reply[:end]), "Thought:"))
+}
+
+func firstSubmatch(pattern *regexp.Regexp, text string) string {
+	if match := pattern.FindStringSubmatch(text); match != nil {
+		return strings.TrimSpace(match[1])
+	}
+	return ""
+}
+
+// ReActTechnique runs library prompts as the question of a ReActAgent
+type ReActTechnique struct {
+	agent *ReActAgent
+}
+
+// ReAct answers library prompts with DefaultTools
+var ReAct = NewReActTechnique(NewReActAgent(DefaultTools()))
+
+// NewReActTechnique creates a ReAct technique around agent, e.g. one with domain tools
+func NewReActTechnique(agent *ReActAgent) *ReActTechnique {
+	return &ReActTechnique{agent: agent}
+}
+
+func (t *ReActTechnique) Info() TechniqueInfo {
+	return TechniqueInfo{
+		Name:        TechniqueReAct,
+		Title:       "ReAct",
+		Emoji:       "🛠️",
+		Description: "Reasoning interleaved with tool use",
+	}
+}
+
+func (t *ReActTechnique) DefaultParams() bedrock.ModelParams {
+	params := bedrock.GetDefaultClaudeParams()
+	params.Temperature = 0.2 // Tool calls need exact names and inputs
+	params.MaxTokens = 400   // Each call writes a single Thought and Action
+	return params
+}
+
+func (t *ReActTechnique) Execute(ctx context.Context, client bedrock.Invoker, spec *PromptSpec, params bedrock.ModelParams, values map[string]string) (*Result, error) {
+	question, err := spec.Render(values)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := t.agent.Run(ctx, client, question, spec.Params.Apply(params))
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute %s: %w", strings.ToLower(spec.Title), err)
+	}
+
+	steps := make([]string, 0, len(result.Steps))
+	for _, step := range result.Steps {
+		if step.Action == "" {
+			steps = append(steps, step.Thought)
+			continue
+		}
+		steps = append(steps, fmt.Sprintf("%s → %s(%s) → %s", step.Thought, step.Action, strconv.Quote(step.Input), oneLine(step.Observation)))
+	}
+
+	return &Result{Prompt: question, Response: result.Response, Steps: steps}, nil
+}
+
+// oneLine shortens an observation for display
+func oneLine(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) > 120 {
+		return strings.ToValidUTF8(text[:120], "") + "..."
+	}
+	return text
+}
@@ -0,0 +1,141 @@
+package prompting
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+	"aws-bedrock-prompt-engineering/internal/bedrock/bedrocktest"
+	"aws-bedrock-prompt-engineering/internal/tools"
+)
+
+// upperTool stands in for a domain tool added outside the tools package
+type upperTool struct{}
+
+func (upperTool) Name() string        { return "upper" }
+func (upperTool) Description() string { return "Upper-cases its input" }
+func (upperTool) Run(ctx context.Context, input string) (string, error) {
+	return strings.ToUpper(input), nil
+}
+
+func TestReActAgentUsesTools(t *testing.T) {
+	fake := bedrocktest.NewFake().RespondSequence(`.*`,
+		"Thought: I need the price.\nAction: search_corpus\nAction Input: pro",
+		"Thought: Now compute the yearly cost.\nAction: calculator\nAction Input: 3 * 49 * 12 * 0.85",
+		"Thought: I now know the final answer\nFinal Answer: $1,499.40",
+	)
+
+	result, err := NewReActAgent(DefaultTools()).Run(context.Background(), fake, "What do 3 Pro seats cost per year?", bedrock.ModelParams{})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if result.Answer != "$1,499.40" || len(result.Steps) != 3 {
+		t.Fatalf("Answer = %q after %d steps, want $1,499.40 after 3", result.Answer, len(result.Steps))
+	}
+	if step := result.Steps[1]; step.Action != "calculator" || step.Observation != "1499.4" {
+		t.Errorf("calculator step = %+v", step)
+	}
+
+	calls := fake.Calls()
+	// Observations are fed back, and generation stops before the model writes its own
+	if !strings.Contains(calls[1].Prompt, "products/pricing.md:6: | Pro") {
+		t.Errorf("second prompt is missing the search observation:\n%s", calls[1].Prompt)
+	}
+	if !strings.Contains(calls[2].Prompt, "Observation: 1499.4") {
+		t.Errorf("third prompt is missing the calculator observation:\n%s", calls[2].Prompt)
+	}
+	if stops := calls[0].Params.StopSequences; len(stops) != 1 || stops[0] != observationStop {
+		t.Errorf("StopSequences = %q, want the observation stop", stops)
+	}
+}
+
+func TestReActAgentIgnoresInventedObservations(t *testing.T) {
+	// The fake, like a Llama body, ignores StopSequences, so the model writes on past its action
+	fake := bedrocktest.NewFake().RespondSequence(`.*`,
+		"Thought: I need the price.\nAction: search_corpus\nAction Input: pro\nObservation: Pro costs $10\nThought: I now know the final answer\nFinal Answer: $10",
+		"Thought: I now know the final answer\nFinal Answer: $49",
+	)
+
+	result, err := NewReActAgent(DefaultTools()).Run(context.Background(), fake, "What does a Pro seat cost?", bedrock.ModelParams{})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if result.Answer != "$49" || len(result.Steps) != 2 {
+		t.Fatalf("Answer = %q after %d steps, want $49 after 2", result.Answer, len(result.Steps))
+	}
+	if step := result.Steps[0]; step.Action != "search_corpus" || step.Input != "pro" || !strings.Contains(step.Observation, "products/pricing.md") {
+		t.Errorf("search step = %+v, want the real search for \"pro\"", step)
+	}
+	if strings.Contains(fake.Calls()[1].Prompt, "Pro costs $10") {
+		t.Error("the invented observation was fed back to the model")
+	}
+}
+
+func TestReActAgentRecoversFromBadActions(t *testing.T) {
+	set, err := tools.NewSet(upperTool{})
+	if err != nil {
+		t.Fatalf("NewSet() error = %v", err)
+	}
+	fake := bedrocktest.NewFake().RespondSequence(`.*`,
+		"Thought: Let me guess.",
+		"Thought: Try a search.\nAction: web_search\nAction Input: go",
+		"Thought: Use upper.\nAction: upper\nAction Input: \"go\"",
+		"Final Answer: GO",
+	)
+
+	result, err := NewReActAgent(set).Run(context.Background(), fake, "Shout go", bedrock.ModelParams{})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if !strings.HasPrefix(result.Steps[0].Observation, "Invalid format") {
+		t.Errorf("malformed reply observation = %q", result.Steps[0].Observation)
+	}
+	if !strings.Contains(result.Steps[1].Observation, `Unknown tool "web_search"`) {
+		t.Errorf("unknown tool observation = %q", result.Steps[1].Observation)
+	}
+	if result.Steps[2].Observation != "GO" || result.Answer != "GO" {
+		t.Errorf("upper observation = %q, Answer = %q", result.Steps[2].Observation, result.Answer)
+	}
+}
+
+func TestReActAgentStepLimit(t *testing.T) {
+	fake := bedrocktest.NewFake().Default("Thought: Again.\nAction: calculator\nAction Input: 1 + 1")
+	agent := NewReActAgent(DefaultTools())
+	agent.MaxSteps = 3
+
+	result, err := agent.Run(context.Background(), fake, "Loop forever", bedrock.ModelParams{})
+	if !errors.Is(err, ErrStepLimit) {
+		t.Fatalf("Run() error = %v, want ErrStepLimit", err)
+	}
+	if len(result.Steps) != 3 || len(fake.Calls()) != 3 {
+		t.Errorf("ran %d steps with %d calls, want 3", len(result.Steps), len(fake.Calls()))
+	}
+}
+
+func TestReActTechnique(t *testing.T) {
+	t.Setenv("MODEL_ID", testModelID)
+
+	fake := bedrocktest.NewFake().RespondSequence(`.*`,
+		"Thought: Check the policy.\nAction: lookup_file\nAction Input: policies/time-off.md",
+		"Final Answer: 25",
+	)
+	spec, _ := DefaultLibrary().Get("time-off-balance")
+
+	technique := TechniqueFor(TechniqueReAct)
+	result, err := technique.Execute(context.Background(), fake, spec, technique.DefaultParams(), spec.Inputs)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if result.Response.Completion != "Final Answer: 25" || len(result.Steps) != 2 {
+		t.Errorf("Completion = %q with steps %q", result.Response.Completion, result.Steps)
+	}
+	if !strings.Contains(result.Steps[0], `lookup_file("policies/time-off.md")`) {
+		t.Errorf("Steps[0] = %q", result.Steps[0])
+	}
+}
@@ -0,0 +1,72 @@
+package prompting
+
+import (
+	"fmt"
+	"sync"
+)
+
+// registry holds the registered techniques in registration order. The built-ins are registered
+// during variable initialization, so they come before techniques registered from init functions.
+var registry = newTechniqueRegistry(ZeroShot, FewShot, ChainOfThought, TreeOfThoughts, ReAct)
+
+type techniqueRegistry struct {
+	mu         sync.RWMutex
+	techniques []Technique
+	byName     map[string]Technique
+}
+
+func newTechniqueRegistry(builtins ...Technique) *techniqueRegistry {
+	r := &techniqueRegistry{byName: map[string]Technique{}}
+	for _, technique := range builtins {
+		r.register(technique)
+	}
+	return r
+}
+
+func (r *techniqueRegistry) register(technique Technique) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := technique.Info().Name
+	if name == "" {
+		panic("prompting: Register called with a technique without a name")
+	}
+	if _, ok := r.byName[name]; ok {
+		panic(fmt.Sprintf("prompting: technique %q registered twice", name))
+	}
+
+	r.techniques = append(r.techniques, technique)
+	r.byName[name] = technique
+}
+
+// Register makes a technique available under its name. It panics if the name is empty or
+// already taken, so it is meant to be called from init functions.
+func Register(technique Technique) {
+	registry.register(technique)
+}
+
+// Lookup returns the technique registered under name
+func Lookup(name string) (Technique, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	technique, ok := registry.byName[name]
+	return technique, ok
+}
+
+// Techniques returns every registered technique in registration order
+func Techniques() []Technique {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	return append([]Technique(nil), registry.techniques...)
+}
+
+// TechniqueFor returns the technique registered under name. Names only the prompt library
+// knows get a single-call technique with the default ModelParams, so any prompt can run.
+func TechniqueFor(name string) Technique {
+	if technique, ok := Lookup(name); ok {
+		return technique
+	}
+	return NewPromptTechnique(TechniqueInfo{Name: name, Title: name, Emoji: "📝"}, nil)
+}
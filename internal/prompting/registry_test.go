@@ -0,0 +1,62 @@
+package prompting
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+	"aws-bedrock-prompt-engineering/internal/bedrock/bedrocktest"
+)
+
+// shoutTechnique is a stand-in for a technique defined outside this package
+type shoutTechnique struct {
+	*PromptTechnique
+}
+
+func TestRegisterTechnique(t *testing.T) {
+	t.Setenv("MODEL_ID", testModelID)
+
+	shout := shoutTechnique{NewPromptTechnique(TechniqueInfo{Name: "test-shout", Title: "Shout"}, func(params *bedrock.ModelParams) {
+		params.Temperature = 0.9
+	})}
+	Register(shout)
+
+	if got, ok := Lookup("test-shout"); !ok || got.Info().Title != "Shout" {
+		t.Fatalf("Lookup() = %v, %v, want the registered technique", got, ok)
+	}
+
+	// Built-ins stay first, and library-only names come after the registered ones
+	lib, err := LoadLibrary(fstest.MapFS{"a.yaml": {Data: []byte(`
+- {name: one, technique: role-play, template: "Pretend"}
+- {name: two, technique: test-shout, template: "HELLO"}
+- {name: three, technique: zero-shot, template: "Hi"}
+`)}})
+	if err != nil {
+		t.Fatalf("LoadLibrary() error = %v", err)
+	}
+
+	var names []string
+	for _, technique := range lib.Techniques() {
+		names = append(names, technique.Info().Name)
+	}
+	if len(names) != 3 || names[0] != "zero-shot" || names[1] != "test-shout" || names[2] != "role-play" {
+		t.Errorf("Techniques() = %v, want [zero-shot test-shout role-play]", names)
+	}
+
+	fake := bedrocktest.NewFake().Default("ok")
+	RunTechnique(context.Background(), fake, lib, shout)
+	if calls := fake.Calls(); len(calls) != 1 || calls[0].Prompt != "HELLO" || calls[0].Params.Temperature != 0.9 {
+		t.Errorf("RunTechnique() calls = %+v, want HELLO at temperature 0.9", calls)
+	}
+}
+
+func TestRegisterDuplicatePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Register() of a taken name did not panic")
+		}
+	}()
+
+	Register(NewPromptTechnique(TechniqueInfo{Name: TechniqueZeroShot}, nil))
+}
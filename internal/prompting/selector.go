@@ -0,0 +1,241 @@
+package prompting
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+)
+
+// ExampleSelector picks the examples of a pool that are most relevant to a query,
+// most relevant first
+type ExampleSelector interface {
+	Select(ctx context.Context, query string, k int) ([]Example, error)
+}
+
+// BM25 ranking parameters: term frequency saturation and document length normalization
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// embedConcurrency bounds the embedding calls made while indexing a pool
+const embedConcurrency = 8
+
+type posting struct {
+	example   int
+	frequency int
+}
+
+// BM25Selector ranks examples by the BM25 score of their input against the query. It runs
+// entirely offline; the inverted index only scores examples that share a word with the query,
+// so pools of thousands stay fast.
+type BM25Selector struct {
+	pool      []Example
+	postings  map[string][]posting
+	lengths   []int
+	avgLength float64
+}
+
+// NewBM25Selector indexes the inputs of pool
+func NewBM25Selector(pool []Example) *BM25Selector {
+	s := &BM25Selector{pool: pool, postings: map[string][]posting{}, lengths: make([]int, len(pool))}
+
+	total := 0
+	for i, example := range pool {
+		terms := tokenize(example.Input)
+		s.lengths[i] = len(terms)
+		total += len(terms)
+
+		frequencies := map[string]int{}
+		for _, term := range terms {
+			frequencies[term]++
+		}
+		for term, frequency := range frequencies {
+			s.postings[term] = append(s.postings[term], posting{example: i, frequency: frequency})
+		}
+	}
+	if len(pool) > 0 {
+		s.avgLength = float64(total) / float64(len(pool))
+	}
+
+	return s
+}
+
+// Select returns the k examples with the highest BM25 score. When fewer than k examples share
+// a word with the query, the rest are filled from the pool in order so the prompt still has k demonstrations.
+func (s *BM25Selector) Select(ctx context.Context, query string, k int) ([]Example, error) {
+	scores := map[int]float64{}
+	n := float64(len(s.pool))
+
+	seen := map[string]bool{}
+	for _, term := range tokenize(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		postings := s.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for _, p := range postings {
+			tf := float64(p.frequency)
+			norm := 1 - bm25B + bm25B*float64(s.lengths[p.example])/s.avgLength
+			scores[p.example] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+		}
+	}
+
+	return topK(s.pool, scores, k), ctx.Err()
+}
+
+// EmbeddingSelector ranks examples by the cosine similarity of their input's embedding to the
+// query's. The pool is embedded once when the selector is created; each Select embeds only the query.
+type EmbeddingSelector struct {
+	embedder bedrock.Embedder
+	modelID  string
+	pool     []Example
+	vectors  [][]float64
+}
+
+// NewEmbeddingSelector embeds the inputs of pool with modelID, a few calls at a time
+func NewEmbeddingSelector(ctx context.Context, embedder bedrock.Embedder, modelID string, pool []Example) (*EmbeddingSelector, error) {
+	s := &EmbeddingSelector{embedder: embedder, modelID: modelID, pool: pool, vectors: make([][]float64, len(pool))}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		slots    = make(chan struct{}, embedConcurrency)
+	)
+	for i, example := range pool {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			vector, err := embedder.Embed(ctx, modelID, example.Input)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to embed example %d: %w", i+1, err)
+				}
+				mu.Unlock()
+				cancel()
+				return
+			}
+			s.vectors[i] = normalize(vector)
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Select returns the k examples whose inputs are closest to the query
+func (s *EmbeddingSelector) Select(ctx context.Context, query string, k int) ([]Example, error) {
+	vector, err := s.embedder.Embed(ctx, s.modelID, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	vector = normalize(vector)
+
+	scores := make(map[int]float64, len(s.pool))
+	for i, example := range s.vectors {
+		if len(example) != len(vector) {
+			return nil, errors.New("query and example embeddings have different dimensions")
+		}
+
+		var dot float64
+		for j := range vector {
+			dot += vector[j] * example[j]
+		}
+		scores[i] = dot
+	}
+
+	return topK(s.pool, scores, k), nil
+}
+
+// topK returns the k scored examples with the highest score, breaking ties by pool order,
+// followed by unscored examples when fewer than k were scored
+func topK(pool []Example, scores map[int]float64, k int) []Example {
+	k = min(k, len(pool))
+	if k <= 0 {
+		return nil
+	}
+
+	ranked := make([]int, 0, len(scores))
+	for i := range scores {
+		ranked = append(ranked, i)
+	}
+	slices.SortFunc(ranked, func(a, b int) int {
+		if c := cmp.Compare(scores[b], scores[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+
+	for i := 0; len(ranked) < k && i < len(pool); i++ {
+		if _, ok := scores[i]; !ok {
+			ranked = append(ranked, i)
+		}
+	}
+
+	examples := make([]Example, k)
+	for i, index := range ranked[:k] {
+		examples[i] = pool[index]
+	}
+	return examples
+}
+
+// tokenize lowercases text and splits it into words of letters and digits
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// normalize scales vector to unit length so a dot product gives the cosine similarity
+func normalize(vector []float64) []float64 {
+	var sum float64
+	for _, v := range vector {
+		sum += v * v
+	}
+	if sum == 0 {
+		return vector
+	}
+
+	norm := math.Sqrt(sum)
+	normalized := make([]float64, len(vector))
+	for i, v := range vector {
+		normalized[i] = v / norm
+	}
+	return normalized
+}
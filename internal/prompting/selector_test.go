@@ -0,0 +1,140 @@
+package prompting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+var emailPool = []Example{
+	{Input: "My invoice shows a double charge for March.", Output: "Billing"},
+	{Input: "The app crashes when I upload a photo.", Output: "Technical Support"},
+	{Input: "Can you add a dark mode to the dashboard?", Output: "Feature Request"},
+	{Input: "I was charged twice, please refund the second charge.", Output: "Billing"},
+	{Input: "Login fails with an error after the update.", Output: "Technical Support"},
+}
+
+func inputs(examples []Example) []string {
+	var texts []string
+	for _, example := range examples {
+		texts = append(texts, example.Input)
+	}
+	return texts
+}
+
+func TestBM25SelectorRanksByRelevance(t *testing.T) {
+	selector := NewBM25Selector(emailPool)
+
+	got, err := selector.Select(context.Background(), "Double charge on my invoice", 2)
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	if want := []Example{emailPool[0], emailPool[3]}; !slices.Equal(got, want) {
+		t.Errorf("Select() = %q, want %q", inputs(got), inputs(want))
+	}
+}
+
+func TestBM25SelectorFillsFromPool(t *testing.T) {
+	selector := NewBM25Selector(emailPool)
+
+	got, err := selector.Select(context.Background(), "dark mode", 3)
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	// Only one example matches; the rest come from the pool in order
+	if want := []Example{emailPool[2], emailPool[0], emailPool[1]}; !slices.Equal(got, want) {
+		t.Errorf("Select() = %q, want %q", inputs(got), inputs(want))
+	}
+
+	got, _ = selector.Select(context.Background(), "anything", 50)
+	if len(got) != len(emailPool) {
+		t.Errorf("Select(k > pool) returned %d examples, want %d", len(got), len(emailPool))
+	}
+}
+
+func TestBM25SelectorLargePool(t *testing.T) {
+	pool := make([]Example, 5000)
+	for i := range pool {
+		pool[i] = Example{Input: fmt.Sprintf("ticket %d about order number %d", i, i*7), Output: "Other"}
+	}
+	pool[4321] = Example{Input: "refund for a damaged blender", Output: "Billing"}
+
+	got, err := NewBM25Selector(pool).Select(context.Background(), "my blender arrived damaged", 1)
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	if got[0] != pool[4321] {
+		t.Errorf("Select() = %q, want the blender example", inputs(got))
+	}
+}
+
+// wordEmbedder embeds text as counts of a fixed vocabulary, standing in for a Bedrock model
+type wordEmbedder struct {
+	calls atomic.Int32
+	err   error
+}
+
+var embedderVocabulary = []string{"charge", "charged", "refund", "crash", "crashes", "login", "error", "dark", "mode"}
+
+func (e *wordEmbedder) Embed(ctx context.Context, modelID, text string) ([]float64, error) {
+	e.calls.Add(1)
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	vector := make([]float64, len(embedderVocabulary))
+	for _, word := range tokenize(text) {
+		if i := slices.Index(embedderVocabulary, word); i >= 0 {
+			vector[i]++
+		}
+	}
+	return vector, nil
+}
+
+func TestEmbeddingSelector(t *testing.T) {
+	embedder := &wordEmbedder{}
+	selector, err := NewEmbeddingSelector(context.Background(), embedder, "amazon.titan-embed-text-v2:0", emailPool)
+	if err != nil {
+		t.Fatalf("NewEmbeddingSelector() error = %v", err)
+	}
+	if got := embedder.calls.Load(); got != int32(len(emailPool)) {
+		t.Errorf("indexing made %d embedding calls, want %d", got, len(emailPool))
+	}
+
+	got, err := selector.Select(context.Background(), "Login error after it crashes", 2)
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	if want := []Example{emailPool[4], emailPool[1]}; !slices.Equal(got, want) {
+		t.Errorf("Select() = %q, want %q", inputs(got), inputs(want))
+	}
+}
+
+func TestEmbeddingSelectorError(t *testing.T) {
+	embedder := &wordEmbedder{err: errors.New("throttled")}
+
+	_, err := NewEmbeddingSelector(context.Background(), embedder, "amazon.titan-embed-text-v2:0", emailPool)
+	if err == nil || !strings.Contains(err.Error(), "failed to embed example") {
+		t.Errorf("NewEmbeddingSelector() error = %v, want an embedding failure", err)
+	}
+}
+
+func TestFewShotBuilderWithSelector(t *testing.T) {
+	builder := NewFewShotBuilder("Classify the email.", nil, "Category").
+		WithSelector(NewBM25Selector(emailPool), 2)
+
+	prompt, err := builder.Build("The login page shows an error")
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if !strings.Contains(prompt, emailPool[4].Input) || strings.Contains(prompt, emailPool[2].Input) {
+		t.Errorf("prompt does not hold the selected examples:\n%s", prompt)
+	}
+	if got := strings.Count(prompt, "Category: "); got != 2 {
+		t.Errorf("prompt has %d examples, want 2:\n%s", got, prompt)
+	}
+}
@@ -0,0 +1,236 @@
+package prompting
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+)
+
+// AnswerExtractor pulls the final answer out of a reasoning chain in a normalized form, so that
+// chains reaching the same answer in different words vote together. It reports false when the
+// chain has no answer.
+type AnswerExtractor func(completion string) (string, bool)
+
+// answerExtractors are the extractors library prompts can name in their answer field
+var answerExtractors = map[string]AnswerExtractor{
+	"dollar": DollarAnswer,
+	"number": NumberAnswer,
+	"yes-no": YesNoAnswer,
+	"final":  FinalAnswer,
+}
+
+var (
+	dollarPattern       = regexp.MustCompile(`\$\s?(\d[\d,]*(?:\.\d+)?)`)
+	numberPattern       = regexp.MustCompile(`-?\d[\d,]*(?:\.\d+)?`)
+	yesNoPattern        = regexp.MustCompile(`(?i)\b(yes|no)\b`)
+	answerLinePattern   = regexp.MustCompile(`(?im)^\W*(?:final answer|answer|conclusion|therefore)\b\W*(.+)$`)
+	trailingPunctuation = ".!;:,\"'"
+)
+
+// AnswerExtractorFor returns the extractor registered under name: "dollar", "number", "yes-no" or "final"
+func AnswerExtractorFor(name string) (AnswerExtractor, error) {
+	extract, ok := answerExtractors[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown answer extractor %q", name)
+	}
+	return extract, nil
+}
+
+// DollarAnswer returns the last dollar amount in the chain, e.g. "$42" for "... leaves $42.00."
+func DollarAnswer(completion string) (string, bool) {
+	matches := dollarPattern.FindAllStringSubmatch(completion, -1)
+	if len(matches) == 0 {
+		return "", false
+	}
+	amount, ok := normalizeNumber(matches[len(matches)-1][1])
+	return "$" + amount, ok
+}
+
+// NumberAnswer returns the last number in the chain
+func NumberAnswer(completion string) (string, bool) {
+	matches := numberPattern.FindAllString(completion, -1)
+	if len(matches) == 0 {
+		return "", false
+	}
+	return normalizeNumber(matches[len(matches)-1])
+}
+
+// YesNoAnswer returns "yes" or "no" from the chain's conclusion, or its last yes or no otherwise
+func YesNoAnswer(completion string) (string, bool) {
+	text := completion
+	if line, ok := answerLine(completion); ok {
+		text = line
+	}
+
+	matches := yesNoPattern.FindAllString(text, -1)
+	if len(matches) == 0 {
+		return "", false
+	}
+	if text != completion {
+		return strings.ToLower(matches[0]), true
+	}
+	return strings.ToLower(matches[len(matches)-1]), true
+}
+
+// FinalAnswer returns the text after the last "Answer:", "Final answer:", "Conclusion:" or
+// "Therefore" line, or the last line of the chain, lowercased and without trailing punctuation
+func FinalAnswer(completion string) (string, bool) {
+	line, ok := answerLine(completion)
+	if !ok {
+		lines := strings.Split(strings.TrimSpace(completion), "\n")
+		line = lines[len(lines)-1]
+	}
+
+	answer := strings.ToLower(strings.TrimRight(strings.TrimSpace(line), trailingPunctuation))
+	return answer, answer != ""
+}
+
+func answerLine(completion string) (string, bool) {
+	matches := answerLinePattern.FindAllStringSubmatch(completion, -1)
+	if len(matches) == 0 {
+		return "", false
+	}
+	return matches[len(matches)-1][1], true
+}
+
+// normalizeNumber drops thousands separators and insignificant decimals, so "1,200.00" is "1200"
+func normalizeNumber(text string) (string, bool) {
+	value, err := strconv.ParseFloat(strings.ReplaceAll(text, ",", ""), 64)
+	if err != nil {
+		return "", false
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64), true
+}
+
+// SelfConsistency samples several reasoning chains for the same prompt and majority-votes
+// on their final answers
+type SelfConsistency struct {
+	Samples     int             // number of chains to sample
+	Concurrency int             // chains sampled at once; zero samples all of them at once
+	Temperature float64         // sampling temperature, higher than usual so the chains differ; zero keeps the prompt's
+	Extract     AnswerExtractor // pulls the answer out of each chain; nil uses FinalAnswer, or the library prompt's answer field
+}
+
+// DefaultSelfConsistency returns 5 chains sampled at once at temperature 0.7
+func DefaultSelfConsistency() SelfConsistency {
+	return SelfConsistency{Samples: 5, Temperature: 0.7}
+}
+
+// Chain is one sampled reasoning path
+type Chain struct {
+	Sample   int                    // 1-based sample number
+	Response *bedrock.ModelResponse // nil when the call failed
+	Answer   string                 // extracted answer; empty when none was found
+	Err      error                  // call failure
+}
+
+// Vote is the number of chains that reached an answer
+type Vote struct {
+	Answer string
+	Count  int
+}
+
+// ConsistencyResult is the outcome of a self-consistency run
+type ConsistencyResult struct {
+	Prompt     string
+	Answer     string  // majority answer
+	Agreement  float64 // share of completed chains that reached the majority answer
+	Votes      []Vote  // answers by descending count
+	Chains     []Chain // every sample, in sample order
+	Dissenting []Chain // completed chains that did not reach the majority answer
+}
+
+// Run samples s.Samples chains of prompt concurrently and votes on their answers. Failed calls
+// are reported in Chains and left out of the vote; Run only fails when no chain completes.
+func (s SelfConsistency) Run(ctx context.Context, client bedrock.Invoker, prompt string, params bedrock.ModelParams) (*ConsistencyResult, error) {
+	if s.Samples < 1 {
+		return nil, fmt.Errorf("self-consistency needs at least 1 sample, got %d", s.Samples)
+	}
+	extract := s.Extract
+	if extract == nil {
+		extract = FinalAnswer
+	}
+	if s.Temperature > 0 {
+		params.Temperature = s.Temperature
+	}
+	concurrency := s.Concurrency
+	if concurrency <= 0 {
+		concurrency = s.Samples
+	}
+
+	chains := make([]Chain, s.Samples)
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range chains {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			chain := Chain{Sample: i + 1}
+			chain.Response, chain.Err = client.InvokeModel(ctx, prompt, params)
+			if chain.Err == nil {
+				chain.Answer, _ = extract(chain.Response.Completion)
+			}
+			chains[i] = chain
+		}()
+	}
+	wg.Wait()
+
+	return vote(prompt, chains)
+}
+
+// vote tallies the completed chains. Ties go to the answer reached by the earliest sample.
+func vote(prompt string, chains []Chain) (*ConsistencyResult, error) {
+	result := &ConsistencyResult{Prompt: prompt, Chains: chains}
+
+	completed := 0
+	counts := map[string]int{}
+	var firstErr error
+	for _, chain := range chains {
+		if chain.Err != nil {
+			if firstErr == nil {
+				firstErr = chain.Err
+			}
+			continue
+		}
+
+		completed++
+		if chain.Answer == "" {
+			continue
+		}
+		if counts[chain.Answer] == 0 {
+			result.Votes = append(result.Votes, Vote{Answer: chain.Answer})
+		}
+		counts[chain.Answer]++
+	}
+	if completed == 0 {
+		return nil, fmt.Errorf("failed to sample any reasoning chain: %w", firstErr)
+	}
+
+	for i := range result.Votes {
+		result.Votes[i].Count = counts[result.Votes[i].Answer]
+	}
+	// A stable sort keeps first-reached order among equal counts
+	slices.SortStableFunc(result.Votes, func(a, b Vote) int { return cmp.Compare(b.Count, a.Count) })
+
+	if len(result.Votes) > 0 {
+		result.Answer = result.Votes[0].Answer
+		result.Agreement = float64(result.Votes[0].Count) / float64(completed)
+	}
+	for _, chain := range chains {
+		if chain.Err == nil && chain.Answer != result.Answer {
+			result.Dissenting = append(result.Dissenting, chain)
+		}
+	}
+
+	return result, nil
+}
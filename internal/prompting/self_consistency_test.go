@@ -0,0 +1,102 @@
+package prompting
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+	"aws-bedrock-prompt-engineering/internal/bedrock/bedrocktest"
+)
+
+func TestAnswerExtractors(t *testing.T) {
+	tests := []struct {
+		name       string
+		extract    AnswerExtractor
+		completion string
+		want       string
+		wantOK     bool
+	}{
+		{"dollar takes the last amount", DollarAnswer, "Food costs $108. $150 - $108 = $42.00 left.", "$42", true},
+		{"dollar drops separators", DollarAnswer, "Total: $1,200.50", "$1200.5", true},
+		{"dollar without amount", DollarAnswer, "He has 42 left", "", false},
+		{"number", NumberAnswer, "3 pizzas and 24 drinks, so 7.0", "7", true},
+		{"yes-no from conclusion", YesNoAnswer, "No premise rules it out.\nConclusion: Yes, she can.", "yes", true},
+		{"yes-no without conclusion", YesNoAnswer, "Is it possible? No.", "no", true},
+		{"final from answer line", FinalAnswer, "Step 1...\nFinal answer: Option B.", "option b", true},
+		{"final falls back to last line", FinalAnswer, "Step 1...\nOption B!", "option b", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.extract(tt.completion)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("extract() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSelfConsistencyVote(t *testing.T) {
+	fake := bedrocktest.NewFake().RespondSequence(`.*`,
+		"So he has $42 left.",
+		"So he has $48 left.",
+		"He will have $42.00 left.",
+		"I am not sure.",
+		"Remaining: $42",
+	)
+	sc := SelfConsistency{Samples: 5, Temperature: 0.9, Extract: DollarAnswer}
+
+	result, err := sc.Run(context.Background(), fake, "problem", bedrock.ModelParams{Temperature: 0.4})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if result.Answer != "$42" {
+		t.Errorf("Answer = %q, want $42", result.Answer)
+	}
+	if result.Agreement != 0.6 {
+		t.Errorf("Agreement = %v, want 0.6", result.Agreement)
+	}
+	if len(result.Votes) != 2 || result.Votes[1] != (Vote{Answer: "$48", Count: 1}) {
+		t.Errorf("Votes = %+v, want $42 then $48", result.Votes)
+	}
+	if len(result.Dissenting) != 2 {
+		t.Errorf("Dissenting = %d chains, want 2 (the $48 chain and the one without an answer)", len(result.Dissenting))
+	}
+
+	for _, call := range fake.Calls() {
+		if call.Params.Temperature != 0.9 {
+			t.Errorf("sample Temperature = %v, want 0.9", call.Params.Temperature)
+		}
+	}
+}
+
+func TestSelfConsistencyFailedSamples(t *testing.T) {
+	fake := bedrocktest.NewFake().Fail(`.*`, errors.New("throttled"))
+
+	_, err := SelfConsistency{Samples: 3}.Run(context.Background(), fake, "problem", bedrock.ModelParams{})
+	if err == nil || !strings.Contains(err.Error(), "throttled") {
+		t.Errorf("Run() error = %v, want the sample failure", err)
+	}
+}
+
+func TestChainOfThoughtExecuteSelfConsistent(t *testing.T) {
+	t.Setenv("MODEL_ID", testModelID)
+
+	fake := bedrocktest.NewFake().RespondSequence(`.*`, "Tom has $42 left.", "Tom has $42 left.", "Tom has $30 left.")
+	c := NewChainOfThoughtPrompt(fake)
+
+	result, err := c.ExecuteSelfConsistent(context.Background(), "math-problem-solving", SelfConsistency{Samples: 3, Concurrency: 1})
+	if err != nil {
+		t.Fatalf("ExecuteSelfConsistent() error = %v", err)
+	}
+	// The library prompt's answer field selects DollarAnswer
+	if result.Answer != "$42" || len(result.Dissenting) != 1 {
+		t.Errorf("Answer = %q with %d dissenting, want $42 with 1", result.Answer, len(result.Dissenting))
+	}
+	if got := len(fake.Calls()); got != 3 {
+		t.Errorf("made %d calls, want 3", got)
+	}
+}
@@ -0,0 +1,174 @@
+package prompting
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+)
+
+// Built-in technique names used by the prompt library
+const (
+	TechniqueZeroShot       = "zero-shot"
+	TechniqueFewShot        = "few-shot"
+	TechniqueChainOfThought = "chain-of-thought"
+	TechniqueTreeOfThoughts = "tree-of-thoughts"
+	TechniqueReAct          = "react"
+)
+
+// TechniqueInfo describes a technique in menus and reports
+type TechniqueInfo struct {
+	Name        string // identifier used by the prompt library, e.g. "few-shot"
+	Title       string // display name, e.g. "Few-Shot"
+	Emoji       string // menu icon
+	Description string // one-line summary
+}
+
+// Result is the outcome of running one library prompt with a technique
+type Result struct {
+	Prompt   string                 // the rendered prompt, as first sent to the model
+	Response *bedrock.ModelResponse // the technique's final answer
+	Steps    []string               // intermediate thoughts of multi-step techniques, in order
+	Output   any                    // the response parsed per the prompt's output block, if it has one
+}
+
+// Technique is a prompting strategy. Register a Technique to make it available to the CLI menu
+// and anything else that lists techniques; prompts pick it through their technique field.
+type Technique interface {
+	Info() TechniqueInfo
+	// DefaultParams returns the ModelParams the technique starts from, before per-prompt overrides
+	DefaultParams() bedrock.ModelParams
+	// Execute renders spec with values and runs it against client. Invalid values must be
+	// reported before anything is sent to the model.
+	Execute(ctx context.Context, client bedrock.Invoker, spec *PromptSpec, params bedrock.ModelParams, values map[string]string) (*Result, error)
+}
+
+// PromptTechnique is a technique that sends each prompt as a single call; the technique
+// itself is in how the library prompt is written, e.g. with examples or worked reasoning
+type PromptTechnique struct {
+	info TechniqueInfo
+	tune func(params *bedrock.ModelParams)
+}
+
+// NewPromptTechnique creates a single-call technique. tune adjusts bedrock.GetDefaultClaudeParams
+// into the technique's defaults and may be nil.
+func NewPromptTechnique(info TechniqueInfo, tune func(params *bedrock.ModelParams)) *PromptTechnique {
+	return &PromptTechnique{info: info, tune: tune}
+}
+
+func (t *PromptTechnique) Info() TechniqueInfo {
+	return t.info
+}
+
+func (t *PromptTechnique) DefaultParams() bedrock.ModelParams {
+	params := bedrock.GetDefaultClaudeParams()
+	if t.tune != nil {
+		t.tune(&params)
+	}
+	return params
+}
+
+func (t *PromptTechnique) Execute(ctx context.Context, client bedrock.Invoker, spec *PromptSpec, params bedrock.ModelParams, values map[string]string) (*Result, error) {
+	prompt, err := spec.Render(values)
+	if err != nil {
+		return nil, err
+	}
+
+	params = spec.Params.Apply(params)
+
+	parser := spec.OutputParser()
+	if parser == nil {
+		response, err := client.InvokeModel(ctx, prompt, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute %s: %w", strings.ToLower(spec.Title), err)
+		}
+		return &Result{Prompt: prompt, Response: response}, nil
+	}
+
+	parsed, err := InvokeParsed(ctx, client, prompt, params, parser, defaultReasks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute %s: %w", strings.ToLower(spec.Title), err)
+	}
+
+	result := &Result{Prompt: prompt, Response: parsed.Response, Output: parsed.Value}
+	for _, reason := range parsed.Reasks {
+		result.Steps = append(result.Steps, "re-asked because "+reason)
+	}
+	return result, nil
+}
+
+// ExecuteSpec runs spec with its technique, params and values and prints the prompt and response.
+// Invalid or missing values are reported before anything is sent to the model.
+func ExecuteSpec(ctx context.Context, client bedrock.Invoker, spec *PromptSpec, params bedrock.ModelParams, values map[string]string) error {
+	info := TechniqueFor(spec.Technique).Info()
+
+	result, err := TechniqueFor(spec.Technique).Execute(ctx, client, spec, params, values)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s %s Prompting: %s\n", info.Emoji, info.Title, spec.Title)
+	fmt.Println("Prompt:", result.Prompt)
+	fmt.Println(strings.Repeat("-", 80))
+	for i, step := range result.Steps {
+		fmt.Printf("Step %d: %s\n", i+1, step)
+	}
+	fmt.Printf("Response: %s\n", result.Response.Completion)
+	if result.Output != nil {
+		fmt.Printf("Parsed: %s\n", formatOutput(result.Output))
+	}
+	if spec.Expected != "" {
+		fmt.Printf("Expected: %s\n", spec.Expected)
+	}
+	fmt.Println()
+	return nil
+}
+
+// RunTechnique executes every library prompt of a technique with its sample inputs, logging
+// failures and stopping early once ctx is cancelled
+func RunTechnique(ctx context.Context, client bedrock.Invoker, lib *Library, technique Technique) {
+	info := technique.Info()
+	fmt.Printf("=== %s PROMPTING EXAMPLES ===\n\n", strings.ToUpper(info.Title))
+
+	params := technique.DefaultParams()
+	for _, spec := range lib.ByTechnique(info.Name) {
+		if err := ExecuteSpec(ctx, client, spec, params, spec.Inputs); err != nil {
+			log.Printf("Error in %s: %v", spec.Title, err)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// exampleRunner is the shared implementation of ZeroShotPrompt, FewShotPrompt and ChainOfThoughtPrompt:
+// it runs the technique's prompts from the default library by name
+type exampleRunner struct {
+	client    bedrock.Invoker
+	technique Technique
+	params    bedrock.ModelParams
+}
+
+func newExampleRunner(client bedrock.Invoker, technique Technique) exampleRunner {
+	return exampleRunner{
+		client:    client,
+		technique: technique,
+		params:    technique.DefaultParams(),
+	}
+}
+
+// execute runs a prompt from the default library by name with its sample inputs
+func (r *exampleRunner) execute(ctx context.Context, name string) error {
+	spec, ok := DefaultLibrary().Get(name)
+	if !ok {
+		return fmt.Errorf("prompt %q is not in the prompt library", name)
+	}
+	return ExecuteSpec(ctx, r.client, spec, r.params, spec.Inputs)
+}
+
+// RunAllExamples executes every library prompt of the technique, stopping early once ctx is cancelled
+func (r *exampleRunner) RunAllExamples(ctx context.Context) {
+	RunTechnique(ctx, r.client, DefaultLibrary(), r.technique)
+}
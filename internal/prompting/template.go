@@ -0,0 +1,259 @@
+package prompting
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// VariableType constrains the values a template variable accepts
+type VariableType string
+
+const (
+	TypeString VariableType = "string" // single line; newlines in input are folded into spaces
+	TypeText   VariableType = "text"   // free text that may span several lines
+	TypeInt    VariableType = "int"
+	TypeNumber VariableType = "number"
+	TypeBool   VariableType = "bool"
+	TypeEnum   VariableType = "enum" // one of Values
+)
+
+// Variable declares a named placeholder of a Template
+type Variable struct {
+	Name        string       `yaml:"name"`
+	Type        VariableType `yaml:"type"` // defaults to TypeString
+	Description string       `yaml:"description"`
+	Required    bool         `yaml:"required"`
+	Default     string       `yaml:"default"` // used when an optional variable has no value
+	Values      []string     `yaml:"values"`  // allowed values of an enum
+	Raw         bool         `yaml:"raw"`     // insert values as-is instead of escaping them
+}
+
+// ValidationError lists every problem found with the values given to a template
+type ValidationError struct {
+	Template string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("template %q: %s", e.Template, strings.Join(e.Problems, "; "))
+}
+
+// Template is a prompt with named {{placeholders}}. Every placeholder must be declared as a
+// Variable, so a template that renders is guaranteed to have no unfilled gaps.
+type Template struct {
+	name      string
+	text      string
+	variables []Variable
+	byName    map[string]Variable
+}
+
+var (
+	placeholderPattern  = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+	variableNamePattern = regexp.MustCompile(`^\w+$`)
+)
+
+// NewTemplate parses text and checks it against the declared variables: every placeholder
+// must be declared, every variable used, and defaults must be valid for their type.
+func NewTemplate(name, text string, variables ...Variable) (*Template, error) {
+	t := &Template{name: name, text: text, byName: map[string]Variable{}}
+
+	var problems []string
+	for _, v := range variables {
+		if v.Type == "" {
+			v.Type = TypeString
+		}
+
+		switch {
+		case !variableNamePattern.MatchString(v.Name):
+			problems = append(problems, fmt.Sprintf("invalid variable name %q", v.Name))
+			continue
+		case t.byName[v.Name].Name != "":
+			problems = append(problems, fmt.Sprintf("variable %q is declared twice", v.Name))
+			continue
+		case v.Required && v.Default != "":
+			problems = append(problems, fmt.Sprintf("variable %q is required but has a default", v.Name))
+		case v.Type == TypeEnum && len(v.Values) == 0:
+			problems = append(problems, fmt.Sprintf("enum variable %q has no values", v.Name))
+		}
+		if v.Default != "" {
+			if err := v.check(v.Default); err != nil {
+				problems = append(problems, fmt.Sprintf("default of %s", err))
+			}
+		}
+
+		t.variables = append(t.variables, v)
+		t.byName[v.Name] = v
+	}
+
+	used := map[string]bool{}
+	for _, match := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+		name := match[1]
+		if _, ok := t.byName[name]; !ok && !used[name] {
+			problems = append(problems, fmt.Sprintf("placeholder {{%s}} is not declared", name))
+		}
+		used[name] = true
+	}
+	for _, v := range t.variables {
+		if !used[v.Name] {
+			problems = append(problems, fmt.Sprintf("variable %q is not used in the template", v.Name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, &ValidationError{Template: name, Problems: problems}
+	}
+	return t, nil
+}
+
+// MustTemplate is NewTemplate for templates written in Go source; it panics if the template is invalid
+func MustTemplate(name, text string, variables ...Variable) *Template {
+	t, err := NewTemplate(name, text, variables...)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Name returns the template name used in error messages
+func (t *Template) Name() string {
+	return t.name
+}
+
+// Variables returns the declared variables in declaration order
+func (t *Template) Variables() []Variable {
+	return t.variables
+}
+
+// Validate checks values without rendering: unknown names, missing required variables
+// and values that do not match their type are all reported together
+func (t *Template) Validate(values map[string]string) error {
+	var problems []string
+
+	var unknown []string
+	for name := range values {
+		if _, ok := t.byName[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	slices.Sort(unknown)
+	for _, name := range unknown {
+		problems = append(problems, fmt.Sprintf("unknown variable %q", name))
+	}
+
+	for _, v := range t.variables {
+		value, ok := values[v.Name]
+		if !ok || strings.TrimSpace(value) == "" {
+			if v.Required {
+				problems = append(problems, fmt.Sprintf("missing required variable %q", v.Name))
+			}
+			continue
+		}
+		if err := v.check(value); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Template: t.name, Problems: problems}
+	}
+	return nil
+}
+
+// Render validates values and fills every placeholder. Values are escaped with EscapeInput
+// unless the variable is Raw; defaults come from the template author and are used verbatim.
+func (t *Template) Render(values map[string]string) (string, error) {
+	if err := t.Validate(values); err != nil {
+		return "", err
+	}
+
+	// One pass over the template, so placeholders inside values are never expanded
+	return placeholderPattern.ReplaceAllStringFunc(t.text, func(placeholder string) string {
+		v := t.byName[placeholderPattern.FindStringSubmatch(placeholder)[1]]
+
+		value, ok := values[v.Name]
+		if !ok || strings.TrimSpace(value) == "" {
+			return v.Default
+		}
+		if v.Raw {
+			return value
+		}
+		if v.Type == TypeString {
+			value = strings.Join(strings.Fields(value), " ")
+		}
+		return EscapeInput(value)
+	}), nil
+}
+
+// check reports whether value is valid for the variable's type
+func (v Variable) check(value string) error {
+	value = strings.TrimSpace(value)
+
+	var err error
+	switch v.Type {
+	case TypeInt:
+		_, err = strconv.Atoi(value)
+	case TypeNumber:
+		_, err = strconv.ParseFloat(value, 64)
+	case TypeBool:
+		_, err = strconv.ParseBool(value)
+	case TypeEnum:
+		if !slices.Contains(v.Values, value) {
+			err = fmt.Errorf("want one of %s", strings.Join(v.Values, ", "))
+		}
+	case TypeString, TypeText:
+	default:
+		return fmt.Errorf("variable %q has unknown type %q", v.Name, v.Type)
+	}
+
+	if err != nil {
+		var numErr *strconv.NumError
+		if errors.As(err, &numErr) {
+			err = numErr.Err
+		}
+		return fmt.Errorf("variable %q: %q is not a valid %s (%v)", v.Name, value, v.Type, err)
+	}
+	return nil
+}
+
+// turnMarkers are sequences model families use to delimit conversation turns or instructions.
+// Left in user input they would let the input end the prompt early and start a turn of its own.
+var turnMarkers = strings.NewReplacer(
+	"\n\nHuman:", "\n\nHuman -",
+	"\n\nAssistant:", "\n\nAssistant -",
+	"[INST]", "",
+	"[/INST]", "",
+	"<<SYS>>", "",
+	"<</SYS>>", "",
+	"<s>", "",
+	"</s>", "",
+	"<|begin_of_text|>", "",
+	"<|start_header_id|>", "",
+	"<|end_header_id|>", "",
+	"<|eot_id|>", "",
+)
+
+// EscapeInput makes untrusted text safe to place inside a prompt: it drops control characters
+// other than newlines and tabs, and neutralizes the turn and instruction markers of the supported
+// model families
+func EscapeInput(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 && r != '\n' && r != '\t' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+
+	// Dropping a marker can join the text around it into another one, as in "[IN[INST]ST]",
+	// so replace until nothing changes. Every pass removes text or a colon, so this ends.
+	for {
+		escaped := turnMarkers.Replace(s)
+		if escaped == s {
+			return s
+		}
+		s = escaped
+	}
+}
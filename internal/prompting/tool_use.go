@@ -0,0 +1,163 @@
+package prompting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+	"aws-bedrock-prompt-engineering/internal/tools"
+)
+
+// ErrToolRounds is returned when the model keeps calling tools past the round limit
+var ErrToolRounds = errors.New("model kept calling tools past the round limit")
+
+// maxToolRounds bounds the tool calls made for a single user turn
+const maxToolRounds = 10
+
+// ToolHandler runs a tool call. The input is the JSON object the model sent; returned errors
+// are reported to the model as failed tool results.
+type ToolHandler func(ctx context.Context, input json.RawMessage) (string, error)
+
+// FunctionTool is a Go function the model can call through native tool use
+type FunctionTool struct {
+	Spec    bedrock.ToolSpec
+	Handler ToolHandler
+}
+
+// NewFunctionTool wraps fn as a tool whose input is described by schema. The model's input is
+// decoded into T, so T's JSON field names must match the schema's properties.
+func NewFunctionTool[T any](name, description string, schema map[string]any, fn func(ctx context.Context, input T) (string, error)) FunctionTool {
+	return FunctionTool{
+		Spec: bedrock.ToolSpec{Name: name, Description: description, InputSchema: schema},
+		Handler: func(ctx context.Context, raw json.RawMessage) (string, error) {
+			var input T
+			if err := json.Unmarshal(raw, &input); err != nil {
+				return "", fmt.Errorf("invalid input: %w", err)
+			}
+			return fn(ctx, input)
+		},
+	}
+}
+
+// textToolInput is the input of a tool adapted with FromTool
+type textToolInput struct {
+	Input string `json:"input"`
+}
+
+// FromTool exposes a text-input tool, such as those used by the ReAct agent, as a function tool
+// taking {"input": "..."}
+func FromTool(tool tools.Tool) FunctionTool {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"input": map[string]any{"type": "string", "description": "The input for the tool"},
+		},
+		"required": []string{"input"},
+	}
+
+	return NewFunctionTool(tool.Name(), tool.Description(), schema, func(ctx context.Context, input textToolInput) (string, error) {
+		return tool.Run(ctx, input.Input)
+	})
+}
+
+// Dispatcher maps the tool calls of a model response to Go functions
+type Dispatcher struct {
+	tools  []FunctionTool
+	byName map[string]FunctionTool
+}
+
+// NewDispatcher creates a dispatcher over tools, rejecting empty and duplicate names
+func NewDispatcher(tools ...FunctionTool) (*Dispatcher, error) {
+	d := &Dispatcher{byName: map[string]FunctionTool{}}
+	for _, tool := range tools {
+		name := tool.Spec.Name
+		switch {
+		case name == "":
+			return nil, errors.New("tool has no name")
+		case tool.Handler == nil:
+			return nil, fmt.Errorf("tool %q has no handler", name)
+		case d.byName[name].Handler != nil:
+			return nil, fmt.Errorf("tool %q is defined twice", name)
+		}
+
+		d.tools = append(d.tools, tool)
+		d.byName[name] = tool
+	}
+	return d, nil
+}
+
+// DefaultDispatcher offers DefaultTools to the model through native tool use
+func DefaultDispatcher() *Dispatcher {
+	var functions []FunctionTool
+	for _, tool := range DefaultTools().Tools() {
+		functions = append(functions, FromTool(tool))
+	}
+
+	d, err := NewDispatcher(functions...)
+	if err != nil {
+		panic(fmt.Sprintf("prompting: default dispatcher: %v", err))
+	}
+	return d
+}
+
+// Specs returns the tool definitions to send with a request
+func (d *Dispatcher) Specs() []bedrock.ToolSpec {
+	specs := make([]bedrock.ToolSpec, len(d.tools))
+	for i, tool := range d.tools {
+		specs[i] = tool.Spec
+	}
+	return specs
+}
+
+// Dispatch runs the tool a call names. Unknown tools, inputs missing required properties and
+// handler errors become error results, so the model can correct itself.
+func (d *Dispatcher) Dispatch(ctx context.Context, call bedrock.ToolCall) bedrock.ToolResult {
+	result := bedrock.ToolResult{ToolCallID: call.ID}
+
+	tool, ok := d.byName[call.Name]
+	if !ok {
+		result.Content, result.IsError = fmt.Sprintf("unknown tool %q", call.Name), true
+		return result
+	}
+	if missing := missingProperties(tool.Spec.InputSchema, call.Input); len(missing) > 0 {
+		result.Content, result.IsError = fmt.Sprintf("missing required input: %s", strings.Join(missing, ", ")), true
+		return result
+	}
+
+	output, err := tool.Handler(ctx, call.Input)
+	if err != nil {
+		result.Content, result.IsError = err.Error(), true
+		return result
+	}
+	result.Content = output
+	return result
+}
+
+// missingProperties returns the schema's required properties that input does not set
+func missingProperties(schema map[string]any, input json.RawMessage) []string {
+	var fields map[string]json.RawMessage
+	json.Unmarshal(input, &fields)
+
+	var required []string
+	switch names := schema["required"].(type) {
+	case []string:
+		required = names
+	case []any:
+		for _, name := range names {
+			if name, ok := name.(string); ok {
+				required = append(required, name)
+			}
+		}
+	}
+
+	var missing []string
+	for _, name := range required {
+		if _, ok := fields[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
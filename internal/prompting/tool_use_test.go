@@ -0,0 +1,159 @@
+package prompting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+	"aws-bedrock-prompt-engineering/internal/bedrock/bedrocktest"
+)
+
+type weatherInput struct {
+	City string `json:"city"`
+}
+
+var weatherSchema = map[string]any{
+	"type":       "object",
+	"properties": map[string]any{"city": map[string]any{"type": "string"}},
+	"required":   []string{"city"},
+}
+
+func weatherTool() FunctionTool {
+	return NewFunctionTool("get_weather", "Current weather for a city", weatherSchema, func(ctx context.Context, input weatherInput) (string, error) {
+		if input.City == "Atlantis" {
+			return "", errors.New("city not found")
+		}
+		return fmt.Sprintf("18°C and cloudy in %s", input.City), nil
+	})
+}
+
+func toolCall(id, name, input string) *bedrock.ModelResponse {
+	return &bedrock.ModelResponse{
+		StopReason: bedrock.StopReasonToolUse,
+		ToolCalls:  []bedrock.ToolCall{{ID: id, Name: name, Input: json.RawMessage(input)}},
+		Usage:      &bedrock.Usage{InputTokens: 10, OutputTokens: 5},
+	}
+}
+
+func TestDispatcherDispatch(t *testing.T) {
+	d, err := NewDispatcher(weatherTool(), FromTool(upperTool{}))
+	if err != nil {
+		t.Fatalf("NewDispatcher() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		call    bedrock.ToolCall
+		want    string
+		isError bool
+	}{
+		{"typed input", bedrock.ToolCall{Name: "get_weather", Input: json.RawMessage(`{"city":"Oslo"}`)}, "18°C and cloudy in Oslo", false},
+		{"adapted text tool", bedrock.ToolCall{Name: "upper", Input: json.RawMessage(`{"input":"shout"}`)}, "SHOUT", false},
+		{"handler error", bedrock.ToolCall{Name: "get_weather", Input: json.RawMessage(`{"city":"Atlantis"}`)}, "city not found", true},
+		{"missing required", bedrock.ToolCall{Name: "get_weather", Input: json.RawMessage(`{}`)}, "missing required input: city", true},
+		{"invalid input", bedrock.ToolCall{Name: "get_weather", Input: json.RawMessage(`{"city":7}`)}, "invalid input", true},
+		{"unknown tool", bedrock.ToolCall{Name: "launch", Input: json.RawMessage(`{}`)}, `unknown tool "launch"`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.call.ID = "call-1"
+			result := d.Dispatch(context.Background(), tt.call)
+			if result.ToolCallID != "call-1" || result.IsError != tt.isError || !strings.HasPrefix(result.Content, tt.want) {
+				t.Errorf("Dispatch() = %+v, want %q (error %t)", result, tt.want, tt.isError)
+			}
+		})
+	}
+
+	if _, err := NewDispatcher(weatherTool(), weatherTool()); err == nil {
+		t.Error("NewDispatcher() with a duplicate name should fail")
+	}
+}
+
+func TestConversationRunsTools(t *testing.T) {
+	d, err := NewDispatcher(weatherTool())
+	if err != nil {
+		t.Fatalf("NewDispatcher() error = %v", err)
+	}
+	fake := bedrocktest.NewFake().
+		RespondWith(`weather in Oslo`, toolCall("call-1", "get_weather", `{"city":"Oslo"}`)).
+		RespondWith(`cloudy in Oslo`, &bedrock.ModelResponse{Completion: "It is 18°C and cloudy.", StopReason: "end_turn", Usage: &bedrock.Usage{InputTokens: 20, OutputTokens: 8}})
+
+	var seen []bedrock.ToolResult
+	conversation := NewConversation(fake, bedrock.ModelParams{}).WithTools(d).OnToolCall(func(call bedrock.ToolCall, result bedrock.ToolResult) {
+		seen = append(seen, result)
+	})
+
+	response, err := conversation.Send(context.Background(), "What's the weather in Oslo?")
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if response.Completion != "It is 18°C and cloudy." {
+		t.Errorf("Completion = %q", response.Completion)
+	}
+	if response.Usage.InputTokens != 30 || response.Usage.OutputTokens != 13 {
+		t.Errorf("Usage = %+v, want the sum of both calls", response.Usage)
+	}
+	if len(seen) != 1 || seen[0].Content != "18°C and cloudy in Oslo" {
+		t.Errorf("OnToolCall saw %+v", seen)
+	}
+
+	calls := fake.Calls()
+	if len(calls[0].Tools) != 1 || calls[0].Tools[0].Name != "get_weather" {
+		t.Errorf("Tools = %+v, want get_weather", calls[0].Tools)
+	}
+	// user, assistant tool call, user tool result, assistant answer
+	history := conversation.Messages()
+	if len(history) != 4 || len(history[1].ToolCalls) != 1 || history[2].ToolResults[0].ToolCallID != "call-1" {
+		t.Errorf("history = %+v", history)
+	}
+}
+
+func TestConversationToolRoundLimit(t *testing.T) {
+	d, err := NewDispatcher(weatherTool())
+	if err != nil {
+		t.Fatalf("NewDispatcher() error = %v", err)
+	}
+	fake := bedrocktest.NewFake().RespondWith(`.*`, toolCall("call-1", "get_weather", `{"city":"Oslo"}`))
+
+	conversation := NewConversation(fake, bedrock.ModelParams{}).WithTools(d)
+	if _, err := conversation.Send(context.Background(), "Weather?"); !errors.Is(err, ErrToolRounds) {
+		t.Fatalf("Send() error = %v, want ErrToolRounds", err)
+	}
+	if len(conversation.Messages()) != 0 {
+		t.Errorf("a failed turn should leave the history unchanged, got %d messages", len(conversation.Messages()))
+	}
+	if got := len(fake.Calls()); got != maxToolRounds+1 {
+		t.Errorf("made %d calls, want %d", got, maxToolRounds+1)
+	}
+}
+
+func TestConversationSkipsEmptyReply(t *testing.T) {
+	fake := bedrocktest.NewFake().RespondSequence(`.*`, "", "Hello again.")
+	conversation := NewConversation(fake, bedrock.ModelParams{})
+
+	response, err := conversation.Send(context.Background(), "Hi")
+	if err != nil || response.Completion != "" {
+		t.Fatalf("Send() = %+v, %v, want the empty reply", response, err)
+	}
+	if len(conversation.Messages()) != 0 {
+		t.Errorf("an empty reply should leave the history unchanged, got %+v", conversation.Messages())
+	}
+
+	if _, err := conversation.Send(context.Background(), "Hi again"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	// The next turn does not re-send the empty reply, which Converse would reject
+	for _, message := range fake.Calls()[1].Messages {
+		if message.Content == "" && len(message.ToolCalls) == 0 && len(message.ToolResults) == 0 {
+			t.Errorf("second turn sent an empty message: %+v", fake.Calls()[1].Messages)
+		}
+	}
+	if history := conversation.Messages(); len(history) != 2 || history[1].Content != "Hello again." {
+		t.Errorf("history = %+v, want the second exchange only", history)
+	}
+}
@@ -0,0 +1,343 @@
+package prompting
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+)
+
+// SearchStrategy selects how ThoughtSearch explores the tree
+type SearchStrategy string
+
+const (
+	// SearchBFS expands every thought of a level, best first, before moving a level deeper
+	SearchBFS SearchStrategy = "bfs"
+	// SearchBeam keeps only the BeamWidth best thoughts of each level
+	SearchBeam SearchStrategy = "beam"
+)
+
+// finalAnswerPrefix marks a proposed step that solves the problem; such thoughts are not expanded
+const finalAnswerPrefix = "final answer:"
+
+// Each expansion costs two calls: one to propose next steps and one to score them
+const callsPerExpansion = 2
+
+var (
+	proposeTemplate = MustTemplate("tree-of-thoughts propose", `You are solving a problem one step at a time.
+
+Problem:
+{{problem}}
+
+Steps so far:
+{{steps}}
+
+Propose {{count}} different possible next steps. Each must be a single, concrete step that moves toward the solution. If a step reaches the solution, start it with "Final answer:".
+Write them as a numbered list, one step per line, with no other text.`,
+		Variable{Name: "problem", Type: TypeText, Raw: true},
+		Variable{Name: "steps", Type: TypeText, Raw: true},
+		Variable{Name: "count", Type: TypeInt},
+	)
+
+	evaluateTemplate = MustTemplate("tree-of-thoughts evaluate", `You are judging possible next steps for a problem.
+
+Problem:
+{{problem}}
+
+Steps so far:
+{{steps}}
+
+Candidate next steps:
+{{candidates}}
+
+Rate how likely each candidate is to lead to a correct solution, from 0 (impossible or wrong) to 10 (certainly correct).
+Answer with one line per candidate in the form "<number>: <score>" and no other text.`,
+		Variable{Name: "problem", Type: TypeText, Raw: true},
+		Variable{Name: "steps", Type: TypeText, Raw: true},
+		Variable{Name: "candidates", Type: TypeText, Raw: true},
+	)
+
+	answerTemplate = MustTemplate("tree-of-thoughts answer", `Problem:
+{{problem}}
+
+Reasoning steps:
+{{steps}}
+
+Using these steps, give the final answer to the problem.`,
+		Variable{Name: "problem", Type: TypeText, Raw: true},
+		Variable{Name: "steps", Type: TypeText, Raw: true},
+	)
+
+	listItemPattern = regexp.MustCompile(`^\s*(?:\d+\s*[.):]|[-*•])\s*(.+)$`)
+	scorePattern    = regexp.MustCompile(`(?m)^\D*?(\d+)\s*[.):=-]+\s*(\d+(?:\.\d+)?)`)
+)
+
+// ThoughtSearch configures a Tree-of-Thoughts search: the model proposes candidate next steps,
+// scores them, and the search keeps extending the most promising partial solutions
+type ThoughtSearch struct {
+	Strategy  SearchStrategy
+	Breadth   int     // candidate next steps proposed per thought
+	BeamWidth int     // thoughts kept per level by SearchBeam
+	MaxDepth  int     // maximum number of steps in a path
+	MaxCalls  int     // model calls allowed, including the final answer
+	MinScore  float64 // thoughts scored below this are pruned
+}
+
+// DefaultThoughtSearch returns a beam search of width 2 over 3 proposals per thought, at most
+// 3 steps deep and 20 calls
+func DefaultThoughtSearch() ThoughtSearch {
+	return ThoughtSearch{Strategy: SearchBeam, Breadth: 3, BeamWidth: 2, MaxDepth: 3, MaxCalls: 20, MinScore: 1}
+}
+
+// Thought is one step of a path through the tree
+type Thought struct {
+	Text  string
+	Score float64 // the model's 0-10 rating of the step
+	Depth int     // 1-based position in the path
+}
+
+// ThoughtResult is the outcome of a Tree-of-Thoughts search
+type ThoughtResult struct {
+	Answer          string
+	Path            []Thought              // the best path, first step first
+	Response        *bedrock.ModelResponse // the final answer call; nil when the path ended in a final answer
+	Calls           int                    // model calls made
+	Explored        int                    // thoughts proposed and scored
+	BudgetExhausted bool                   // the search stopped because MaxCalls was reached
+}
+
+type thoughtNode struct {
+	Thought
+	parent *thoughtNode
+}
+
+func (n *thoughtNode) path() []Thought {
+	var path []Thought
+	for node := n; node != nil; node = node.parent {
+		path = append(path, node.Thought)
+	}
+	slices.Reverse(path)
+	return path
+}
+
+func (n *thoughtNode) final() bool {
+	return strings.HasPrefix(strings.ToLower(n.Text), finalAnswerPrefix)
+}
+
+// Run searches for a solution to problem. Proposals use params; scoring runs at temperature 0
+// so ratings are stable. The search ends once the best thought is a final answer; otherwise the
+// model answers from the best path, with one call of the budget kept for it.
+func (s ThoughtSearch) Run(ctx context.Context, client bedrock.Invoker, problem string, params bedrock.ModelParams) (*ThoughtResult, error) {
+	switch {
+	case s.Strategy != SearchBFS && s.Strategy != SearchBeam:
+		return nil, fmt.Errorf("unknown search strategy %q", s.Strategy)
+	case s.Breadth < 1 || s.MaxDepth < 1:
+		return nil, fmt.Errorf("tree-of-thoughts needs a breadth and depth of at least 1, got %d and %d", s.Breadth, s.MaxDepth)
+	case s.Strategy == SearchBeam && s.BeamWidth < 1:
+		return nil, fmt.Errorf("beam search needs a beam width of at least 1, got %d", s.BeamWidth)
+	case s.MaxCalls < callsPerExpansion+1:
+		return nil, fmt.Errorf("tree-of-thoughts needs a budget of at least %d calls, got %d", callsPerExpansion+1, s.MaxCalls)
+	}
+
+	result := &ThoughtResult{}
+	var best *thoughtNode
+	frontier := []*thoughtNode{nil}
+
+	for depth := 1; depth <= s.MaxDepth && len(frontier) > 0; depth++ {
+		var level []*thoughtNode
+		for _, parent := range frontier {
+			if result.Calls+callsPerExpansion > s.MaxCalls-1 {
+				result.BudgetExhausted = true
+				break
+			}
+
+			children, err := s.expand(ctx, client, problem, parent, depth, params, result)
+			if err != nil {
+				return nil, err
+			}
+			level = append(level, children...)
+		}
+
+		level = slices.DeleteFunc(level, func(n *thoughtNode) bool { return n.Score < s.MinScore })
+		slices.SortStableFunc(level, func(a, b *thoughtNode) int { return cmp.Compare(b.Score, a.Score) })
+		if s.Strategy == SearchBeam && len(level) > s.BeamWidth {
+			level = level[:s.BeamWidth]
+		}
+
+		// Deeper thoughts win ties, since they carry the solution further
+		if len(level) > 0 && (best == nil || level[0].Score >= best.Score) {
+			best = level[0]
+		}
+		if best != nil && best.final() {
+			break
+		}
+
+		frontier = slices.DeleteFunc(level, (*thoughtNode).final)
+		if result.BudgetExhausted {
+			break
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("tree-of-thoughts found no step scoring at least %g", s.MinScore)
+	}
+	result.Path = best.path()
+
+	if best.final() {
+		result.Answer = strings.TrimSpace(best.Text[len(finalAnswerPrefix):])
+		return result, nil
+	}
+
+	prompt, err := answerTemplate.Render(map[string]string{"problem": problem, "steps": formatSteps(result.Path)})
+	if err != nil {
+		return nil, err
+	}
+	result.Calls++
+	response, err := client.InvokeModel(ctx, prompt, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to answer from the best path: %w", err)
+	}
+	result.Response = response
+	result.Answer = strings.TrimSpace(response.Completion)
+	return result, nil
+}
+
+// expand proposes next steps after parent and scores them
+func (s ThoughtSearch) expand(ctx context.Context, client bedrock.Invoker, problem string, parent *thoughtNode, depth int, params bedrock.ModelParams, result *ThoughtResult) ([]*thoughtNode, error) {
+	steps := formatSteps(parent.path())
+
+	prompt, err := proposeTemplate.Render(map[string]string{"problem": problem, "steps": steps, "count": strconv.Itoa(s.Breadth)})
+	if err != nil {
+		return nil, err
+	}
+	result.Calls++
+	response, err := client.InvokeModel(ctx, prompt, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to propose thoughts: %w", err)
+	}
+
+	candidates := parseList(response.Completion)
+	if len(candidates) > s.Breadth {
+		candidates = candidates[:s.Breadth]
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	var numbered strings.Builder
+	for i, candidate := range candidates {
+		fmt.Fprintf(&numbered, "%d. %s\n", i+1, candidate)
+	}
+	prompt, err = evaluateTemplate.Render(map[string]string{"problem": problem, "steps": steps, "candidates": strings.TrimSpace(numbered.String())})
+	if err != nil {
+		return nil, err
+	}
+	scoring := params
+	scoring.Temperature = 0
+	result.Calls++
+	response, err = client.InvokeModel(ctx, prompt, scoring)
+	if err != nil {
+		return nil, fmt.Errorf("failed to score thoughts: %w", err)
+	}
+
+	scores := parseScores(response.Completion)
+	children := make([]*thoughtNode, len(candidates))
+	for i, candidate := range candidates {
+		children[i] = &thoughtNode{Thought: Thought{Text: candidate, Score: scores[i+1], Depth: depth}, parent: parent}
+	}
+	result.Explored += len(children)
+	return children, nil
+}
+
+// formatSteps numbers the thoughts of a path for the prompts
+func formatSteps(path []Thought) string {
+	if len(path) == 0 {
+		return "None yet"
+	}
+
+	lines := make([]string, len(path))
+	for i, thought := range path {
+		lines[i] = fmt.Sprintf("%d. %s", i+1, thought.Text)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseList returns the items of a numbered or bulleted list, ignoring any other lines
+func parseList(text string) []string {
+	var items []string
+	for _, line := range strings.Split(text, "\n") {
+		if match := listItemPattern.FindStringSubmatch(line); match != nil {
+			items = append(items, strings.TrimSpace(match[1]))
+		}
+	}
+	return items
+}
+
+// parseScores reads "<number>: <score>" lines; unrated candidates score 0
+func parseScores(text string) map[int]float64 {
+	scores := map[int]float64{}
+	for _, match := range scorePattern.FindAllStringSubmatch(text, -1) {
+		index, _ := strconv.Atoi(match[1])
+		score, _ := strconv.ParseFloat(match[2], 64)
+		scores[index] = min(score, 10)
+	}
+	return scores
+}
+
+// TreeOfThoughtsTechnique runs library prompts as the problem of a ThoughtSearch
+type TreeOfThoughtsTechnique struct {
+	search ThoughtSearch
+}
+
+// TreeOfThoughts explores branching reasoning steps with DefaultThoughtSearch
+var TreeOfThoughts = NewTreeOfThoughtsTechnique(DefaultThoughtSearch())
+
+// NewTreeOfThoughtsTechnique creates a Tree-of-Thoughts technique with its own search settings
+func NewTreeOfThoughtsTechnique(search ThoughtSearch) *TreeOfThoughtsTechnique {
+	return &TreeOfThoughtsTechnique{search: search}
+}
+
+func (t *TreeOfThoughtsTechnique) Info() TechniqueInfo {
+	return TechniqueInfo{
+		Name:        TechniqueTreeOfThoughts,
+		Title:       "Tree-of-Thoughts",
+		Emoji:       "🌳",
+		Description: "Searching branching reasoning steps",
+	}
+}
+
+func (t *TreeOfThoughtsTechnique) DefaultParams() bedrock.ModelParams {
+	params := bedrock.GetDefaultClaudeParams()
+	params.Temperature = 0.7 // Varied proposals give the search real alternatives
+	params.MaxTokens = 500   // Each call proposes, scores or answers a single step
+	return params
+}
+
+func (t *TreeOfThoughtsTechnique) Execute(ctx context.Context, client bedrock.Invoker, spec *PromptSpec, params bedrock.ModelParams, values map[string]string) (*Result, error) {
+	problem, err := spec.Render(values)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := t.search.Run(ctx, client, problem, spec.Params.Apply(params))
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute %s: %w", strings.ToLower(spec.Title), err)
+	}
+
+	response := result.Response
+	if response == nil {
+		response = &bedrock.ModelResponse{Completion: result.Answer}
+	}
+
+	steps := make([]string, len(result.Path))
+	for i, thought := range result.Path {
+		steps[i] = fmt.Sprintf("%s (score %g)", thought.Text, thought.Score)
+	}
+
+	return &Result{Prompt: problem, Response: response, Steps: steps}, nil
+}
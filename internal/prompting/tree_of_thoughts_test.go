@@ -0,0 +1,123 @@
+package prompting
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+	"aws-bedrock-prompt-engineering/internal/bedrock/bedrocktest"
+)
+
+func TestThoughtSearchBeam(t *testing.T) {
+	fake := bedrocktest.NewFake().
+		RespondSequence(`Propose`, "1. Try A\n2. Try B", "Here you go:\n1. Try B1\n2. Final answer: 42").
+		RespondSequence(`Rate how likely`, "1: 3\n2: 8", "1: 5\n2: 9")
+	search := ThoughtSearch{Strategy: SearchBeam, Breadth: 2, BeamWidth: 1, MaxDepth: 3, MaxCalls: 20}
+
+	result, err := search.Run(context.Background(), fake, "What is 6 × 7?", bedrock.ModelParams{Temperature: 0.7})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if result.Answer != "42" || result.Response != nil {
+		t.Errorf("Answer = %q, Response = %v, want 42 from the final thought", result.Answer, result.Response)
+	}
+	if len(result.Path) != 2 || result.Path[0].Text != "Try B" || result.Path[1].Score != 9 {
+		t.Errorf("Path = %+v, want Try B then the final answer", result.Path)
+	}
+	if result.Calls != 4 || result.Explored != 4 {
+		t.Errorf("Calls = %d, Explored = %d, want 4 and 4", result.Calls, result.Explored)
+	}
+
+	calls := fake.Calls()
+	// The second proposal builds on the best first step, and scoring runs at temperature 0
+	if !strings.Contains(calls[2].Prompt, "1. Try B") {
+		t.Errorf("second proposal prompt does not continue from Try B:\n%s", calls[2].Prompt)
+	}
+	if calls[1].Params.Temperature != 0 || calls[0].Params.Temperature != 0.7 {
+		t.Errorf("temperatures = %v and %v, want 0.7 for proposals and 0 for scoring", calls[0].Params.Temperature, calls[1].Params.Temperature)
+	}
+}
+
+func TestThoughtSearchBFSPrunes(t *testing.T) {
+	fake := bedrocktest.NewFake().
+		RespondSequence(`Propose`, "1. Try A\n2. Try B", "1. Try B1").
+		RespondSequence(`Rate how likely`, "1: 0\n2: 8", "1: 6").
+		Respond(`Using these steps`, "The answer is 42.")
+	search := ThoughtSearch{Strategy: SearchBFS, Breadth: 2, MaxDepth: 2, MaxCalls: 20, MinScore: 1}
+
+	result, err := search.Run(context.Background(), fake, "problem", bedrock.ModelParams{})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// Try A scored below MinScore, so only Try B is expanded
+	if result.Calls != 5 {
+		t.Errorf("Calls = %d, want 5", result.Calls)
+	}
+	if result.Path[0].Text != "Try B" || result.Answer != "The answer is 42." {
+		t.Errorf("Path = %+v, Answer = %q", result.Path, result.Answer)
+	}
+}
+
+func TestThoughtSearchBudget(t *testing.T) {
+	fake := bedrocktest.NewFake().
+		Respond(`Propose`, "1. Try A\n2. Try B").
+		Respond(`Rate how likely`, "1: 4\n2: 7").
+		Respond(`Using these steps`, "best effort")
+	search := ThoughtSearch{Strategy: SearchBFS, Breadth: 2, MaxDepth: 5, MaxCalls: 4}
+
+	result, err := search.Run(context.Background(), fake, "problem", bedrock.ModelParams{})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if !result.BudgetExhausted || result.Calls != 3 {
+		t.Errorf("BudgetExhausted = %v, Calls = %d, want true and 3", result.BudgetExhausted, result.Calls)
+	}
+	if len(result.Path) != 1 || result.Path[0].Text != "Try B" || result.Answer != "best effort" {
+		t.Errorf("Path = %+v, Answer = %q", result.Path, result.Answer)
+	}
+}
+
+func TestThoughtSearchInvalid(t *testing.T) {
+	fake := bedrocktest.NewFake()
+	for _, search := range []ThoughtSearch{
+		{Strategy: "dfs", Breadth: 2, MaxDepth: 2, MaxCalls: 10},
+		{Strategy: SearchBeam, Breadth: 2, MaxDepth: 2, MaxCalls: 10},
+		{Strategy: SearchBFS, Breadth: 2, MaxDepth: 2, MaxCalls: 2},
+	} {
+		if _, err := search.Run(context.Background(), fake, "problem", bedrock.ModelParams{}); err == nil {
+			t.Errorf("Run(%+v) succeeded, want a configuration error", search)
+		}
+	}
+	if len(fake.Calls()) != 0 {
+		t.Error("invalid searches called the model")
+	}
+}
+
+func TestTreeOfThoughtsTechnique(t *testing.T) {
+	t.Setenv("MODEL_ID", testModelID)
+
+	fake := bedrocktest.NewFake().
+		Respond(`Propose`, "1. 13 - 9 = 4 (left: 4 4 10)\n2. Final answer: (10 - 4) × (13 - 9) = 24").
+		Respond(`Rate how likely`, "1: 6\n2: 10")
+	spec, _ := DefaultLibrary().Get("game-of-24")
+
+	technique := TechniqueFor(TechniqueTreeOfThoughts)
+	result, err := technique.Execute(context.Background(), fake, spec, technique.DefaultParams(), spec.Inputs)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if result.Response.Completion != "(10 - 4) × (13 - 9) = 24" {
+		t.Errorf("Completion = %q", result.Response.Completion)
+	}
+	if len(result.Steps) != 1 || !strings.Contains(result.Steps[0], "score 10") {
+		t.Errorf("Steps = %q, want the final thought with its score", result.Steps)
+	}
+	if !strings.Contains(fake.Calls()[0].Prompt, "4 9 10 13") {
+		t.Errorf("proposal prompt does not hold the problem:\n%s", fake.Calls()[0].Prompt)
+	}
+}
@@ -0,0 +1,122 @@
+package prompting
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock/bedrocktest"
+)
+
+const testModelID = "anthropic.claude-v2:1"
+
+// exampleCase describes one Execute* method and what its prompt must contain
+type exampleCase struct {
+	name     string
+	contains string
+	errMsg   string
+	run      func(context.Context) error
+}
+
+// withScriptedAnswers makes fake answer the library prompts that declare an output format with
+// a completion in that format, and every other prompt with "scripted reply". Rules added to
+// fake before it take precedence.
+func withScriptedAnswers(fake *bedrocktest.Fake) *bedrocktest.Fake {
+	return fake.
+		Respond(`(Sentiment|Classification):$`, "negative").
+		Respond(`Category:$`, "support").
+		Respond(`Entities:$`, "- PERSON: Dr. Sarah Johnson\n- ORGANIZATION: Harvard University\n- LOCATION: Boston").
+		Default("scripted reply")
+}
+
+// runExampleCases checks that every example sends its prompt with the expected
+// temperature and wraps errors from the model client
+func runExampleCases(t *testing.T, newCases func(fake *bedrocktest.Fake) []exampleCase, temperature float64, maxTokens int) {
+	t.Helper()
+
+	// Each subtest rebuilds the cases so the example is bound to a fresh fake
+	for i, tc := range newCases(bedrocktest.NewFake()) {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := withScriptedAnswers(bedrocktest.NewFake())
+			run := newCases(fake)[i].run
+
+			if err := run(context.Background()); err != nil {
+				t.Fatalf("%s() error = %v", tc.name, err)
+			}
+
+			calls := fake.Calls()
+			if len(calls) != 1 {
+				t.Fatalf("%s() made %d calls, want 1", tc.name, len(calls))
+			}
+			if !strings.Contains(calls[0].Prompt, tc.contains) {
+				t.Errorf("%s() prompt does not contain %q", tc.name, tc.contains)
+			}
+			if calls[0].Params.ModelID != testModelID {
+				t.Errorf("%s() ModelID = %q, want %q", tc.name, calls[0].Params.ModelID, testModelID)
+			}
+			if temperature > 0 && calls[0].Params.Temperature != temperature {
+				t.Errorf("%s() Temperature = %v, want %v", tc.name, calls[0].Params.Temperature, temperature)
+			}
+			if calls[0].Params.MaxTokens != maxTokens {
+				t.Errorf("%s() MaxTokens = %d, want %d", tc.name, calls[0].Params.MaxTokens, maxTokens)
+			}
+		})
+
+		t.Run(tc.name+"/error", func(t *testing.T) {
+			injected := errors.New("ThrottlingException")
+			fake := bedrocktest.NewFake().Fail(`.*`, injected)
+			run := newCases(fake)[i].run
+
+			err := run(context.Background())
+			if !errors.Is(err, injected) {
+				t.Fatalf("%s() error = %v, want wrapped %v", tc.name, err, injected)
+			}
+			if !strings.Contains(err.Error(), tc.errMsg) {
+				t.Errorf("%s() error = %q, want it to contain %q", tc.name, err, tc.errMsg)
+			}
+		})
+	}
+}
+
+func zeroShotCases(fake *bedrocktest.Fake) []exampleCase {
+	z := NewZeroShotPrompt(fake)
+	return []exampleCase{
+		{"ExecuteTextClassification", "I absolutely love this new restaurant!", "failed to execute text classification", z.ExecuteTextClassification},
+		{"ExecuteQuestionAnswering", "What is the capital of Japan", "failed to execute question answering", z.ExecuteQuestionAnswering},
+		{"ExecuteLanguageTranslation", "Translate the following English text to French", "failed to execute language translation", z.ExecuteLanguageTranslation},
+		{"ExecuteCodeGeneration", "calculate_factorial", "failed to execute code generation", z.ExecuteCodeGeneration},
+	}
+}
+
+func TestZeroShotExamples(t *testing.T) {
+	t.Setenv("MODEL_ID", testModelID)
+	runExampleCases(t, zeroShotCases, 0.3, 500)
+}
+
+func TestZeroShotRunAllExamples(t *testing.T) {
+	t.Setenv("MODEL_ID", testModelID)
+
+	fake := withScriptedAnswers(bedrocktest.NewFake().Fail(`capital of Japan`, errors.New("ValidationException")))
+	NewZeroShotPrompt(fake).RunAllExamples(context.Background())
+
+	// A failing example is logged and the remaining examples still run
+	if got := len(fake.Calls()); got != 4 {
+		t.Errorf("RunAllExamples() made %d calls, want 4", got)
+	}
+}
+
+func TestZeroShotRunAllExamplesCancelled(t *testing.T) {
+	t.Setenv("MODEL_ID", testModelID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	fake := bedrocktest.NewFake().Default("scripted reply")
+	NewZeroShotPrompt(fake).RunAllExamples(ctx)
+
+	// The first example fails with the context error and the rest are skipped
+	if got := len(fake.Calls()); got != 1 {
+		t.Errorf("RunAllExamples() made %d calls after cancellation, want 1", got)
+	}
+}
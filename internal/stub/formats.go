@@ -0,0 +1,304 @@
+package stub
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// format is one model family's wire format, seen from the server side: it pulls the prompt out
+// of a request body and renders replies the way that family's client adapter expects them
+type format interface {
+	// prompt returns the text the scripted replies are matched against
+	prompt() string
+	// response renders a complete InvokeModel response body
+	response(reply string, usage usage) any
+	// chunks renders the InvokeModelWithResponseStream chunks for the reply pieces
+	chunks(pieces []string, usage usage) []any
+}
+
+type usage struct {
+	input  int
+	output int
+}
+
+// detectFormat sniffs the request body to find which family's format the client used
+func detectFormat(body []byte) (format, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, fmt.Errorf("request body is not a JSON object: %w", err)
+	}
+
+	var f format
+	switch {
+	case fields["messages"] != nil:
+		f = &messagesFormat{}
+	case fields["max_tokens_to_sample"] != nil:
+		f = &textCompletionFormat{}
+	case fields["inputText"] != nil:
+		f = &titanFormat{}
+	case fields["max_gen_len"] != nil:
+		f = &llamaFormat{}
+	case fields["message"] != nil:
+		f = &cohereChatFormat{}
+	case fields["p"] != nil:
+		f = &cohereFormat{}
+	case fields["prompt"] != nil:
+		f = &mistralFormat{}
+	default:
+		return nil, fmt.Errorf("unrecognized request body format")
+	}
+
+	if err := json.Unmarshal(body, f); err != nil {
+		return nil, fmt.Errorf("failed to decode request body: %w", err)
+	}
+
+	return f, nil
+}
+
+// withMetrics attaches the invocation metrics Bedrock appends to the last chunk of every stream
+func withMetrics(chunk map[string]any, u usage) map[string]any {
+	chunk["amazon-bedrock-invocationMetrics"] = map[string]any{
+		"inputTokenCount":   u.input,
+		"outputTokenCount":  u.output,
+		"invocationLatency": 0,
+		"firstByteLatency":  0,
+	}
+	return chunk
+}
+
+type messagesFormat struct {
+	Messages []struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	} `json:"messages"`
+}
+
+func (f *messagesFormat) prompt() string {
+	if len(f.Messages) == 0 {
+		return ""
+	}
+
+	// Content is either a plain string or a list of blocks
+	content := f.Messages[len(f.Messages)-1].Content
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return text
+	}
+
+	var blocks []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	json.Unmarshal(content, &blocks)
+
+	var b strings.Builder
+	for _, block := range blocks {
+		b.WriteString(block.Text)
+	}
+	return b.String()
+}
+
+func (f *messagesFormat) response(reply string, u usage) any {
+	return map[string]any{
+		"id":            "msg_stub",
+		"type":          "message",
+		"role":          "assistant",
+		"model":         "stub",
+		"content":       []map[string]any{{"type": "text", "text": reply}},
+		"stop_reason":   "end_turn",
+		"stop_sequence": nil,
+		"usage":         map[string]any{"input_tokens": u.input, "output_tokens": u.output},
+	}
+}
+
+func (f *messagesFormat) chunks(pieces []string, u usage) []any {
+	chunks := []any{
+		map[string]any{"type": "message_start", "message": map[string]any{
+			"id": "msg_stub", "type": "message", "role": "assistant", "model": "stub", "content": []any{},
+			"usage": map[string]any{"input_tokens": u.input, "output_tokens": 0},
+		}},
+		map[string]any{"type": "content_block_start", "index": 0, "content_block": map[string]any{"type": "text", "text": ""}},
+	}
+
+	for _, piece := range pieces {
+		chunks = append(chunks, map[string]any{
+			"type": "content_block_delta", "index": 0,
+			"delta": map[string]any{"type": "text_delta", "text": piece},
+		})
+	}
+
+	return append(chunks,
+		map[string]any{"type": "content_block_stop", "index": 0},
+		map[string]any{"type": "message_delta",
+			"delta": map[string]any{"stop_reason": "end_turn", "stop_sequence": nil},
+			"usage": map[string]any{"output_tokens": u.output},
+		},
+		withMetrics(map[string]any{"type": "message_stop"}, u),
+	)
+}
+
+type textCompletionFormat struct {
+	Prompt string `json:"prompt"`
+}
+
+func (f *textCompletionFormat) prompt() string {
+	text := strings.TrimSuffix(strings.TrimSpace(f.Prompt), "Assistant:")
+	if i := strings.LastIndex(text, "Human:"); i >= 0 {
+		text = text[i+len("Human:"):]
+	}
+	return strings.TrimSpace(text)
+}
+
+func (f *textCompletionFormat) response(reply string, u usage) any {
+	return map[string]any{"type": "completion", "completion": reply, "stop_reason": "stop_sequence", "stop": "\n\nHuman:"}
+}
+
+func (f *textCompletionFormat) chunks(pieces []string, u usage) []any {
+	var chunks []any
+	for _, piece := range pieces {
+		chunks = append(chunks, map[string]any{"type": "completion", "completion": piece, "stop_reason": nil, "stop": nil})
+	}
+
+	return append(chunks, withMetrics(map[string]any{
+		"type": "completion", "completion": "", "stop_reason": "stop_sequence", "stop": "\n\nHuman:",
+	}, u))
+}
+
+type titanFormat struct {
+	InputText string `json:"inputText"`
+}
+
+func (f *titanFormat) prompt() string {
+	text := strings.TrimSuffix(strings.TrimSpace(f.InputText), "Bot:")
+	if i := strings.LastIndex(text, "User:"); i >= 0 {
+		text = text[i+len("User:"):]
+	}
+	return strings.TrimSpace(text)
+}
+
+func (f *titanFormat) response(reply string, u usage) any {
+	return map[string]any{
+		"inputTextTokenCount": u.input,
+		"results": []map[string]any{
+			{"tokenCount": u.output, "outputText": reply, "completionReason": "FINISH"},
+		},
+	}
+}
+
+func (f *titanFormat) chunks(pieces []string, u usage) []any {
+	var chunks []any
+	for i, piece := range pieces {
+		chunk := map[string]any{"outputText": piece, "index": 0, "totalOutputTextTokenCount": i + 1, "completionReason": nil, "inputTextTokenCount": u.input}
+		if i == len(pieces)-1 {
+			chunk["completionReason"] = "FINISH"
+			withMetrics(chunk, u)
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+type llamaFormat struct {
+	Prompt string `json:"prompt"`
+}
+
+func (f *llamaFormat) prompt() string {
+	text := f.Prompt
+	if i := strings.LastIndex(text, "user<|end_header_id|>"); i >= 0 {
+		text = text[i+len("user<|end_header_id|>"):]
+		text, _, _ = strings.Cut(text, "<|eot_id|>")
+	} else if i := strings.LastIndex(text, "[INST]"); i >= 0 {
+		text = strings.TrimSuffix(strings.TrimSpace(text[i+len("[INST]"):]), "[/INST]")
+	}
+	return strings.TrimSpace(text)
+}
+
+func (f *llamaFormat) response(reply string, u usage) any {
+	return map[string]any{"generation": reply, "prompt_token_count": u.input, "generation_token_count": u.output, "stop_reason": "stop"}
+}
+
+func (f *llamaFormat) chunks(pieces []string, u usage) []any {
+	var chunks []any
+	for i, piece := range pieces {
+		chunk := map[string]any{"generation": piece, "prompt_token_count": nil, "generation_token_count": i + 1, "stop_reason": nil}
+		if i == len(pieces)-1 {
+			chunk["stop_reason"] = "stop"
+			withMetrics(chunk, u)
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+type mistralFormat struct {
+	Prompt string `json:"prompt"`
+}
+
+func (f *mistralFormat) prompt() string {
+	text := strings.TrimPrefix(f.Prompt, "<s>")
+	text = strings.TrimPrefix(strings.TrimSpace(text), "[INST]")
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "[/INST]"))
+}
+
+func (f *mistralFormat) response(reply string, u usage) any {
+	return map[string]any{"outputs": []map[string]any{{"text": reply, "stop_reason": "stop"}}}
+}
+
+func (f *mistralFormat) chunks(pieces []string, u usage) []any {
+	var chunks []any
+	for i, piece := range pieces {
+		output := map[string]any{"text": piece, "stop_reason": nil}
+		chunk := map[string]any{"outputs": []map[string]any{output}}
+		if i == len(pieces)-1 {
+			output["stop_reason"] = "stop"
+			withMetrics(chunk, u)
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+type cohereFormat struct {
+	Prompt string `json:"prompt"`
+}
+
+func (f *cohereFormat) prompt() string {
+	return f.Prompt
+}
+
+func (f *cohereFormat) response(reply string, u usage) any {
+	return map[string]any{
+		"id":          "stub",
+		"prompt":      f.Prompt,
+		"generations": []map[string]any{{"id": "stub", "text": reply, "finish_reason": "COMPLETE"}},
+	}
+}
+
+func (f *cohereFormat) chunks(pieces []string, u usage) []any {
+	var chunks []any
+	for _, piece := range pieces {
+		chunks = append(chunks, map[string]any{"text": piece, "is_finished": false})
+	}
+	return append(chunks, withMetrics(map[string]any{"is_finished": true, "finish_reason": "COMPLETE"}, u))
+}
+
+type cohereChatFormat struct {
+	Message string `json:"message"`
+}
+
+func (f *cohereChatFormat) prompt() string {
+	return f.Message
+}
+
+func (f *cohereChatFormat) response(reply string, u usage) any {
+	return map[string]any{"generation_id": "stub", "text": reply, "finish_reason": "COMPLETE", "chat_history": []any{}}
+}
+
+func (f *cohereChatFormat) chunks(pieces []string, u usage) []any {
+	chunks := []any{map[string]any{"event_type": "stream-start", "generation_id": "stub"}}
+	for _, piece := range pieces {
+		chunks = append(chunks, map[string]any{"event_type": "text-generation", "text": piece})
+	}
+	return append(chunks, withMetrics(map[string]any{"event_type": "stream-end", "finish_reason": "COMPLETE"}, u))
+}
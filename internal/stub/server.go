@@ -0,0 +1,372 @@
+// Package stub emulates the bedrock-runtime InvokeModel, InvokeModelWithResponseStream and Converse
+// endpoints with deterministic or scripted replies, and embedding models with hashed word vectors,
+// so the application can run without network access.
+package stub
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+)
+
+// Reply is a scripted answer for every prompt matching Match
+type Reply struct {
+	Match  string   `json:"match"`            // regular expression matched against the prompt
+	Text   string   `json:"text,omitempty"`   // reply text
+	Error  string   `json:"error,omitempty"`  // Bedrock exception to return instead, e.g. "ThrottlingException"
+	Status int      `json:"status,omitempty"` // HTTP status for Error; derived from the exception name when zero
+	Tool   *ToolUse `json:"tool,omitempty"`   // tool call to make instead of replying; Converse only
+}
+
+// ToolUse is a scripted tool call. The tool result comes back as the next prompt.
+type ToolUse struct {
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input"`
+}
+
+type scriptedReply struct {
+	Reply
+	pattern *regexp.Regexp
+}
+
+// Server serves scripted replies in whichever model family's format the request body uses.
+// Prompts that match no scripted reply get a deterministic echo.
+type Server struct {
+	replies      []scriptedReply
+	chunkDelay   time.Duration
+	mux          *http.ServeMux
+	toolUseCount atomic.Int64
+}
+
+// errorStatus maps Bedrock exception names to the HTTP status codes the service returns
+var errorStatus = map[string]int{
+	"ValidationException":         http.StatusBadRequest,
+	"AccessDeniedException":       http.StatusForbidden,
+	"ResourceNotFoundException":   http.StatusNotFound,
+	"ModelTimeoutException":       http.StatusRequestTimeout,
+	"ThrottlingException":         http.StatusTooManyRequests,
+	"ModelNotReadyException":      http.StatusTooManyRequests,
+	"InternalServerException":     http.StatusInternalServerError,
+	"ServiceUnavailableException": http.StatusServiceUnavailable,
+}
+
+// embeddingDimensions is the length of the vectors returned for embedding models
+const embeddingDimensions = 256
+
+// piecePattern splits a reply into the word-sized pieces sent as stream chunks
+var piecePattern = regexp.MustCompile(`\s*\S+`)
+
+// NewServer creates a stub server. chunkDelay is the pause between stream chunks.
+func NewServer(replies []Reply, chunkDelay time.Duration) (*Server, error) {
+	s := &Server{chunkDelay: chunkDelay, mux: http.NewServeMux()}
+
+	for _, reply := range replies {
+		pattern, err := regexp.Compile(reply.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reply pattern %q: %w", reply.Match, err)
+		}
+		s.replies = append(s.replies, scriptedReply{Reply: reply, pattern: pattern})
+	}
+
+	s.mux.HandleFunc("POST /model/{modelId}/invoke", s.handleInvoke)
+	s.mux.HandleFunc("POST /model/{modelId}/invoke-with-response-stream", s.handleInvokeStream)
+	s.mux.HandleFunc("POST /model/{modelId}/converse", s.handleConverse)
+
+	return s, nil
+}
+
+// LoadReplies reads scripted replies from a JSON file containing an array of Reply
+func LoadReplies(path string) ([]Reply, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replies file: %w", err)
+	}
+
+	var replies []Reply
+	if err := json.Unmarshal(data, &replies); err != nil {
+		return nil, fmt.Errorf("failed to parse replies file: %w", err)
+	}
+
+	return replies, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Printf("%s %s", r.Method, r.URL.Path)
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handleInvoke(w http.ResponseWriter, r *http.Request) {
+	if strings.Contains(r.PathValue("modelId"), "embed") {
+		s.handleEmbed(w, r)
+		return
+	}
+
+	f, reply, ok := s.prepare(w, r)
+	if !ok {
+		return
+	}
+
+	u := countUsage(f.prompt(), reply)
+	w.Header().Set("X-Amzn-Bedrock-Input-Token-Count", strconv.Itoa(u.input))
+	w.Header().Set("X-Amzn-Bedrock-Output-Token-Count", strconv.Itoa(u.output))
+	writeJSON(w, http.StatusOK, f.response(reply, u))
+}
+
+func (s *Server) handleInvokeStream(w http.ResponseWriter, r *http.Request) {
+	f, reply, ok := s.prepare(w, r)
+	if !ok {
+		return
+	}
+
+	pieces := piecePattern.FindAllString(reply, -1)
+	chunks := f.chunks(pieces, countUsage(f.prompt(), reply))
+
+	w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := eventstream.NewEncoder()
+	flusher, _ := w.(http.Flusher)
+	for i, chunk := range chunks {
+		if err := writeChunk(encoder, w, chunk); err != nil {
+			log.Printf("failed to write stream chunk: %v", err)
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if i < len(chunks)-1 && s.chunkDelay > 0 {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(s.chunkDelay):
+			}
+		}
+	}
+}
+
+func (s *Server) handleConverse(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Messages []struct {
+			Role    string `json:"role"`
+			Content []struct {
+				Text       string `json:"text"`
+				ToolResult *struct {
+					Content []struct {
+						Text string `json:"text"`
+					} `json:"content"`
+				} `json:"toolResult"`
+			} `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, "ValidationException", 0, fmt.Sprintf("failed to decode request body: %v", err))
+		return
+	}
+
+	// Tool results are matched like text, so a script can answer once a tool has run
+	var prompt strings.Builder
+	if len(request.Messages) > 0 {
+		for _, block := range request.Messages[len(request.Messages)-1].Content {
+			prompt.WriteString(block.Text)
+			if block.ToolResult != nil {
+				for _, result := range block.ToolResult.Content {
+					prompt.WriteString(result.Text)
+				}
+			}
+		}
+	}
+
+	if scripted := s.match(prompt.String()); scripted != nil && scripted.Tool != nil {
+		input := scripted.Tool.Input
+		if len(input) == 0 {
+			input = json.RawMessage("{}")
+		}
+		u := countUsage(prompt.String(), "")
+		writeJSON(w, http.StatusOK, map[string]any{
+			"output": map[string]any{"message": map[string]any{
+				"role": "assistant",
+				"content": []map[string]any{{"toolUse": map[string]any{
+					"toolUseId": fmt.Sprintf("tooluse_stub_%d", s.toolUseCount.Add(1)),
+					"name":      scripted.Tool.Name,
+					"input":     input,
+				}}},
+			}},
+			"stopReason": "tool_use",
+			"usage":      map[string]any{"inputTokens": u.input, "outputTokens": u.output, "totalTokens": u.input + u.output},
+			"metrics":    map[string]any{"latencyMs": 0},
+		})
+		return
+	}
+
+	reply, ok := s.reply(w, prompt.String())
+	if !ok {
+		return
+	}
+
+	u := countUsage(prompt.String(), reply)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"output": map[string]any{"message": map[string]any{
+			"role":    "assistant",
+			"content": []map[string]any{{"text": reply}},
+		}},
+		"stopReason": "end_turn",
+		"usage":      map[string]any{"inputTokens": u.input, "outputTokens": u.output, "totalTokens": u.input + u.output},
+		"metrics":    map[string]any{"latencyMs": 0},
+	})
+}
+
+// handleEmbed answers Titan and Cohere embedding requests with embedText vectors
+func (s *Server) handleEmbed(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		InputText string   `json:"inputText"`
+		Texts     []string `json:"texts"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, "ValidationException", 0, fmt.Sprintf("failed to decode request body: %v", err))
+		return
+	}
+
+	if strings.HasPrefix(r.PathValue("modelId"), "cohere.") {
+		embeddings := make([][]float64, len(request.Texts))
+		for i, text := range request.Texts {
+			embeddings[i] = embedText(text)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"id": "stub", "texts": request.Texts, "embeddings": embeddings})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"embedding":           embedText(request.InputText),
+		"inputTextTokenCount": len(strings.Fields(request.InputText)),
+	})
+}
+
+// embedText hashes the words of text into a fixed-size vector, so texts sharing words stay
+// similar and the stub needs no model
+func embedText(text string) []float64 {
+	vector := make([]float64, embeddingDimensions)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		h := fnv.New32a()
+		h.Write([]byte(word))
+		vector[h.Sum32()%embeddingDimensions]++
+	}
+	return vector
+}
+
+// prepare decodes the request body and picks the reply, writing an error response when it cannot
+func (s *Server) prepare(w http.ResponseWriter, r *http.Request) (format, string, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, "ValidationException", 0, fmt.Sprintf("failed to read request body: %v", err))
+		return nil, "", false
+	}
+
+	f, err := detectFormat(body)
+	if err != nil {
+		writeError(w, "ValidationException", 0, err.Error())
+		return nil, "", false
+	}
+
+	reply, ok := s.reply(w, f.prompt())
+	return f, reply, ok
+}
+
+// match returns the first scripted reply whose pattern matches prompt, or nil
+func (s *Server) match(prompt string) *scriptedReply {
+	for i := range s.replies {
+		if s.replies[i].pattern.MatchString(prompt) {
+			return &s.replies[i]
+		}
+	}
+	return nil
+}
+
+// reply finds the scripted reply for prompt, writing the scripted error response if there is one
+func (s *Server) reply(w http.ResponseWriter, prompt string) (string, bool) {
+	scripted := s.match(prompt)
+	if scripted == nil {
+		return echo(prompt), true
+	}
+
+	if scripted.Error != "" {
+		writeError(w, scripted.Error, scripted.Status, "scripted error from bedrock stub")
+		return "", false
+	}
+	return scripted.Text, true
+}
+
+// echo is the deterministic reply for prompts without a scripted answer
+func echo(prompt string) string {
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(prompt), "\n")
+	if len(firstLine) > 80 {
+		firstLine = firstLine[:80] + "..."
+	}
+	return fmt.Sprintf("Stub reply to: %s", firstLine)
+}
+
+// countUsage approximates token counts with word counts so they stay deterministic
+func countUsage(prompt, reply string) usage {
+	return usage{input: len(strings.Fields(prompt)), output: len(strings.Fields(reply))}
+}
+
+func writeChunk(encoder *eventstream.Encoder, w io.Writer, chunk any) error {
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		return err
+	}
+
+	// PayloadPart carries the chunk base64-encoded in a "bytes" field
+	payload, err := json.Marshal(map[string][]byte{"bytes": data})
+	if err != nil {
+		return err
+	}
+
+	var headers eventstream.Headers
+	headers.Set(":message-type", eventstream.StringValue("event"))
+	headers.Set(":event-type", eventstream.StringValue("chunk"))
+	headers.Set(":content-type", eventstream.StringValue("application/json"))
+
+	var buf bytes.Buffer
+	if err := encoder.Encode(&buf, eventstream.Message{Headers: headers, Payload: payload}); err != nil {
+		return err
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, exception string, status int, message string) {
+	if status == 0 {
+		status = errorStatus[exception]
+	}
+	if status == 0 {
+		status = http.StatusBadRequest
+	}
+
+	w.Header().Set("X-Amzn-ErrorType", exception)
+	writeJSON(w, status, map[string]string{"message": message})
+}
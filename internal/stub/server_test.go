@@ -0,0 +1,157 @@
+package stub
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"aws-bedrock-prompt-engineering/internal/bedrock"
+)
+
+// newTestClient starts a stub server and points a real bedrock.Client at it
+func newTestClient(t *testing.T, replies []Reply) *bedrock.Client {
+	t.Helper()
+
+	server, err := NewServer(replies, 0)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	t.Setenv("AWS_ENDPOINT_URL", ts.URL)
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+	client, err := bedrock.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return client
+}
+
+var testModelIDs = []string{
+	"anthropic.claude-v2:1",
+	"anthropic.claude-3-haiku-20240307-v1:0",
+	"amazon.titan-text-express-v1",
+	"meta.llama3-8b-instruct-v1:0",
+	"mistral.mistral-7b-instruct-v0:2",
+	"cohere.command-text-v14",
+	"cohere.command-r-v1:0",
+}
+
+func TestInvokeModelScriptedReply(t *testing.T) {
+	client := newTestClient(t, []Reply{{Match: `(?i)sentiment`, Text: "negative"}})
+
+	for _, modelID := range testModelIDs {
+		t.Run(modelID, func(t *testing.T) {
+			params := bedrock.ModelParams{ModelID: modelID, Temperature: 0.5, TopP: 1, TopK: 250, MaxTokens: 100}
+
+			resp, err := client.InvokeModel(context.Background(), "Classify the sentiment of this review", params)
+			if err != nil {
+				t.Fatalf("InvokeModel() error = %v", err)
+			}
+			if resp.Completion != "negative" {
+				t.Errorf("Completion = %q, want %q", resp.Completion, "negative")
+			}
+			// Token counts come from the response headers, even for bodies that carry none
+			if resp.Usage == nil || resp.Usage.InputTokens == 0 || resp.Usage.OutputTokens != 1 {
+				t.Errorf("Usage = %+v, want input tokens and 1 output token", resp.Usage)
+			}
+		})
+	}
+}
+
+func TestInvokeModelStreamEcho(t *testing.T) {
+	client := newTestClient(t, nil)
+
+	for _, modelID := range testModelIDs {
+		t.Run(modelID, func(t *testing.T) {
+			params := bedrock.ModelParams{ModelID: modelID, Temperature: 0.5, TopP: 1, TopK: 250, MaxTokens: 100}
+
+			var streamed strings.Builder
+			resp, err := client.InvokeModelStream(context.Background(), "Tell me a story", params, func(text string) {
+				streamed.WriteString(text)
+			})
+			if err != nil {
+				t.Fatalf("InvokeModelStream() error = %v", err)
+			}
+
+			want := "Stub reply to: Tell me a story"
+			if streamed.String() != want || resp.Completion != want {
+				t.Errorf("streamed %q, Completion %q, want %q", streamed.String(), resp.Completion, want)
+			}
+			if resp.StopReason == "" {
+				t.Error("StopReason is empty")
+			}
+		})
+	}
+}
+
+func TestInvokeModelScriptedError(t *testing.T) {
+	client := newTestClient(t, []Reply{{Match: `.*`, Error: "AccessDeniedException"}})
+
+	params := bedrock.ModelParams{ModelID: "anthropic.claude-v2:1", MaxTokens: 100}
+	_, err := client.InvokeModel(context.Background(), "anything", params)
+	if err == nil || !strings.Contains(err.Error(), "AccessDeniedException") {
+		t.Errorf("InvokeModel() error = %v, want AccessDeniedException", err)
+	}
+}
+
+func TestEmbed(t *testing.T) {
+	client := newTestClient(t, nil)
+
+	for _, modelID := range []string{"amazon.titan-embed-text-v2:0", "cohere.embed-english-v3"} {
+		t.Run(modelID, func(t *testing.T) {
+			embedding, err := client.Embed(context.Background(), modelID, "The package arrived late")
+			if err != nil {
+				t.Fatalf("Embed() error = %v", err)
+			}
+			if len(embedding) != embeddingDimensions {
+				t.Errorf("len(embedding) = %d, want %d", len(embedding), embeddingDimensions)
+			}
+		})
+	}
+}
+
+func TestConverseToolUse(t *testing.T) {
+	client := newTestClient(t, []Reply{
+		{Match: `(?i)multiply`, Tool: &ToolUse{Name: "calculator", Input: json.RawMessage(`{"input":"6 * 7"}`)}},
+		{Match: `^42$`, Text: "The answer is 42."},
+	})
+	params := bedrock.ModelParams{ModelID: "anthropic.claude-3-haiku-20240307-v1:0", MaxTokens: 100}
+	tools := []bedrock.ToolSpec{{
+		Name:        "calculator",
+		Description: "Evaluates arithmetic",
+		InputSchema: map[string]any{"type": "object", "properties": map[string]any{"input": map[string]any{"type": "string"}}},
+	}}
+
+	messages := []bedrock.Message{{Role: bedrock.RoleUser, Content: "Multiply 6 by 7"}}
+	resp, err := client.Converse(context.Background(), messages, params, tools...)
+	if err != nil {
+		t.Fatalf("Converse() error = %v", err)
+	}
+	if resp.StopReason != bedrock.StopReasonToolUse || len(resp.ToolCalls) != 1 {
+		t.Fatalf("StopReason = %q with %d tool calls, want one tool call", resp.StopReason, len(resp.ToolCalls))
+	}
+	call := resp.ToolCalls[0]
+	if call.Name != "calculator" || call.ID == "" || string(call.Input) != `{"input":"6 * 7"}` {
+		t.Errorf("ToolCall = %+v", call)
+	}
+
+	messages = append(messages,
+		bedrock.Message{Role: bedrock.RoleAssistant, ToolCalls: resp.ToolCalls},
+		bedrock.Message{Role: bedrock.RoleUser, ToolResults: []bedrock.ToolResult{{ToolCallID: call.ID, Content: "42"}}},
+	)
+	resp, err = client.Converse(context.Background(), messages, params, tools...)
+	if err != nil {
+		t.Fatalf("Converse() with tool result error = %v", err)
+	}
+	if resp.Completion != "The answer is 42." {
+		t.Errorf("Completion = %q, want the scripted answer", resp.Completion)
+	}
+}
@@ -0,0 +1,187 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Calculator evaluates arithmetic expressions with +, -, *, /, %, ^ and parentheses
+type Calculator struct{}
+
+func (Calculator) Name() string { return "calculator" }
+
+func (Calculator) Description() string {
+	return `Evaluates an arithmetic expression with + - * / % ^ and parentheses, e.g. "(49 * 12) * 0.85"`
+}
+
+func (Calculator) Run(ctx context.Context, input string) (string, error) {
+	value, err := Evaluate(input)
+	if err != nil {
+		return "", err
+	}
+	// Rounding hides binary floating point noise such as 1499.3999999999999
+	return strconv.FormatFloat(math.Round(value*1e9)/1e9, 'f', -1, 64), nil
+}
+
+// Evaluate computes an arithmetic expression. Models often write × and ÷, dollar signs and
+// thousands separators, so those are accepted too.
+func Evaluate(expression string) (float64, error) {
+	expression = strings.NewReplacer("×", "*", "÷", "/", "$", "", ",", "").Replace(expression)
+
+	p := &parser{input: []rune(expression)}
+	value, err := p.expression()
+	if err != nil {
+		return 0, err
+	}
+	if p.skipSpace(); p.pos < len(p.input) {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos+1)
+	}
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, errors.New("result is not a finite number")
+	}
+	return value, nil
+}
+
+// parser is a recursive descent parser over the grammar
+//
+//	expression = term { ("+" | "-") term }
+//	term       = power { ("*" | "/" | "%") power }
+//	power      = unary [ "^" power ]
+//	unary      = "-" unary | primary
+//	primary    = number | "(" expression ")"
+type parser struct {
+	input []rune
+	pos   int
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+// accept consumes op if it is the next non-space character
+func (p *parser) accept(op rune) bool {
+	p.skipSpace()
+	if p.pos < len(p.input) && p.input[p.pos] == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expression() (float64, error) {
+	left, err := p.term()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		switch {
+		case p.accept('+'):
+			right, err := p.term()
+			if err != nil {
+				return 0, err
+			}
+			left += right
+		case p.accept('-'):
+			right, err := p.term()
+			if err != nil {
+				return 0, err
+			}
+			left -= right
+		default:
+			return left, nil
+		}
+	}
+}
+
+func (p *parser) term() (float64, error) {
+	left, err := p.power()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		var op rune
+		switch {
+		case p.accept('*'):
+			op = '*'
+		case p.accept('/'):
+			op = '/'
+		case p.accept('%'):
+			op = '%'
+		default:
+			return left, nil
+		}
+
+		right, err := p.power()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case op == '*':
+			left *= right
+		case right == 0:
+			return 0, errors.New("division by zero")
+		case op == '/':
+			left /= right
+		default:
+			left = math.Mod(left, right)
+		}
+	}
+}
+
+func (p *parser) power() (float64, error) {
+	base, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	if !p.accept('^') {
+		return base, nil
+	}
+
+	exponent, err := p.power()
+	if err != nil {
+		return 0, err
+	}
+	return math.Pow(base, exponent), nil
+}
+
+func (p *parser) unary() (float64, error) {
+	if p.accept('-') {
+		value, err := p.unary()
+		return -value, err
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (float64, error) {
+	if p.accept('(') {
+		value, err := p.expression()
+		if err != nil {
+			return 0, err
+		}
+		if !p.accept(')') {
+			return 0, errors.New("missing closing parenthesis")
+		}
+		return value, nil
+	}
+
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.input) && (unicode.IsDigit(p.input[p.pos]) || p.input[p.pos] == '.') {
+		p.pos++
+	}
+	if start == p.pos {
+		if p.pos == len(p.input) {
+			return 0, errors.New("unexpected end of expression")
+		}
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos+1)
+	}
+
+	return strconv.ParseFloat(string(p.input[start:p.pos]), 64)
+}
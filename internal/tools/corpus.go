@@ -0,0 +1,136 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strings"
+)
+
+// maxObservation caps the text a corpus tool returns, so a large file cannot fill the prompt
+const maxObservation = 4000
+
+// maxMatches caps the lines returned by CorpusSearch
+const maxMatches = 20
+
+// FileLookup reads files from a local corpus. The corpus is an fs.FS, so lookups cannot
+// escape it; use os.DirFS for a directory on disk.
+type FileLookup struct {
+	corpus fs.FS
+}
+
+// NewFileLookup creates a lookup tool over corpus
+func NewFileLookup(corpus fs.FS) *FileLookup {
+	return &FileLookup{corpus: corpus}
+}
+
+func (*FileLookup) Name() string { return "lookup_file" }
+
+func (*FileLookup) Description() string {
+	return `Returns the contents of a file in the document corpus, e.g. "policies/travel.md"; input "list" lists the files`
+}
+
+func (t *FileLookup) Run(ctx context.Context, input string) (string, error) {
+	name := strings.Trim(strings.TrimSpace(input), `"'`)
+	if name == "" || name == "list" {
+		files, err := corpusFiles(t.corpus)
+		if err != nil {
+			return "", err
+		}
+		return strings.Join(files, "\n"), nil
+	}
+
+	if !fs.ValidPath(name) {
+		return "", fmt.Errorf("invalid path %q: use a relative path from the list of files", name)
+	}
+	data, err := fs.ReadFile(t.corpus, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("no file %q; input \"list\" to see the files", name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	return truncate(string(data)), nil
+}
+
+// CorpusSearch finds the lines of a local corpus that match a regular expression
+type CorpusSearch struct {
+	corpus fs.FS
+}
+
+// NewCorpusSearch creates a search tool over corpus
+func NewCorpusSearch(corpus fs.FS) *CorpusSearch {
+	return &CorpusSearch{corpus: corpus}
+}
+
+func (*CorpusSearch) Name() string { return "search_corpus" }
+
+func (*CorpusSearch) Description() string {
+	return `Searches the document corpus with a case-insensitive regular expression, e.g. "pro plan|seat", and returns matching lines as file:line: text`
+}
+
+func (t *CorpusSearch) Run(ctx context.Context, input string) (string, error) {
+	pattern, err := regexp.Compile("(?i)" + strings.Trim(strings.TrimSpace(input), `"'`))
+	if err != nil {
+		return "", fmt.Errorf("invalid regular expression: %w", err)
+	}
+
+	files, err := corpusFiles(t.corpus)
+	if err != nil {
+		return "", err
+	}
+
+	var matches []string
+	for _, name := range files {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+
+		data, err := fs.ReadFile(t.corpus, name)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			if pattern.MatchString(line) {
+				matches = append(matches, fmt.Sprintf("%s:%d: %s", name, i+1, strings.TrimSpace(line)))
+			}
+		}
+	}
+
+	switch {
+	case len(matches) == 0:
+		return "No matches", nil
+	case len(matches) > maxMatches:
+		return strings.Join(matches[:maxMatches], "\n") + fmt.Sprintf("\n... %d more matches; use a more specific pattern", len(matches)-maxMatches), nil
+	}
+	return strings.Join(matches, "\n"), nil
+}
+
+// corpusFiles lists the regular files of corpus in lexical order
+func corpusFiles(corpus fs.FS) ([]string, error) {
+	var files []string
+	err := fs.WalkDir(corpus, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			files = append(files, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list corpus: %w", err)
+	}
+	return files, nil
+}
+
+func truncate(text string) string {
+	if len(text) <= maxObservation {
+		return text
+	}
+	// Cutting at a byte offset can split a multi-byte character
+	return strings.ToValidUTF8(text[:maxObservation], "") + "\n... (truncated)"
+}
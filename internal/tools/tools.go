@@ -0,0 +1,63 @@
+// Package tools holds the actions an agent can take between model calls. Each tool takes a
+// single text input and returns a text observation, so any agent loop can drive it.
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// Tool is an action an agent can run. Implement it to give agents domain-specific abilities.
+type Tool interface {
+	// Name is the identifier the model uses to call the tool, e.g. "calculator"
+	Name() string
+	// Description tells the model what the tool does and what input it expects
+	Description() string
+	// Run executes the tool. Errors are shown to the model so it can correct its input.
+	Run(ctx context.Context, input string) (string, error)
+}
+
+// Set is a collection of tools with unique names, kept in the order they were added
+type Set struct {
+	tools  []Tool
+	byName map[string]Tool
+}
+
+// NewSet creates a set from tools, rejecting empty and duplicate names
+func NewSet(tools ...Tool) (*Set, error) {
+	s := &Set{byName: map[string]Tool{}}
+	for _, tool := range tools {
+		name := tool.Name()
+		if name == "" {
+			return nil, fmt.Errorf("tool %T has no name", tool)
+		}
+		if _, ok := s.byName[name]; ok {
+			return nil, fmt.Errorf("tool %q is defined twice", name)
+		}
+
+		s.tools = append(s.tools, tool)
+		s.byName[name] = tool
+	}
+	return s, nil
+}
+
+// Get returns the tool with the given name
+func (s *Set) Get(name string) (Tool, bool) {
+	tool, ok := s.byName[name]
+	return tool, ok
+}
+
+// Tools returns the tools in the order they were added
+func (s *Set) Tools() []Tool {
+	return slices.Clone(s.tools)
+}
+
+// Names returns the tool names in the order they were added
+func (s *Set) Names() []string {
+	names := make([]string, len(s.tools))
+	for i, tool := range s.tools {
+		names[i] = tool.Name()
+	}
+	return names
+}
@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       float64
+	}{
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"2 ^ 3 ^ 2", 512},
+		{"-4 + 10 / 4", -1.5},
+		{"$1,200 × 3 ÷ 4", 900},
+		{"17 % 5", 2},
+		{"3 * 49 * 12 * 0.85", 1499.4},
+	}
+
+	for _, tt := range tests {
+		got, err := Evaluate(tt.expression)
+		if err != nil {
+			t.Errorf("Evaluate(%q) error = %v", tt.expression, err)
+			continue
+		}
+		if diff := got - tt.want; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("Evaluate(%q) = %v, want %v", tt.expression, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	for _, expression := range []string{"", "1 +", "(1 + 2", "2 / 0", "1 + x", "1 2"} {
+		if got, err := Evaluate(expression); err == nil {
+			t.Errorf("Evaluate(%q) = %v, want an error", expression, got)
+		}
+	}
+}
+
+var testCorpus = fstest.MapFS{
+	"policies/travel.md": {Data: []byte("# Travel\nHotel limit is $200 per night.\nMeals up to $75 per day.\n")},
+	"pricing.md":         {Data: []byte("Pro plan: $49 per seat per month.\n")},
+}
+
+func TestFileLookup(t *testing.T) {
+	lookup := NewFileLookup(testCorpus)
+
+	list, err := lookup.Run(context.Background(), "list")
+	if err != nil || list != "policies/travel.md\npricing.md" {
+		t.Errorf(`Run("list") = %q, %v`, list, err)
+	}
+
+	content, err := lookup.Run(context.Background(), `"pricing.md"`)
+	if err != nil || !strings.Contains(content, "$49") {
+		t.Errorf(`Run("pricing.md") = %q, %v`, content, err)
+	}
+
+	for _, name := range []string{"../secrets.txt", "/etc/passwd", "missing.md"} {
+		if _, err := lookup.Run(context.Background(), name); err == nil {
+			t.Errorf("Run(%q) succeeded, want an error", name)
+		}
+	}
+}
+
+func TestCorpusSearch(t *testing.T) {
+	search := NewCorpusSearch(testCorpus)
+
+	got, err := search.Run(context.Background(), "HOTEL|seat")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	want := "policies/travel.md:2: Hotel limit is $200 per night.\npricing.md:1: Pro plan: $49 per seat per month."
+	if got != want {
+		t.Errorf("Run() = %q, want %q", got, want)
+	}
+
+	if got, _ := search.Run(context.Background(), "visa"); got != "No matches" {
+		t.Errorf("Run(no match) = %q", got)
+	}
+	if _, err := search.Run(context.Background(), "(unclosed"); err == nil {
+		t.Error("Run() with an invalid pattern succeeded")
+	}
+}
+
+func TestNewSetRejectsDuplicates(t *testing.T) {
+	if _, err := NewSet(Calculator{}, Calculator{}); err == nil {
+		t.Error("NewSet() with a duplicate name succeeded")
+	}
+
+	set, err := NewSet(Calculator{}, NewFileLookup(testCorpus))
+	if err != nil {
+		t.Fatalf("NewSet() error = %v", err)
+	}
+	if names := strings.Join(set.Names(), ","); names != "calculator,lookup_file" {
+		t.Errorf("Names() = %s", names)
+	}
+}
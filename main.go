@@ -2,10 +2,15 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"time"
 
 	"aws-bedrock-prompt-engineering/internal/bedrock"
 	"aws-bedrock-prompt-engineering/internal/prompting"
@@ -23,29 +28,45 @@ func main() {
 		log.Fatal("Error creating Bedrock client: ", err)
 	}
 
+	library, err := loadLibrary()
+	if err != nil {
+		log.Fatal("Error loading prompt library: ", err)
+	}
+
+	// Subcommands run non-interactively; without one the menu starts
+	if len(os.Args) > 1 {
+		if err := runCommand(client, library, os.Args[1], os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	ledger := bedrock.NewLedger(bedrock.DefaultPrices)
+	techniques := library.Techniques()
+
 	// Display welcome message
-	displayWelcomeMessage()
+	displayWelcomeMessage(techniques)
 
-	// Main menu loop
+	// Main menu loop: one entry per technique in the library, then the fixed actions
 	for {
-		choice := displayMenu()
-
-		switch choice {
-		case "1":
-			runZeroShotExamples(client)
-		case "2":
-			runFewShotExamples(client)
-		case "3":
-			runChainOfThoughtExamples(client)
-		case "4":
-			runAllExamples(client)
-		case "5":
-			runInteractiveMode(client)
-		case "6":
+		choice, err := strconv.Atoi(displayMenu(techniques))
+		n := len(techniques)
+
+		switch {
+		case err != nil || choice < 1 || choice > n+3:
+			fmt.Println("❌ Invalid choice. Please try again.")
+		case choice <= n:
+			technique := techniques[choice-1]
+			runCancellable(func(ctx context.Context) { runTechniqueExamples(ctx, client, ledger, library, technique) })
+		case choice == n+1:
+			runCancellable(func(ctx context.Context) { runAllExamples(ctx, client, ledger, library) })
+		case choice == n+2:
+			runInteractiveMode(client, ledger)
+		case choice == n+3:
+			printUsage(ledger)
+			printRateStats(client.RateLimiter())
 			fmt.Println("👋 Thank you for using AWS Bedrock Prompt Engineering Demo!")
 			return
-		default:
-			fmt.Println("❌ Invalid choice. Please try again.")
 		}
 
 		fmt.Println("\nPress Enter to continue...")
@@ -53,74 +74,157 @@ func main() {
 	}
 }
 
-func displayWelcomeMessage() {
+// loadLibrary reads the prompt library from PROMPT_LIBRARY_DIR, or uses the one built into the binary
+func loadLibrary() (*prompting.Library, error) {
+	dir := os.Getenv("PROMPT_LIBRARY_DIR")
+	if dir == "" {
+		return prompting.DefaultLibrary(), nil
+	}
+
+	return prompting.LoadLibrary(os.DirFS(dir))
+}
+
+// runCommand runs a subcommand with a context that Ctrl-C cancels
+func runCommand(client *bedrock.Client, library *prompting.Library, name string, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var err error
+	switch name {
+	case "eval":
+		err = runEval(ctx, client, library, args)
+	case "compare":
+		err = runCompare(ctx, client, library, args)
+	case "batch":
+		err = runBatch(ctx, client, library, args)
+	default:
+		return fmt.Errorf("unknown command %q (available: eval, compare, batch)", name)
+	}
+	printRateStats(client.RateLimiter())
+	return err
+}
+
+func displayWelcomeMessage(techniques []prompting.Technique) {
 	fmt.Println("🚀 Welcome to AWS Bedrock Prompt Engineering Demo!")
 	fmt.Println(strings.Repeat("=", 60))
-	fmt.Println("This application demonstrates three key prompting techniques:")
-	fmt.Println("• Zero-Shot Prompting: Direct questions without examples")
-	fmt.Println("• Few-Shot Prompting: Learning from provided examples")
-	fmt.Println("• Chain-of-Thought: Step-by-step reasoning process")
+	fmt.Printf("This application demonstrates %d prompting techniques:\n", len(techniques))
+	for _, technique := range techniques {
+		if info := technique.Info(); info.Description != "" {
+			fmt.Printf("• %s Prompting: %s\n", info.Title, info.Description)
+		} else {
+			fmt.Printf("• %s Prompting\n", info.Title)
+		}
+	}
 	fmt.Println(strings.Repeat("=", 60))
 }
 
-func displayMenu() string {
+func displayMenu(techniques []prompting.Technique) string {
+	n := len(techniques)
+
 	fmt.Println("\n📋 Main Menu:")
-	fmt.Println("1. 🎯 Zero-Shot Prompting Examples")
-	fmt.Println("2. 🎪 Few-Shot Prompting Examples")
-	fmt.Println("3. 🧠 Chain-of-Thought Prompting Examples")
-	fmt.Println("4. 🌟 Run All Examples")
-	fmt.Println("5. 💬 Interactive Mode")
-	fmt.Println("6. 🚪 Exit")
-	fmt.Print("\nEnter your choice (1-6): ")
+	for i, technique := range techniques {
+		info := technique.Info()
+		fmt.Printf("%d. %s %s Prompting Examples\n", i+1, info.Emoji, info.Title)
+	}
+	fmt.Printf("%d. 🌟 Run All Examples\n", n+1)
+	fmt.Printf("%d. 💬 Interactive Mode\n", n+2)
+	fmt.Printf("%d. 🚪 Exit\n", n+3)
+	fmt.Printf("\nEnter your choice (1-%d): ", n+3)
 
 	reader := bufio.NewReader(os.Stdin)
 	choice, _ := reader.ReadString('\n')
 	return strings.TrimSpace(choice)
 }
 
-func runZeroShotExamples(client *bedrock.Client) {
+func runTechniqueExamples(ctx context.Context, client *bedrock.Client, ledger *bedrock.Ledger, library *prompting.Library, technique prompting.Technique) {
 	fmt.Println("\n" + strings.Repeat("=", 80))
-	zeroShot := prompting.NewZeroShotPrompt(client)
-	zeroShot.RunAllExamples()
+	prompting.RunTechnique(ctx, ledger.Meter(client, technique.Info().Title), library, technique)
 	fmt.Println(strings.Repeat("=", 80))
 }
 
-func runFewShotExamples(client *bedrock.Client) {
-	fmt.Println("\n" + strings.Repeat("=", 80))
-	fewShot := prompting.NewFewShotPrompt(client)
-	fewShot.RunAllExamples()
-	fmt.Println(strings.Repeat("=", 80))
+func runAllExamples(ctx context.Context, client *bedrock.Client, ledger *bedrock.Ledger, library *prompting.Library) {
+	fmt.Println("\n🌟 Running all prompting technique examples...")
+
+	for i, technique := range library.Techniques() {
+		if i > 0 {
+			fmt.Println("\n⏳ Pausing between techniques...")
+		}
+
+		runTechniqueExamples(ctx, client, ledger, library, technique)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+
+	fmt.Println("\n✅ All examples completed!")
+	printUsage(ledger)
 }
 
-func runChainOfThoughtExamples(client *bedrock.Client) {
-	fmt.Println("\n" + strings.Repeat("=", 80))
-	chainOfThought := prompting.NewChainOfThoughtPrompt(client)
-	chainOfThought.RunAllExamples()
-	fmt.Println(strings.Repeat("=", 80))
+// printUsage prints the token usage and estimated cost per technique and for the session so far
+func printUsage(ledger *bedrock.Ledger) {
+	session := ledger.Session()
+	if session.Calls == 0 {
+		return
+	}
+
+	fmt.Println("\n📊 Token Usage and Estimated Cost:")
+	fmt.Printf("%-20s %6s %10s %10s %10s\n", "Technique", "Calls", "Input", "Output", "Cost (USD)")
+	fmt.Println(strings.Repeat("-", 60))
+	for _, technique := range ledger.Techniques() {
+		printUsageRow(technique.Technique, technique.UsageTotals)
+	}
+	fmt.Println(strings.Repeat("-", 60))
+	printUsageRow("Session", session)
+
+	if session.Unpriced > 0 {
+		fmt.Printf("⚠️  %d call(s) used a model without a price in bedrock.DefaultPrices and are not included in the cost\n", session.Unpriced)
+	}
 }
 
-func runAllExamples(client *bedrock.Client) {
-	fmt.Println("\n🌟 Running all prompting technique examples...")
+// printRateStats reports how long calls queued for their quota, when the client is rate limited
+func printRateStats(limiter *bedrock.RateLimiter) {
+	stats := limiter.Stats()
+	if len(stats) == 0 {
+		return
+	}
+
+	fmt.Println("\n🚦 Rate Limiter Queueing:")
+	fmt.Printf("%-42s %6s %7s %10s %10s %9s %6s\n", "Model", "Calls", "Queued", "Mean wait", "Max wait", "Throttled", "Rate")
+	fmt.Println(strings.Repeat("-", 96))
+	for _, s := range stats {
+		fmt.Printf("%-42s %6d %7d %10s %10s %9d %5.0f%%\n", s.ModelID, s.Calls, s.Queued,
+			s.MeanWait().Round(time.Millisecond), s.MaxWait.Round(time.Millisecond), s.Throttled, s.Rate*100)
+	}
+}
 
-	runZeroShotExamples(client)
-	fmt.Println("\n⏳ Pausing between techniques...")
+func printUsageRow(name string, totals bedrock.UsageTotals) {
+	fmt.Printf("%-20s %6d %10d %10d %10.4f\n", name, totals.Calls, totals.InputTokens, totals.OutputTokens, totals.Cost)
+}
 
-	runFewShotExamples(client)
-	fmt.Println("\n⏳ Pausing between techniques...")
+// runCancellable runs a menu action with a context that Ctrl-C cancels, so an interrupt
+// stops the in-flight request and returns to the menu instead of exiting the program
+func runCancellable(run func(ctx context.Context)) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-	runChainOfThoughtExamples(client)
+	run(ctx)
 
-	fmt.Println("\n✅ All examples completed!")
+	if ctx.Err() != nil {
+		fmt.Println("\n⏹️  Cancelled, returning to menu")
+	}
 }
 
-func runInteractiveMode(client *bedrock.Client) {
+func runInteractiveMode(client *bedrock.Client, ledger *bedrock.Ledger) {
 	fmt.Println("\n💬 Interactive Mode - Enter your own prompts!")
-	fmt.Println("Type 'exit' to return to main menu")
+	fmt.Println("Type 'exit' to return to main menu, '/tools' to let the model call tools, press Ctrl-C to stop a response")
 	fmt.Println(strings.Repeat("-", 50))
 
 	reader := bufio.NewReader(os.Stdin)
 	params := bedrock.GetDefaultClaudeParams()
 
+	// With tools on, turns go through a Converse conversation so the model can call them
+	var conversation *prompting.Conversation
+
 	for {
 		fmt.Print("\n🤖 Enter your prompt: ")
 		prompt, _ := reader.ReadString('\n')
@@ -135,14 +239,83 @@ func runInteractiveMode(client *bedrock.Client) {
 			continue
 		}
 
+		if prompt == "/tools" {
+			if conversation != nil {
+				conversation = nil
+				fmt.Println("🔧 Tools off")
+				continue
+			}
+			dispatcher := prompting.DefaultDispatcher()
+			conversation = prompting.NewConversation(client, params).WithTools(dispatcher).OnToolCall(printToolCall)
+			fmt.Printf("🔧 Tools on: %s (needs a model with tool use, e.g. Claude 3)\n", toolNames(dispatcher))
+			continue
+		}
+
+		if conversation != nil {
+			runToolTurn(conversation, ledger, params.ModelID, prompt)
+			continue
+		}
+
 		fmt.Println("\n🔄 Processing your request...")
-		response, err := client.InvokeModel(prompt, params)
-		if err != nil {
+		fmt.Println("\n🎯 Response:")
+
+		// Ctrl-C only cancels the in-flight stream while a response is being generated
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		response, err := client.InvokeModelStream(ctx, prompt, params, func(text string) {
+			fmt.Print(text)
+		})
+		stop()
+		// A cancelled or failed stream is still billed for the tokens it used
+		if err == nil || response != nil && response.Usage != nil {
+			ledger.Record("Interactive", params.ModelID, response.Usage)
+		}
+
+		fmt.Println()
+		if errors.Is(err, context.Canceled) {
+			fmt.Println("⏹️  Response cancelled")
+		} else if err != nil {
 			fmt.Printf("❌ Error: %v\n", err)
 			continue
 		}
 
-		fmt.Printf("\n🎯 Response:\n%s\n", response.Completion)
 		fmt.Println(strings.Repeat("-", 50))
 	}
 }
+
+// runToolTurn sends one interactive turn with tools, printing each tool call as it runs
+func runToolTurn(conversation *prompting.Conversation, ledger *bedrock.Ledger, modelID, prompt string) {
+	fmt.Println("\n🔄 Processing your request...")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	response, err := conversation.Send(ctx, prompt)
+	stop()
+
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("⏹️  Response cancelled")
+		return
+	} else if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return
+	}
+	ledger.Record("Interactive", modelID, response.Usage)
+
+	fmt.Println("\n🎯 Response:")
+	fmt.Println(response.Completion)
+	fmt.Println(strings.Repeat("-", 50))
+}
+
+func printToolCall(call bedrock.ToolCall, result bedrock.ToolResult) {
+	status := "→"
+	if result.IsError {
+		status = "❌"
+	}
+	fmt.Printf("🔧 %s(%s) %s %s\n", call.Name, call.Input, status, result.Content)
+}
+
+func toolNames(dispatcher *prompting.Dispatcher) string {
+	var names []string
+	for _, spec := range dispatcher.Specs() {
+		names = append(names, spec.Name)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,26 @@
+// Package prompts embeds the default prompt library, one YAML file per technique.
+// Every file is a list of prompt specs; see prompting.PromptSpec for the fields.
+package prompts
+
+import "embed"
+
+// FS holds the library files, loaded with prompting.LoadLibrary
+//
+//go:embed *.yaml
+var FS embed.FS
+
+// Corpus holds the documents the ReAct demo's file lookup and search tools read, under corpus/
+//
+//go:embed corpus
+var Corpus embed.FS
+
+// Datasets holds the golden datasets the eval command scores techniques against, under datasets/
+//
+//go:embed datasets
+var Datasets embed.FS
+
+// Variants holds prompts written only for eval and compare, under variants/: versions of a dataset's
+// task for other techniques. They are kept out of FS so the demo menu does not run them.
+//
+//go:embed variants
+var Variants embed.FS